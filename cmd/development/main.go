@@ -3,9 +3,9 @@ package main
 import (
 	"time"
 
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
 	"github.com/theggv/kf2-stats-backend/pkg/common/config"
 	"github.com/theggv/kf2-stats-backend/pkg/common/cron"
 	"github.com/theggv/kf2-stats-backend/pkg/common/database/mysql"
@@ -32,23 +32,49 @@ func main() {
 		panic(err)
 	}
 
-	db.InitTables()
+	// Schema and migrations are applied by one replica at a time
+	err = db.WithLock("migrations", 5*time.Minute, func() {
+		db.InitTables()
+		migrations.ExecuteAll(db.Conn)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	cacheStore, err := cachestore.New(cachestore.Options{
+		Backend:       config.CacheBackend,
+		Prefix:        config.CachePrefix,
+		RedisAddr:     config.RedisAddr,
+		RedisPassword: config.RedisPassword,
+		RedisDB:       config.RedisDB,
+	})
+	if err != nil {
+		panic(err)
+	}
 
-	rootStore := store.New(db.Conn, config)
-	memoryStore := persist.NewMemoryStore(5 * time.Minute)
+	rootStore := store.New(db.Conn, config, cacheStore)
 
-	// Run migrations
-	migrations.ExecuteAll(db.Conn)
+	if config.RunsWorker() {
+		cron.SetupTasks(rootStore)
+	}
 
-	cron.SetupTasks(rootStore)
+	if !config.RunsApi() {
+		// Worker only processes background jobs
+		select {}
+	}
 
 	r := gin.Default()
 
+	// Client ip is taken from X-Forwarded-For only behind trusted proxies
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		panic(err)
+	}
+
 	// Setup cors
 	r.Use(cors.Default())
 
 	// Register api routes
-	router.RegisterApiRoutes(r, rootStore, memoryStore)
+	router.RegisterApiRoutes(r, rootStore)
 
 	// Setup swagger
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
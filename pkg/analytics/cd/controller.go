@@ -0,0 +1,82 @@
+package cd
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type controller struct {
+	service *CDAnalyticsService
+}
+
+// @Summary Get spawn cycles of CD sessions with win rate and difficulty
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		CyclesRequest true "Body"
+// @Success 200 {object} 	CyclesResponse
+// @Router /analytics/cd/cycles [post]
+func (c *controller) getCycles(ctx *gin.Context) {
+	var req CyclesRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := c.service.GetCycles(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &CyclesResponse{
+		Items: items,
+	})
+}
+
+// @Summary Get spawn cycle stats by max monsters and wipe waves
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		CycleDetailsRequest true "Body"
+// @Success 200 {object} 	CycleDetailsResponse
+// @Router /analytics/cd/cycles/details [post]
+func (c *controller) getCycleDetails(ctx *gin.Context) {
+	var req CycleDetailsRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.GetCycleDetails(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// @Summary Get spawn cycle metric grouped by time period
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		CycleTrendRequest true "Body"
+// @Success 200 {object} 	CycleTrendResponse
+// @Router /analytics/cd/cycles/trend [post]
+func (c *controller) getCycleTrend(ctx *gin.Context) {
+	var req CycleTrendRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := c.service.GetCycleTrend(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &CycleTrendResponse{
+		Items: items,
+	})
+}
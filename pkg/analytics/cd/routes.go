@@ -0,0 +1,84 @@
+package cd
+
+import (
+	"fmt"
+	"time"
+
+	cache "github.com/chenyahui/gin-cache"
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+func (f *CycleFilter) cacheKey() string {
+	from, to := "", ""
+	if f.From != nil && f.To != nil {
+		from, to = f.From.Format("2006-01-02"), f.To.Format("2006-01-02")
+	}
+
+	return fmt.Sprintf("%v/%v/%v/%v/%v/%v",
+		f.ServerId, util.IntArrayToString(f.ServerIds, ","),
+		f.Difficulty, f.Length, from, to,
+	)
+}
+
+func (f *CycleFilter) cacheTags() []string {
+	return cachestore.ServerTags(append([]int{f.ServerId}, f.ServerIds...)...)
+}
+
+func RegisterRoutes(
+	r *gin.RouterGroup,
+	service *CDAnalyticsService,
+	cacheStore cachestore.Store,
+) {
+	controller := controller{
+		service: service,
+	}
+
+	routes := r.Group("/analytics/cd",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
+
+	routes.POST("/cycles",
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req CyclesRequest) string {
+					return fmt.Sprintf("%v/%v", req.cacheKey(), req.Limit)
+				},
+				func(req CyclesRequest) []string {
+					return req.cacheTags()
+				},
+			),
+		),
+		controller.getCycles)
+
+	routes.POST("/cycles/details",
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req CycleDetailsRequest) string {
+					return fmt.Sprintf("%v/%v", req.cacheKey(), req.SpawnCycle)
+				},
+				func(req CycleDetailsRequest) []string {
+					return req.cacheTags()
+				},
+			),
+		),
+		controller.getCycleDetails)
+
+	routes.POST("/cycles/trend",
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req CycleTrendRequest) string {
+					return fmt.Sprintf("%v/%v/%v/%v", req.cacheKey(), req.SpawnCycle, req.Metric, req.Period)
+				},
+				func(req CycleTrendRequest) []string {
+					return req.cacheTags()
+				},
+			),
+		),
+		controller.getCycleTrend)
+}
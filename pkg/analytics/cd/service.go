@@ -0,0 +1,299 @@
+package cd
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/analytics"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
+)
+
+// Completed CD sessions joined with their spawn cycle settings and calculated difficulty
+const cycleSessionsStmt = `
+	SELECT
+		session.id,
+		session.status,
+		session.started_at,
+		extra.spawn_cycle,
+		coalesce(extra.max_monsters, 0) AS max_monsters,
+		coalesce(diff.final_score, 0) AS calc_diff
+	FROM session
+	INNER JOIN session_game_data_extra extra ON extra.session_id = session.id
+	LEFT JOIN session_diff diff ON diff.session_id = session.id
+	WHERE %v`
+
+type CDAnalyticsService struct {
+	db *sql.DB
+}
+
+func NewCDAnalyticsService(db *sql.DB) *CDAnalyticsService {
+	service := CDAnalyticsService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *CDAnalyticsService) buildConds(filter CycleFilter) ([]string, []any) {
+	conds := make([]string, 0)
+	args := make([]any, 0)
+
+	conds = append(conds, moderation.NotExcludedCond)
+
+	conds = append(conds,
+		"session.started_at is not null",
+		fmt.Sprintf("session.mode = %v", models.ControlledDifficulty),
+		fmt.Sprintf("session.status IN (%v, %v)", models.Win, models.Lose),
+		"coalesce(extra.spawn_cycle, '') != ''",
+	)
+
+	if len(filter.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.server_id IN (%v)", util.IntArrayToString(filter.ServerIds, ",")))
+	} else if filter.ServerId != 0 {
+		conds = append(conds, "session.server_id = ?")
+		args = append(args, filter.ServerId)
+	}
+
+	if filter.Difficulty != 0 {
+		conds = append(conds, "session.diff = ?")
+		args = append(args, filter.Difficulty)
+	}
+
+	if filter.Length != 0 {
+		conds = append(conds, "session.length = ?")
+		args = append(args, filter.Length)
+	}
+
+	if filter.From != nil && filter.To != nil {
+		conds = append(conds, "DATE(session.started_at) BETWEEN ? AND ?")
+		args = append(args, filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02"))
+	}
+
+	return conds, args
+}
+
+func (s *CDAnalyticsService) GetCycles(req CyclesRequest) ([]*CycleStats, error) {
+	conds, args := s.buildConds(req.CycleFilter)
+
+	stmt := fmt.Sprintf(`
+		WITH filtered AS (
+			%v
+		), max_monsters AS (
+			SELECT
+				spawn_cycle,
+				max_monsters,
+				row_number() OVER (PARTITION BY spawn_cycle ORDER BY count(*) DESC, max_monsters) AS rn
+			FROM filtered
+			GROUP BY spawn_cycle, max_monsters
+		)
+		SELECT
+			f.spawn_cycle,
+			count(*) AS sessions,
+			count(CASE WHEN f.status = %v THEN 1 END),
+			avg(f.calc_diff),
+			coalesce(mm.max_monsters, 0)
+		FROM filtered f
+		LEFT JOIN max_monsters mm ON mm.spawn_cycle = f.spawn_cycle AND mm.rn = 1
+		GROUP BY f.spawn_cycle, mm.max_monsters
+		ORDER BY sessions DESC
+		LIMIT %v`,
+		fmt.Sprintf(cycleSessionsStmt, strings.Join(conds, " AND ")),
+		models.Win, clampLimit(req.Limit),
+	)
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*CycleStats{}
+	for rows.Next() {
+		item := CycleStats{}
+
+		err := rows.Scan(
+			&item.SpawnCycle, &item.Sessions, &item.Wins,
+			&item.AvgCalcDiff, &item.MaxMonsters,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.Sessions > 0 {
+			item.WinRate = float64(item.Wins) / float64(item.Sessions)
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+func (s *CDAnalyticsService) GetCycleDetails(req CycleDetailsRequest) (*CycleDetailsResponse, error) {
+	conds, args := s.buildConds(req.CycleFilter)
+
+	conds = append(conds, "extra.spawn_cycle = ?")
+	args = append(args, req.SpawnCycle)
+
+	res := CycleDetailsResponse{
+		CycleStats: CycleStats{
+			SpawnCycle: req.SpawnCycle,
+		},
+		MaxMonstersStats: []*CycleMaxMonstersStats{},
+		Waves:            []*CycleWaveStats{},
+	}
+
+	{
+		rows, err := s.db.Query(fmt.Sprintf(`
+			WITH filtered AS (
+				%v
+			)
+			SELECT
+				max_monsters,
+				count(*) AS sessions,
+				count(CASE WHEN status = %v THEN 1 END),
+				avg(calc_diff)
+			FROM filtered
+			GROUP BY max_monsters
+			ORDER BY sessions DESC, max_monsters`,
+			fmt.Sprintf(cycleSessionsStmt, strings.Join(conds, " AND ")), models.Win,
+		), args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		calcDiffSum := 0.0
+		for rows.Next() {
+			item := CycleMaxMonstersStats{}
+
+			err := rows.Scan(&item.MaxMonsters, &item.Sessions, &item.Wins, &item.AvgCalcDiff)
+			if err != nil {
+				return nil, err
+			}
+
+			if item.Sessions > 0 {
+				item.WinRate = float64(item.Wins) / float64(item.Sessions)
+			}
+
+			res.Sessions += item.Sessions
+			res.Wins += item.Wins
+			calcDiffSum += item.AvgCalcDiff * float64(item.Sessions)
+
+			res.MaxMonstersStats = append(res.MaxMonstersStats, &item)
+		}
+
+		if len(res.MaxMonstersStats) > 0 {
+			res.MaxMonsters = res.MaxMonstersStats[0].MaxMonsters
+		}
+
+		if res.Sessions > 0 {
+			res.WinRate = float64(res.Wins) / float64(res.Sessions)
+			res.AvgCalcDiff = calcDiffSum / float64(res.Sessions)
+		}
+	}
+
+	{
+		rows, err := s.db.Query(fmt.Sprintf(`
+			WITH filtered AS (
+				%v
+			), with_last_wave AS (
+				SELECT
+					f.id,
+					f.status,
+					(SELECT max(wave) FROM wave_stats WHERE session_id = f.id) AS last_wave
+				FROM filtered f
+			)
+			SELECT
+				ws.wave,
+				count(DISTINCT ws.session_id),
+				count(DISTINCT CASE WHEN f.status = %v AND f.last_wave = ws.wave THEN ws.session_id END)
+			FROM with_last_wave f
+			INNER JOIN wave_stats ws ON ws.session_id = f.id
+			GROUP BY ws.wave
+			ORDER BY ws.wave`,
+			fmt.Sprintf(cycleSessionsStmt, strings.Join(conds, " AND ")), models.Lose,
+		), args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			item := CycleWaveStats{}
+
+			err := rows.Scan(&item.Wave, &item.Sessions, &item.Losses)
+			if err != nil {
+				return nil, err
+			}
+
+			if item.Sessions > 0 {
+				item.LossRate = float64(item.Losses) / float64(item.Sessions)
+			}
+
+			res.Waves = append(res.Waves, &item)
+		}
+	}
+
+	return &res, nil
+}
+
+func (s *CDAnalyticsService) GetCycleTrend(req CycleTrendRequest) ([]*models.PeriodData, error) {
+	conds, args := s.buildConds(req.CycleFilter)
+
+	conds = append(conds, "extra.spawn_cycle = ?")
+	args = append(args, req.SpawnCycle)
+
+	var value string
+	switch req.Metric {
+	case Sessions:
+		value = "count(*)"
+	case WinRate:
+		value = fmt.Sprintf("100 * count(CASE WHEN status = %v THEN 1 END) / count(*)", models.Win)
+	case AvgCalcDiff:
+		value = "avg(calc_diff)"
+	default:
+		return nil, fmt.Errorf("expected TrendMetric enum, got %v", req.Metric)
+	}
+
+	var period string
+	switch req.Period {
+	case analytics.Month:
+		period = "DATE_FORMAT(started_at, '%Y-%m-01 00:00:00')"
+	case analytics.Year:
+		period = "DATE_FORMAT(started_at, '%Y-01-01 00:00:00')"
+	case analytics.Date:
+		period = "DATE_FORMAT(started_at, '%Y-%m-%d 00:00:00')"
+	case analytics.Week:
+		period = "DATE_FORMAT(DATE_SUB(started_at, INTERVAL WEEKDAY(started_at) DAY), '%Y-%m-%d 00:00:00')"
+	default:
+		return nil, analytics.NewIncorrectPeriod(req.Period)
+	}
+
+	stmt := fmt.Sprintf(`
+		WITH filtered AS (
+			%v
+		)
+		SELECT
+			%v as period,
+			%v as value
+		FROM filtered
+		GROUP BY period
+		ORDER BY period`,
+		fmt.Sprintf(cycleSessionsStmt, strings.Join(conds, " AND ")), period, value,
+	)
+
+	return analytics.ExecuteHistoricalQuery(s.db, stmt, args...)
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return 10
+	} else if limit > 100 {
+		return 100
+	}
+	return limit
+}
@@ -0,0 +1,94 @@
+package cd
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/analytics"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type TrendMetric = int
+
+const (
+	Sessions TrendMetric = iota + 1
+	WinRate
+	AvgCalcDiff
+)
+
+type CycleFilter struct {
+	ServerId int `json:"server_id"`
+	// Used to roll up several servers, takes precedence over server_id
+	ServerIds []int `json:"server_ids"`
+
+	Difficulty models.GameDifficulty `json:"diff"`
+	Length     models.GameLength     `json:"length"`
+
+	From *time.Time `json:"date_from"`
+	To   *time.Time `json:"date_to"`
+}
+
+type CycleStats struct {
+	SpawnCycle string `json:"spawn_cycle"`
+
+	Sessions int     `json:"sessions"`
+	Wins     int     `json:"wins"`
+	WinRate  float64 `json:"win_rate"`
+
+	AvgCalcDiff float64 `json:"avg_calc_diff"`
+	// Most common max_monsters value
+	MaxMonsters int `json:"max_monsters"`
+}
+
+type CyclesRequest struct {
+	CycleFilter
+
+	Limit int `json:"limit"`
+}
+
+type CyclesResponse struct {
+	Items []*CycleStats `json:"items"`
+}
+
+type CycleMaxMonstersStats struct {
+	MaxMonsters int `json:"max_monsters"`
+
+	Sessions int     `json:"sessions"`
+	Wins     int     `json:"wins"`
+	WinRate  float64 `json:"win_rate"`
+
+	AvgCalcDiff float64 `json:"avg_calc_diff"`
+}
+
+type CycleWaveStats struct {
+	Wave     int `json:"wave"`
+	Sessions int `json:"sessions"`
+
+	// Lost sessions which ended on this wave
+	Losses   int     `json:"losses"`
+	LossRate float64 `json:"loss_rate"`
+}
+
+type CycleDetailsRequest struct {
+	CycleFilter
+
+	SpawnCycle string `json:"spawn_cycle" binding:"required"`
+}
+
+type CycleDetailsResponse struct {
+	CycleStats
+
+	MaxMonstersStats []*CycleMaxMonstersStats `json:"max_monsters_stats"`
+	Waves            []*CycleWaveStats        `json:"waves"`
+}
+
+type CycleTrendRequest struct {
+	CycleFilter
+
+	SpawnCycle string               `json:"spawn_cycle" binding:"required"`
+	Metric     TrendMetric          `json:"metric" binding:"required"`
+	Period     analytics.TimePeriod `json:"period" binding:"required"`
+}
+
+type CycleTrendResponse struct {
+	Items []*models.PeriodData `json:"items"`
+}
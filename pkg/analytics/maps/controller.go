@@ -2,6 +2,7 @@ package maps
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -34,3 +35,32 @@ func (c *controller) getMapAnalytics(ctx *gin.Context) {
 		Items: items,
 	})
 }
+
+// @Summary Get map deep-dive analytics: wipe waves, deaths, durations, perks and kills
+// @Tags 	Analytics
+// @Produce json
+// @Param   id path   	 	int true "Map id"
+// @Param   body body 		MapDetailsRequest true "Body"
+// @Success 200 {object} 	MapDetailsResponse
+// @Router /analytics/maps/{id} [post]
+func (c *controller) getMapDetails(ctx *gin.Context) {
+	var req MapDetailsRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.GetMapDetails(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
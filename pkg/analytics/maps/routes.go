@@ -5,28 +5,56 @@ import (
 	"time"
 
 	cache "github.com/chenyahui/gin-cache"
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 )
 
 func RegisterRoutes(
 	r *gin.RouterGroup,
 	service *MapAnalyticsService,
-	memoryStore *persist.MemoryStore,
+	cacheStore cachestore.Store,
 ) {
 	controller := controller{
 		service: service,
 	}
 
-	routes := r.Group("/analytics/")
+	routes := r.Group("/analytics/",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
 
 	routes.POST("/maps",
-		cache.Cache(memoryStore, 5*time.Minute,
-			strategy.CacheByRequestBody(func(req MapAnalyticsRequest) string {
-				return fmt.Sprintf("%v/%v/%v/%v",
-					req.ServerId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Limit)
-			}),
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req MapAnalyticsRequest) string {
+					return fmt.Sprintf("%v/%v/%v/%v/%v",
+						req.ServerId, util.IntArrayToString(req.ServerIds, ","),
+						req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Limit)
+				},
+				func(req MapAnalyticsRequest) []string {
+					return cachestore.ServerTags(append([]int{req.ServerId}, req.ServerIds...)...)
+				},
+			),
 		),
 		controller.getMapAnalytics)
+
+	routes.POST("/maps/:id",
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req MapDetailsRequest) string {
+					return fmt.Sprintf("%v/%v/%v/%v/%v/%v/%v",
+						req.ServerId, util.IntArrayToString(req.ServerIds, ","),
+						req.Mode, req.Difficulty, req.Length,
+						req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+				},
+				func(req MapDetailsRequest) []string {
+					return cachestore.ServerTags(append([]int{req.ServerId}, req.ServerIds...)...)
+				},
+			),
+		),
+		controller.getMapDetails)
 }
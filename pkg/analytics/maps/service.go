@@ -4,6 +4,10 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
 )
 
 type MapAnalyticsService struct {
@@ -26,9 +30,13 @@ func (s *MapAnalyticsService) GetMapAnalytics(
 	conds := make([]string, 0)
 	args := make([]any, 0)
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	conds = append(conds, "session.started_at is not null")
 
-	if req.ServerId != 0 {
+	if len(req.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
+	} else if req.ServerId != 0 {
 		conds = append(conds, "session.server_id = ?")
 		args = append(args, req.ServerId)
 	}
@@ -56,7 +64,7 @@ func (s *MapAnalyticsService) GetMapAnalytics(
 		), result AS (
 			SELECT
 				maps.id as map_id,
-				maps.name as map_name,
+				coalesce(maps.display_name, maps.name) as map_name,
 				cte.value as value
 			FROM maps_rating cte
 			INNER JOIN maps ON maps.id = cte.map_id
@@ -100,6 +108,263 @@ func (s *MapAnalyticsService) GetMapAnalytics(
 	return items, nil
 }
 
+func (s *MapAnalyticsService) GetMapDetails(
+	mapId int, req MapDetailsRequest,
+) (*MapDetailsResponse, error) {
+	conds := make([]string, 0)
+	args := make([]any, 0)
+
+	conds = append(conds, moderation.NotExcludedCond)
+
+	conds = append(conds, "session.map_id = ?")
+	args = append(args, mapId)
+
+	// Only completed games tell something about the map difficulty
+	conds = append(conds, fmt.Sprintf("session.status IN (%v, %v)", models.Win, models.Lose))
+
+	if len(req.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
+	} else if req.ServerId != 0 {
+		conds = append(conds, "session.server_id = ?")
+		args = append(args, req.ServerId)
+	}
+
+	if req.Mode != 0 {
+		conds = append(conds, "session.mode = ?")
+		args = append(args, req.Mode)
+	}
+
+	if req.Difficulty != 0 {
+		conds = append(conds, "session.diff = ?")
+		args = append(args, req.Difficulty)
+	}
+
+	if req.Length != 0 {
+		conds = append(conds, "session.length = ?")
+		args = append(args, req.Length)
+	}
+
+	conds = append(conds, "DATE(session.started_at) BETWEEN ? AND ?")
+	args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+
+	res := MapDetailsResponse{
+		MapId:     mapId,
+		Waves:     []*MapWaveStats{},
+		Durations: []*MapWaveDuration{},
+		Perks:     []*MapPerkStats{},
+	}
+
+	err := s.db.QueryRow(`SELECT coalesce(display_name, name) FROM maps WHERE id = ?`, mapId).
+		Scan(&res.MapName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRow(fmt.Sprintf(`
+		SELECT
+			count(*),
+			count(CASE WHEN session.status = %v THEN 1 END)
+		FROM session
+		WHERE %v`,
+		models.Win, strings.Join(conds, " AND "),
+	), args...).Scan(&res.Sessions, &res.Wins)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Losses = res.Sessions - res.Wins
+	if res.Sessions > 0 {
+		res.WinRate = float64(res.Wins) / float64(res.Sessions)
+	}
+
+	waves, err := s.getMapWaves(conds, args)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, wave := range waves {
+		res.Kills.Add(wave.Kills)
+	}
+	res.Waves = waves
+
+	res.Durations, err = s.getMapWaveDurations(conds, args)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Perks, err = s.getMapPerks(conds, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Wipe rate, deaths and kills by wave number
+func (s *MapAnalyticsService) getMapWaves(conds []string, args []any) ([]*MapWaveStats, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		WITH filtered AS (
+			SELECT
+				session.id,
+				session.status,
+				(SELECT max(wave) FROM wave_stats WHERE session_id = session.id) AS last_wave
+			FROM session
+			WHERE %v
+		)
+		SELECT
+			ws.wave,
+			count(DISTINCT ws.session_id),
+			count(DISTINCT ws.id),
+			count(DISTINCT CASE WHEN f.status = %v AND f.last_wave = ws.wave THEN ws.session_id END),
+			count(wsp.id),
+			count(CASE WHEN wsp.is_dead THEN 1 END),
+			coalesce(sum(k.cyst), 0),
+			coalesce(sum(k.alpha_clot), 0),
+			coalesce(sum(k.slasher), 0),
+			coalesce(sum(k.stalker), 0),
+			coalesce(sum(k.crawler), 0),
+			coalesce(sum(k.gorefast), 0),
+			coalesce(sum(k.rioter), 0),
+			coalesce(sum(k.elite_crawler), 0),
+			coalesce(sum(k.gorefiend), 0),
+			coalesce(sum(k.siren), 0),
+			coalesce(sum(k.bloat), 0),
+			coalesce(sum(k.edar), 0),
+			coalesce(sum(k.husk_n + k.husk_b), 0),
+			coalesce(sum(k.scrake), 0),
+			coalesce(sum(k.fp), 0),
+			coalesce(sum(k.qp), 0),
+			coalesce(sum(k.boss), 0),
+			coalesce(sum(k.custom), 0)
+		FROM filtered f
+		INNER JOIN wave_stats ws ON ws.session_id = f.id
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		LEFT JOIN wave_stats_player_kills k ON k.player_stats_id = wsp.id
+		WHERE %v
+		GROUP BY ws.wave
+		ORDER BY ws.wave`,
+		strings.Join(conds, " AND "), models.Lose, moderation.NotBannedCond("wsp.player_id"),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*MapWaveStats{}
+	for rows.Next() {
+		item := MapWaveStats{}
+		var playerWaves int
+
+		err := rows.Scan(
+			&item.Wave, &item.Sessions, &item.Attempts, &item.Losses,
+			&playerWaves, &item.Deaths,
+			&item.Kills.Cyst, &item.Kills.AlphaClot, &item.Kills.Slasher,
+			&item.Kills.Stalker, &item.Kills.Crawler, &item.Kills.Gorefast,
+			&item.Kills.Rioter, &item.Kills.EliteCrawler, &item.Kills.Gorefiend,
+			&item.Kills.Siren, &item.Kills.Bloat, &item.Kills.Edar, &item.Kills.Husk,
+			&item.Kills.Scrake, &item.Kills.FP, &item.Kills.QP,
+			&item.Kills.Boss, &item.Kills.Custom,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.Sessions > 0 {
+			item.LossRate = float64(item.Losses) / float64(item.Sessions)
+		}
+		if playerWaves > 0 {
+			item.DeathRate = float64(item.Deaths) / float64(playerWaves)
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// Average wave duration by wave number and player count, trader skips are ignored
+func (s *MapAnalyticsService) getMapWaveDurations(conds []string, args []any) ([]*MapWaveDuration, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		WITH waves AS (
+			SELECT
+				ws.wave,
+				time_to_sec(timediff(ws.completed_at, ws.started_at)) AS duration,
+				(SELECT count(*) FROM wave_stats_player WHERE stats_id = ws.id) AS players
+			FROM session
+			INNER JOIN wave_stats ws ON ws.session_id = session.id
+			WHERE %v
+		)
+		SELECT wave, players, avg(duration), count(*)
+		FROM waves
+		WHERE duration > 15 AND players > 0
+		GROUP BY wave, players
+		ORDER BY wave, players`,
+		strings.Join(conds, " AND "),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*MapWaveDuration{}
+	for rows.Next() {
+		item := MapWaveDuration{}
+
+		err := rows.Scan(&item.Wave, &item.Players, &item.AvgDuration, &item.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// Pick rate and win rate of each perk
+func (s *MapAnalyticsService) getMapPerks(conds []string, args []any) ([]*MapPerkStats, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			aggr.perk,
+			count(*),
+			count(CASE WHEN session.status = %v THEN 1 END)
+		FROM session
+		INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+		WHERE %v AND %v
+		GROUP BY aggr.perk
+		ORDER BY aggr.perk`,
+		models.Win, strings.Join(conds, " AND "), moderation.NotBannedCond("aggr.user_id"),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totalPicks := 0
+	items := []*MapPerkStats{}
+	for rows.Next() {
+		item := MapPerkStats{}
+
+		err := rows.Scan(&item.Perk, &item.Picks, &item.Wins)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.Picks > 0 {
+			item.WinRate = float64(item.Wins) / float64(item.Picks)
+		}
+		totalPicks += item.Picks
+
+		items = append(items, &item)
+	}
+
+	for _, item := range items {
+		item.PickRate = float64(item.Picks) / float64(totalPicks)
+	}
+
+	return items, nil
+}
+
 func clampLimit(limit int) int {
 	if limit <= 0 {
 		return 10
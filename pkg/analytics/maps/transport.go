@@ -1,10 +1,17 @@
 package maps
 
-import "time"
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
 
 type MapAnalyticsRequest struct {
 	ServerId int `json:"server_id"`
 
+	// Used to roll up several servers, takes precedence over server_id
+	ServerIds []int `json:"server_ids"`
+
 	From time.Time `json:"date_from"`
 	To   time.Time `json:"date_to"`
 
@@ -21,3 +28,66 @@ type MapAnalytics struct {
 type MapAnalyticsResponse struct {
 	Items []*MapAnalytics `json:"items"`
 }
+
+type MapDetailsRequest struct {
+	ServerId int `json:"server_id"`
+
+	// Used to roll up several servers, takes precedence over server_id
+	ServerIds []int `json:"server_ids"`
+
+	Mode       models.GameMode       `json:"mode"`
+	Difficulty models.GameDifficulty `json:"diff"`
+	Length     models.GameLength     `json:"length"`
+
+	From time.Time `json:"date_from" binding:"required"`
+	To   time.Time `json:"date_to" binding:"required"`
+}
+
+type MapWaveStats struct {
+	Wave     int `json:"wave"`
+	Sessions int `json:"sessions"`
+	Attempts int `json:"attempts"`
+
+	// Lost sessions which ended on this wave
+	Losses   int     `json:"losses"`
+	LossRate float64 `json:"loss_rate"`
+
+	Deaths    int     `json:"deaths"`
+	DeathRate float64 `json:"death_rate"`
+
+	Kills models.ZedCounter `json:"kills"`
+}
+
+type MapWaveDuration struct {
+	Wave    int `json:"wave"`
+	Players int `json:"players"`
+
+	AvgDuration float64 `json:"avg_duration"`
+	Count       int     `json:"count"`
+}
+
+type MapPerkStats struct {
+	Perk int `json:"perk"`
+
+	Picks    int     `json:"picks"`
+	PickRate float64 `json:"pick_rate"`
+
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+}
+
+type MapDetailsResponse struct {
+	MapId   int    `json:"map_id"`
+	MapName string `json:"map_name"`
+
+	Sessions int     `json:"sessions"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	WinRate  float64 `json:"win_rate"`
+
+	Waves     []*MapWaveStats    `json:"waves"`
+	Durations []*MapWaveDuration `json:"durations"`
+	Perks     []*MapPerkStats    `json:"perks"`
+
+	Kills models.ZedCounter `json:"kills"`
+}
@@ -5,36 +5,51 @@ import (
 	"time"
 
 	cache "github.com/chenyahui/gin-cache"
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
 )
 
 func RegisterRoutes(
 	r *gin.RouterGroup,
 	service *PerksAnalyticsService,
-	memoryStore *persist.MemoryStore,
+	cacheStore cachestore.Store,
 ) {
 	controller := controller{
 		service: service,
 	}
 
-	routes := r.Group("/analytics/")
+	routes := r.Group("/analytics/",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
 
 	routes.POST("/perks/playtime",
-		cache.Cache(memoryStore, 5*time.Minute,
-			strategy.CacheByRequestBody(func(req PerksPlayTimeRequest) string {
-				return fmt.Sprintf("%v/%v/%v/%v",
-					req.ServerId, req.UserId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
-			}),
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req PerksPlayTimeRequest) string {
+					return fmt.Sprintf("%v/%v/%v/%v",
+						req.ServerId, req.UserId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+				},
+				func(req PerksPlayTimeRequest) []string {
+					return cachestore.ServerTags(req.ServerId)
+				},
+			),
 		),
 		controller.getPerksPlayTime)
 	routes.POST("/perks/kills",
-		cache.Cache(memoryStore, 5*time.Minute,
-			strategy.CacheByRequestBody(func(req PerksKillsRequest) string {
-				return fmt.Sprintf("%v/%v/%v/%v",
-					req.ServerId, req.UserId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
-			}),
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req PerksKillsRequest) string {
+					return fmt.Sprintf("%v/%v/%v/%v",
+						req.ServerId, req.UserId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+				},
+				func(req PerksKillsRequest) []string {
+					return cachestore.ServerTags(req.ServerId)
+				},
+			),
 		),
 		controller.getPerksKills)
 }
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
 )
 
 type PerksAnalyticsService struct {
@@ -24,6 +26,8 @@ func (s *PerksAnalyticsService) GetPerksPlayTime(
 	conds := make([]string, 0)
 	args := make([]interface{}, 0)
 
+	conds = append(conds, moderation.NotExcludedCond, moderation.NotBannedCond("aggr.user_id"))
+
 	if req.ServerId != 0 {
 		conds = append(conds, "session.server_id = ?")
 		args = append(args, req.ServerId)
@@ -81,6 +85,8 @@ func (s *PerksAnalyticsService) GetPerksKills(
 	conds := make([]string, 0)
 	args := make([]interface{}, 0)
 
+	conds = append(conds, moderation.NotExcludedCond, moderation.NotBannedCond("aggr.user_id"))
+
 	if req.ServerId != 0 {
 		conds = append(conds, "session.server_id = ?")
 		args = append(args, req.ServerId)
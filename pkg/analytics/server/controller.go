@@ -134,3 +134,25 @@ func (c *controller) getCurrentOnline(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, res)
 }
+
+// @Summary Get level ups on the server grouped by date and the most active levelers
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		LevelingActivityRequest true "Body"
+// @Success 201 {object} 	LevelingActivityResponse
+// @Router /analytics/server/leveling [post]
+func (c *controller) getLevelingActivity(ctx *gin.Context) {
+	var req LevelingActivityRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.getLevelingActivity(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
@@ -5,68 +5,115 @@ import (
 	"time"
 
 	cache "github.com/chenyahui/gin-cache"
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 )
 
 func RegisterRoutes(
 	r *gin.RouterGroup,
 	service *ServerAnalyticsService,
-	memoryStore *persist.MemoryStore,
+	cacheStore cachestore.Store,
 ) {
 	controller := controller{
 		service: service,
 	}
 
-	routes := r.Group("/analytics/")
+	store := cachestore.Tagged(cacheStore, cachestore.TagSessions)
+
+	routes := r.Group("/analytics/",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
 
 	routes.POST("/server/session/count",
-		cache.Cache(memoryStore, 5*time.Minute,
-			strategy.CacheByRequestBody(func(req SessionCountRequest) string {
-				if req.From != nil && req.To != nil {
-					return fmt.Sprintf("%v/%v/%v/%v",
-						req.ServerId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Period)
-				}
-
-				return fmt.Sprintf("%v/%v", req.ServerId, req.Period)
-			}),
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req SessionCountRequest) string {
+					if req.From != nil && req.To != nil {
+						return fmt.Sprintf("%v/%v/%v/%v/%v",
+							req.ServerId, util.IntArrayToString(req.ServerIds, ","),
+							req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Period)
+					}
+
+					return fmt.Sprintf("%v/%v/%v", req.ServerId, util.IntArrayToString(req.ServerIds, ","), req.Period)
+				},
+				func(req SessionCountRequest) []string {
+					return cachestore.ServerTags(append([]int{req.ServerId}, req.ServerIds...)...)
+				},
+			),
 		),
 		controller.getSessionCount)
 
 	routes.POST("/server/session/count/hist",
 		controller.getSessionCountHist)
 
+	routes.POST("/server/leveling",
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req LevelingActivityRequest) string {
+					key := fmt.Sprintf("%v/%v/%v",
+						req.ServerId, util.IntArrayToString(req.Perks, ","), req.Limit)
+
+					if req.From != nil && req.To != nil {
+						return fmt.Sprintf("%v/%v/%v",
+							key, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+					}
+
+					return key
+				},
+				func(req LevelingActivityRequest) []string {
+					return cachestore.ServerTags(req.ServerId)
+				},
+			),
+		),
+		controller.getLevelingActivity)
+
 	routes.POST("/server/usage",
-		cache.Cache(memoryStore, 5*time.Minute,
-			strategy.CacheByRequestBody(func(req UsageInMinutesRequest) string {
-				if req.From != nil && req.To != nil {
-					return fmt.Sprintf("%v/%v/%v/%v",
-						req.ServerId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Period)
-				}
-
-				return fmt.Sprintf("%v/%v", req.ServerId, req.Period)
-			}),
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req UsageInMinutesRequest) string {
+					if req.From != nil && req.To != nil {
+						return fmt.Sprintf("%v/%v/%v/%v/%v",
+							req.ServerId, util.IntArrayToString(req.ServerIds, ","),
+							req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Period)
+					}
+
+					return fmt.Sprintf("%v/%v/%v", req.ServerId, util.IntArrayToString(req.ServerIds, ","), req.Period)
+				},
+				func(req UsageInMinutesRequest) []string {
+					return cachestore.ServerTags(append([]int{req.ServerId}, req.ServerIds...)...)
+				},
+			),
 		),
 		controller.getUsageInMinutes)
 	routes.POST("/server/online",
-		cache.Cache(memoryStore, 5*time.Minute,
-			strategy.CacheByRequestBody(func(req PlayersOnlineRequest) string {
-				if req.From != nil && req.To != nil {
-					return fmt.Sprintf("%v/%v/%v/%v",
-						req.ServerId, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Period)
-				}
-
-				return fmt.Sprintf("%v/%v", req.ServerId, req.Period)
-			}),
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req PlayersOnlineRequest) string {
+					if req.From != nil && req.To != nil {
+						return fmt.Sprintf("%v/%v/%v/%v/%v",
+							req.ServerId, util.IntArrayToString(req.ServerIds, ","),
+							req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Period)
+					}
+
+					return fmt.Sprintf("%v/%v/%v", req.ServerId, util.IntArrayToString(req.ServerIds, ","), req.Period)
+				},
+				func(req PlayersOnlineRequest) []string {
+					return cachestore.ServerTags(append([]int{req.ServerId}, req.ServerIds...)...)
+				},
+			),
 		),
 		controller.getPlayersOnline)
 
 	routes.GET("/server/popular",
-		cache.CacheByRequestURI(memoryStore, 5*time.Minute),
+		cache.CacheByRequestURI(store, 5*time.Minute),
 		controller.getPopularServers)
 	routes.GET("/server/current-online",
-		cache.CacheByRequestURI(memoryStore, 15*time.Second),
+		cache.CacheByRequestURI(store, 15*time.Second),
 		controller.getCurrentOnline)
 
 }
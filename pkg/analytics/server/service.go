@@ -8,10 +8,14 @@ import (
 	"github.com/theggv/kf2-stats-backend/pkg/analytics"
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
 )
 
 type ServerAnalyticsService struct {
 	db *sql.DB
+
+	userService *users.UserService
 }
 
 func NewServerAnalyticsService(db *sql.DB) *ServerAnalyticsService {
@@ -22,15 +26,23 @@ func NewServerAnalyticsService(db *sql.DB) *ServerAnalyticsService {
 	return &service
 }
 
+func (s *ServerAnalyticsService) Inject(userService *users.UserService) {
+	s.userService = userService
+}
+
 func (s *ServerAnalyticsService) GetSessionCount(
 	req SessionCountRequest,
 ) ([]*models.PeriodData, error) {
 	conds := make([]string, 0)
 	args := make([]any, 0)
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	conds = append(conds, "session.started_at is not null")
 
-	if req.ServerId != 0 {
+	if len(req.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
+	} else if req.ServerId != 0 {
 		conds = append(conds, "session.server_id = ?")
 		args = append(args, req.ServerId)
 	}
@@ -78,8 +90,16 @@ func (s *ServerAnalyticsService) GetUsageInMinutes(
 	conds := make([]string, 0)
 	args := make([]any, 0)
 
-	conds = append(conds, "session.started_at is not null", "session.completed_at is not null", "session.server_id = ?")
-	args = append(args, req.ServerId)
+	conds = append(conds, moderation.NotExcludedCond)
+
+	conds = append(conds, "session.started_at is not null", "session.completed_at is not null")
+
+	if len(req.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
+	} else {
+		conds = append(conds, "session.server_id = ?")
+		args = append(args, req.ServerId)
+	}
 
 	var period string
 	switch req.Period {
@@ -122,9 +142,13 @@ func (s *ServerAnalyticsService) GetPlayersOnline(
 	conds := make([]string, 0)
 	args := make([]any, 0)
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	conds = append(conds, "session.started_at is not null")
 
-	if req.ServerId != 0 {
+	if len(req.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
+	} else if req.ServerId != 0 {
 		conds = append(conds, "session.server_id = ?")
 		args = append(args, req.ServerId)
 	}
@@ -180,6 +204,7 @@ func (s *ServerAnalyticsService) GetPopularServers() (*PopularServersResponse, e
 				min(session.diff) as diff
 			FROM session
 			INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+			WHERE NOT session.is_excluded
 			GROUP BY server_id
 			ORDER BY total_users desc
 			LIMIT 5
@@ -238,6 +263,8 @@ func (s *ServerAnalyticsService) getSessionCountHist(
 	conds := []string{}
 	args := []any{}
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	conds = append(conds, "session.server_id = ?")
 	args = append(args, req.ServerId)
 
@@ -289,3 +316,125 @@ func (s *ServerAnalyticsService) getSessionCountHist(
 
 	return analytics.ExecuteHistoricalQuery(s.db, stmt, args...)
 }
+
+// Returns cte with level ups, i.e. waves where level or prestige of the perk
+// is higher than on the previous wave played on the server
+func (s *ServerAnalyticsService) getLevelUpsStmt(req *LevelingActivityRequest) (string, []any) {
+	conds := []string{
+		"session.server_id = ?", "wsp.perk > 0",
+		moderation.NotExcludedCond, moderation.NotBannedCond("wsp.player_id"),
+		users.NotHiddenCond("wsp.player_id", users.HideProfile, users.HideFromLeaderboards),
+	}
+	args := []any{req.ServerId}
+
+	conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
+	if req.From != nil && req.To != nil {
+		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+	} else {
+		args = append(args, "2000-01-01", "3000-01-01")
+	}
+
+	if len(req.Perks) > 0 {
+		conds = append(conds, fmt.Sprintf("wsp.perk IN (%v)", util.IntArrayToString(req.Perks, ",")))
+	}
+
+	stmt := fmt.Sprintf(`
+		WITH player_waves AS (
+			SELECT
+				wsp.player_id, wsp.perk, wsp.level, wsp.prestige, wsp.created_at,
+				LAG(wsp.level) OVER w AS prev_level,
+				LAG(wsp.prestige) OVER w AS prev_prestige
+			FROM session
+			INNER JOIN wave_stats ws ON ws.session_id = session.id
+			INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+			WHERE %v
+			WINDOW w AS (PARTITION BY wsp.player_id, wsp.perk ORDER BY wsp.id)
+		), level_ups AS (
+			SELECT *
+			FROM player_waves
+			WHERE prestige > prev_prestige OR (prestige = prev_prestige AND level > prev_level)
+		)`, strings.Join(conds, " AND "),
+	)
+
+	return stmt, args
+}
+
+func (s *ServerAnalyticsService) getLevelingActivity(
+	req LevelingActivityRequest,
+) (*LevelingActivityResponse, error) {
+	limit := req.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	res := LevelingActivityResponse{
+		Items: []*LevelingActivityResponseItem{},
+	}
+
+	levelUpsStmt, args := s.getLevelUpsStmt(&req)
+
+	hist, err := analytics.ExecuteHistoricalQuery(s.db, fmt.Sprintf(`%v
+		SELECT
+			DATE(created_at) as period,
+			count(*) as value
+		FROM level_ups
+		GROUP BY period
+		ORDER BY period`, levelUpsStmt,
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Hist = hist
+
+	args = append(args, models.MaxPerkLevel, models.MaxPerkLevel, limit)
+
+	rows, err := s.db.Query(fmt.Sprintf(`%v
+		SELECT
+			player_id,
+			count(*) AS level_ups,
+			count(CASE WHEN level >= ? AND prev_level < ? THEN 1 END) AS maxed,
+			count(CASE WHEN prestige > prev_prestige THEN 1 END) AS prestiges
+		FROM level_ups
+		GROUP BY player_id
+		ORDER BY level_ups DESC, player_id
+		LIMIT ?`, levelUpsStmt,
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIds := []int{}
+	for rows.Next() {
+		item := LevelingActivityResponseItem{}
+
+		err := rows.Scan(&item.Id, &item.LevelUps, &item.Maxed, &item.Prestiges)
+		if err != nil {
+			return nil, err
+		}
+
+		userIds = append(userIds, item.Id)
+		res.Items = append(res.Items, &item)
+	}
+
+	if len(userIds) > 0 {
+		profiles, err := s.userService.GetUserProfiles(userIds)
+		if err != nil {
+			return nil, err
+		}
+
+		profilesSet := map[int]*models.UserProfile{}
+		for _, profile := range profiles {
+			profilesSet[profile.Id] = profile
+		}
+
+		for _, item := range res.Items {
+			if profile, ok := profilesSet[item.Id]; ok {
+				item.UserProfile = *profile
+			}
+		}
+	}
+
+	return &res, nil
+}
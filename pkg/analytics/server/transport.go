@@ -9,6 +9,8 @@ import (
 
 type SessionCountRequest struct {
 	ServerId int `json:"server_id"`
+	// Used to roll up several servers, takes precedence over server_id
+	ServerIds []int `json:"server_ids"`
 
 	From   *time.Time           `json:"date_from"`
 	To     *time.Time           `json:"date_to"`
@@ -21,6 +23,8 @@ type SessionCountResponse struct {
 
 type UsageInMinutesRequest struct {
 	ServerId int `json:"server_id" binding:"required"`
+	// Used to roll up several servers, takes precedence over server_id
+	ServerIds []int `json:"server_ids"`
 
 	From   *time.Time           `json:"date_from"`
 	To     *time.Time           `json:"date_to"`
@@ -33,6 +37,8 @@ type UsageInMinutesResponse struct {
 
 type PlayersOnlineRequest struct {
 	ServerId int `json:"server_id"`
+	// Used to roll up several servers, takes precedence over server_id
+	ServerIds []int `json:"server_ids"`
 
 	From   *time.Time           `json:"date_from"`
 	To     *time.Time           `json:"date_to"`
@@ -77,3 +83,28 @@ type SessionCountHistRequest struct {
 
 	AuthUser *models.TokenPayload `json:"-"`
 }
+
+type LevelingActivityRequest struct {
+	ServerId int `json:"server_id" binding:"required"`
+
+	From *time.Time `json:"date_from"`
+	To   *time.Time `json:"date_to"`
+
+	Perks []int `json:"perks"`
+
+	Limit int `json:"limit"`
+}
+
+type LevelingActivityResponseItem struct {
+	models.UserProfile
+
+	LevelUps  int `json:"level_ups"`
+	Maxed     int `json:"maxed"`
+	Prestiges int `json:"prestiges"`
+}
+
+type LevelingActivityResponse struct {
+	Hist []*models.PeriodData `json:"hist"`
+
+	Items []*LevelingActivityResponseItem `json:"items"`
+}
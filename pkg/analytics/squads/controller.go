@@ -0,0 +1,36 @@
+package squads
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type controller struct {
+	service *SquadsAnalyticsService
+}
+
+// @Summary Get the most successful recurring squads on the server
+// @Description Squads are counted both as the whole lobby and inside larger lobbies.
+// @Description Available sort fields: "games", "wins", "win_rate", "avg_difficulty", "last_played"
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		SquadsRequest true "Body"
+// @Success 200 {object} 	SquadsResponse
+// @Router /analytics/squads [post]
+func (c *controller) getSquads(ctx *gin.Context) {
+	var req SquadsRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.GetSquads(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,51 @@
+package squads
+
+import (
+	"fmt"
+	"time"
+
+	cache "github.com/chenyahui/gin-cache"
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
+)
+
+func RegisterRoutes(
+	r *gin.RouterGroup,
+	service *SquadsAnalyticsService,
+	cacheStore cachestore.Store,
+) {
+	controller := controller{
+		service: service,
+	}
+
+	routes := r.Group("/analytics/",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
+
+	routes.POST("/squads",
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req SquadsRequest) string {
+					key := fmt.Sprintf("%v/%v/%v/%v/%v/%v/%v",
+						req.ServerId, req.Size, req.MinGames,
+						req.SortBy.Field, req.SortBy.Direction,
+						req.Pager.Page, req.Pager.ResultsPerPage)
+
+					if req.From != nil && req.To != nil {
+						return fmt.Sprintf("%v/%v/%v",
+							key, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+					}
+
+					return key
+				},
+				func(req SquadsRequest) []string {
+					return append(cachestore.ServerTags(req.ServerId), cachestore.TagUsers)
+				},
+			),
+		),
+		controller.getSquads)
+}
@@ -0,0 +1,359 @@
+package squads
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+)
+
+type SquadsAnalyticsService struct {
+	db *sql.DB
+
+	userService *users.UserService
+}
+
+func NewSquadsAnalyticsService(db *sql.DB) *SquadsAnalyticsService {
+	service := SquadsAnalyticsService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *SquadsAnalyticsService) Inject(userService *users.UserService) {
+	s.userService = userService
+}
+
+// Returns cte with rosters of every completed session on the server.
+// Besides the whole lobby, every smaller group of players of the lobby is counted
+// as a separate roster, so squads playing inside public lobbies are found too.
+func (s *SquadsAnalyticsService) getRostersStmt(req *SquadsRequest) (string, []any) {
+	conds := []string{
+		"session.server_id = ?", "session.is_completed = 1",
+		moderation.NotExcludedCond, moderation.NotBannedCond("aggr.user_id"),
+		users.NotHiddenCond("aggr.user_id", users.HideProfile, users.HideSocial),
+	}
+	args := []any{req.ServerId}
+
+	if req.From != nil && req.To != nil {
+		conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
+		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+	}
+
+	minSize, maxSize := 2, 6
+	if req.Size >= minSize && req.Size <= maxSize {
+		minSize, maxSize = req.Size, req.Size
+	}
+	args = append(args, maxSize, minSize, maxSize, minSize, maxSize)
+
+	// Groups are built in ascending user id order, the same order as the lobby roster
+	stmt := fmt.Sprintf(`
+		WITH RECURSIVE session_rosters AS (
+			SELECT
+				session.id AS session_id,
+				session.status AS status,
+				session.updated_at AS updated_at,
+				max(coalesce(diff.final_score * diff.final_score, 0)) AS difficulty,
+				GROUP_CONCAT(DISTINCT aggr.user_id ORDER BY aggr.user_id) AS roster,
+				count(DISTINCT aggr.user_id) AS size
+			FROM session
+			INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+			LEFT JOIN session_diff diff ON diff.session_id = session.id
+			WHERE %v
+			GROUP BY session.id
+		), members AS (
+			SELECT DISTINCT aggr.session_id, aggr.user_id
+			FROM session_rosters sr
+			INNER JOIN session_aggregated aggr ON aggr.session_id = sr.session_id
+			WHERE %v AND %v
+		), sub_groups AS (
+			SELECT session_id, user_id AS last_user_id, CAST(user_id AS CHAR(255)) AS roster, 1 AS size
+			FROM members
+			UNION ALL
+			SELECT grp.session_id, m.user_id, CONCAT(grp.roster, ',', m.user_id), grp.size + 1
+			FROM sub_groups grp
+			INNER JOIN members m ON m.session_id = grp.session_id AND m.user_id > grp.last_user_id
+			WHERE grp.size < ?
+		), rosters AS (
+			SELECT session_id, status, updated_at, difficulty, roster
+			FROM session_rosters
+			WHERE size BETWEEN ? AND ?
+			UNION ALL
+			SELECT sr.session_id, sr.status, sr.updated_at, sr.difficulty, grp.roster
+			FROM sub_groups grp
+			INNER JOIN session_rosters sr ON sr.session_id = grp.session_id
+			WHERE grp.size BETWEEN ? AND ? AND grp.size < sr.size
+		)`, strings.Join(conds, " AND "),
+		moderation.NotBannedCond("aggr.user_id"),
+		users.NotHiddenCond("aggr.user_id", users.HideProfile, users.HideSocial),
+	)
+
+	return stmt, args
+}
+
+func (s *SquadsAnalyticsService) GetSquads(req SquadsRequest) (*SquadsResponse, error) {
+	page, limit := req.Pager.Parse()
+	minGames := max(req.MinGames, 2)
+
+	defaultSortBy := "wins / games"
+	fieldsMapper := map[string]string{
+		"games":          "games",
+		"wins":           "wins",
+		"win_rate":       "wins / games",
+		"avg_difficulty": "difficulty",
+		"last_played":    "last_played",
+	}
+	sortBy, direction := req.SortBy.Transform(fieldsMapper, defaultSortBy)
+	if req.SortBy.Field == "" {
+		direction = "DESC"
+	}
+
+	rostersStmt, args := s.getRostersStmt(&req)
+	args = append(args, minGames, page*limit, limit)
+
+	stmt := fmt.Sprintf(`%v
+		SELECT
+			roster,
+			count(*) AS games,
+			count(CASE WHEN status = 2 THEN 1 END) AS wins,
+			avg(difficulty) AS difficulty,
+			max(updated_at) AS last_played,
+			count(*) OVER () AS total_results
+		FROM rosters
+		GROUP BY roster
+		HAVING count(*) >= ?
+		ORDER BY %v %v, games DESC, roster ASC
+		LIMIT ?, ?`,
+		rostersStmt, sortBy, direction,
+	)
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := SquadsResponse{
+		Items: []*SquadsResponseItem{},
+		Metadata: &models.PaginationResponse{
+			Page:           page,
+			ResultsPerPage: limit,
+		},
+	}
+
+	squads := map[string]*SquadsResponseItem{}
+	rosters := []string{}
+	userIdSet := map[int]bool{}
+
+	for rows.Next() {
+		item := SquadsResponseItem{
+			Members: []*SquadMember{},
+		}
+
+		var roster string
+
+		err := rows.Scan(
+			&roster, &item.Games, &item.Wins,
+			&item.AvgDifficulty, &item.LastPlayed,
+			&res.Metadata.TotalResults,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.Games > 0 {
+			item.WinRate = float64(item.Wins) / float64(item.Games)
+		}
+
+		for _, value := range strings.Split(roster, ",") {
+			userId, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+
+			item.Members = append(item.Members, &SquadMember{
+				UserProfile: models.UserProfile{Id: userId},
+			})
+			userIdSet[userId] = true
+		}
+
+		squads[roster] = &item
+		rosters = append(rosters, roster)
+		res.Items = append(res.Items, &item)
+	}
+
+	if len(rosters) == 0 {
+		return &res, nil
+	}
+
+	err = s.fillBestLineups(&req, rosters, squads)
+	if err != nil {
+		return nil, err
+	}
+
+	{
+		userIds := []int{}
+		for key := range userIdSet {
+			userIds = append(userIds, key)
+		}
+
+		profiles, err := s.userService.GetUserProfiles(userIds)
+		if err != nil {
+			return nil, err
+		}
+
+		profilesSet := map[int]*models.UserProfile{}
+		for _, profile := range profiles {
+			profilesSet[profile.Id] = profile
+		}
+
+		for _, item := range res.Items {
+			for _, member := range item.Members {
+				if profile, ok := profilesSet[member.Id]; ok {
+					member.UserProfile = *profile
+				}
+			}
+		}
+	}
+
+	return &res, nil
+}
+
+// Finds perk composition with the most wins for each squad
+func (s *SquadsAnalyticsService) fillBestLineups(
+	req *SquadsRequest, rosters []string, squads map[string]*SquadsResponseItem,
+) error {
+	rostersStmt, args := s.getRostersStmt(req)
+
+	placeholders := []string{}
+	for _, roster := range rosters {
+		placeholders = append(placeholders, "?")
+		args = append(args, roster)
+	}
+
+	stmt := fmt.Sprintf(`%v
+		SELECT
+			cte.roster, cte.session_id, cte.status,
+			aggr.user_id, aggr.perk, aggr.playtime_seconds
+		FROM rosters cte
+		INNER JOIN session_aggregated aggr ON aggr.session_id = cte.session_id
+		WHERE cte.roster IN (%v) AND FIND_IN_SET(aggr.user_id, cte.roster) > 0`,
+		rostersStmt, strings.Join(placeholders, ", "),
+	)
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type memberPerk struct {
+		perk     models.Perk
+		playtime int
+	}
+
+	type sessionLineup struct {
+		roster string
+		isWin  bool
+		perks  map[int]*memberPerk
+	}
+
+	sessions := map[string]*sessionLineup{}
+
+	for rows.Next() {
+		var roster string
+		var sessionId, status, userId, perk, playtime int
+
+		err := rows.Scan(&roster, &sessionId, &status, &userId, &perk, &playtime)
+		if err != nil {
+			return err
+		}
+
+		// The same session is counted for the whole lobby and for its smaller groups
+		key := fmt.Sprintf("%v/%v", roster, sessionId)
+
+		session, ok := sessions[key]
+		if !ok {
+			session = &sessionLineup{
+				roster: roster,
+				isWin:  status == models.Win,
+				perks:  map[int]*memberPerk{},
+			}
+			sessions[key] = session
+		}
+
+		// Member perk is the one with the most playtime in the session
+		if current, ok := session.perks[userId]; !ok || current.playtime < playtime {
+			session.perks[userId] = &memberPerk{perk: perk, playtime: playtime}
+		}
+	}
+
+	type lineupStats struct {
+		perks map[int]models.Perk
+		games int
+		wins  int
+	}
+
+	lineups := map[string]map[string]*lineupStats{}
+
+	for _, session := range sessions {
+		keys := []string{}
+		perks := map[int]models.Perk{}
+		for userId, item := range session.perks {
+			keys = append(keys, fmt.Sprintf("%v:%v", userId, item.perk))
+			perks[userId] = item.perk
+		}
+		sort.Strings(keys)
+		key := strings.Join(keys, ",")
+
+		if _, ok := lineups[session.roster]; !ok {
+			lineups[session.roster] = map[string]*lineupStats{}
+		}
+
+		stats, ok := lineups[session.roster][key]
+		if !ok {
+			stats = &lineupStats{perks: perks}
+			lineups[session.roster][key] = stats
+		}
+
+		stats.games += 1
+		if session.isWin {
+			stats.wins += 1
+		}
+	}
+
+	for roster, items := range lineups {
+		squad, ok := squads[roster]
+		if !ok {
+			continue
+		}
+
+		var best *lineupStats
+		for _, item := range items {
+			if best == nil ||
+				item.wins > best.wins ||
+				item.wins == best.wins && item.games < best.games {
+				best = item
+			}
+		}
+
+		if best == nil {
+			continue
+		}
+
+		squad.BestLineupWins = best.wins
+		squad.BestLineupGames = best.games
+
+		for _, member := range squad.Members {
+			member.Perk = best.perks[member.Id]
+		}
+	}
+
+	return nil
+}
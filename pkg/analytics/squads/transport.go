@@ -0,0 +1,50 @@
+package squads
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type SquadsRequest struct {
+	ServerId int `json:"server_id" binding:"required"`
+
+	// Exact squad size, 0 means any size between 2 and 6.
+	// Squads are also counted inside larger lobbies.
+	Size int `json:"size"`
+
+	From *time.Time `json:"date_from"`
+	To   *time.Time `json:"date_to"`
+
+	MinGames int `json:"min_games"`
+
+	SortBy models.SortByRequest     `json:"sort_by"`
+	Pager  models.PaginationRequest `json:"pager"`
+}
+
+type SquadMember struct {
+	models.UserProfile
+
+	Perk models.Perk `json:"perk"`
+}
+
+type SquadsResponseItem struct {
+	Members []*SquadMember `json:"members"`
+
+	Games int `json:"games"`
+	Wins  int `json:"wins"`
+
+	WinRate       float64 `json:"win_rate"`
+	AvgDifficulty float64 `json:"avg_difficulty"`
+
+	// Wins with the best perk composition, members perks are taken from it
+	BestLineupWins  int `json:"best_lineup_wins"`
+	BestLineupGames int `json:"best_lineup_games"`
+
+	LastPlayed time.Time `json:"last_played"`
+}
+
+type SquadsResponse struct {
+	Items    []*SquadsResponseItem      `json:"items"`
+	Metadata *models.PaginationResponse `json:"metadata"`
+}
@@ -7,12 +7,31 @@ import (
 	"github.com/gin-gonic/gin/binding"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 	"github.com/theggv/kf2-stats-backend/pkg/matches/filter"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
 )
 
 type controller struct {
 	service *UserAnalyticsService
 }
 
+// Responds with 403 if one of the users hides the data from the current user
+func (c *controller) isHidden(ctx *gin.Context, userIds []int, flags ...users.PrivacyFlag) bool {
+	authUser, _ := util.GetUserFromCtx(ctx)
+
+	for _, userId := range userIds {
+		err := c.service.userService.CheckPrivacy(authUser, userId, flags...)
+		if err == users.ErrPrivateProfile {
+			ctx.String(http.StatusForbidden, err.Error())
+			return true
+		} else if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return true
+		}
+	}
+
+	return false
+}
+
 // @Summary Get user analytics
 // @Tags 	Analytics
 // @Produce json
@@ -26,6 +45,10 @@ func (c *controller) getUserAnalytics(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile) {
+		return
+	}
+
 	res, err := c.service.GetUserAnalytics(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
@@ -48,6 +71,10 @@ func (c *controller) getPerksAnalytics(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile) {
+		return
+	}
+
 	res, err := c.service.GetPerksAnalytics(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
@@ -70,6 +97,10 @@ func (c *controller) getPlaytimeHist(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile) {
+		return
+	}
+
 	req.AuthUser, _ = util.GetUserFromCtx(ctx)
 
 	res, err := c.service.getPlaytimeHist(req)
@@ -94,6 +125,10 @@ func (c *controller) getAccuracyHist(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile) {
+		return
+	}
+
 	req.AuthUser, _ = util.GetUserFromCtx(ctx)
 
 	res, err := c.service.getAccuracyHist(req)
@@ -118,6 +153,10 @@ func (c *controller) getDifficultyHist(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile) {
+		return
+	}
+
 	res, err := c.service.getDifficultyHist(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
@@ -140,6 +179,10 @@ func (c *controller) getTeammates(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile, users.HideSocial) {
+		return
+	}
+
 	req.AuthUser, _ = util.GetUserFromCtx(ctx)
 
 	res, err := c.service.getTeammates(req)
@@ -164,6 +207,10 @@ func (c *controller) getPlayedMaps(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile) {
+		return
+	}
+
 	res, err := c.service.getPlayedMaps(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
@@ -217,6 +264,10 @@ func (c *controller) getLastGamesWithUser(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, []int{req.OtherUserId}, users.HideProfile, users.HideSocial) {
+		return
+	}
+
 	if req.UserId != user.UserId {
 		ctx.String(http.StatusForbidden, "")
 		return
@@ -244,6 +295,10 @@ func (c *controller) getUserSessions(ctx *gin.Context) {
 		return
 	}
 
+	if c.isHidden(ctx, req.UserIds, users.HideProfile) {
+		return
+	}
+
 	req.AuthUser, _ = util.GetUserFromCtx(ctx)
 
 	res, err := c.service.getUserSessions(req)
@@ -254,3 +309,112 @@ func (c *controller) getUserSessions(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusCreated, res)
 }
+
+// @Summary Get user synergy with teammates
+// @Description Available sort fields: "games", "win_rate", "win_rate_delta", "avg_difficulty", "difficulty_delta"
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		GetSynergyRequest true "Body"
+// @Success 201 {object} 	GetSynergyResponse
+// @Router /analytics/users/synergy [post]
+func (c *controller) getSynergy(ctx *gin.Context) {
+	var req GetSynergyRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile, users.HideSocial) {
+		return
+	}
+
+	req.AuthUser, _ = util.GetUserFromCtx(ctx)
+
+	res, err := c.service.getSynergy(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// @Summary Get user perk pairings with teammates
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		GetPerkPairingsRequest true "Body"
+// @Success 201 {object} 	GetPerkPairingsResponse
+// @Router /analytics/users/synergy/perks [post]
+func (c *controller) getPerkPairings(ctx *gin.Context) {
+	var req GetPerkPairingsRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile, users.HideSocial) {
+		return
+	}
+
+	res, err := c.service.getPerkPairings(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// @Summary Compare users side by side
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		CompareUsersRequest true "Body"
+// @Success 201 {object} 	CompareUsersResponse
+// @Router /analytics/users/compare [post]
+func (c *controller) compareUsers(ctx *gin.Context) {
+	var req CompareUsersRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if c.isHidden(ctx, req.UserIds, users.HideProfile) {
+		return
+	}
+
+	req.AuthUser, _ = util.GetUserFromCtx(ctx)
+
+	res, err := c.service.compareUsers(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// @Summary Get user level and prestige progression for each perk
+// @Tags 	Analytics
+// @Produce json
+// @Param   body body 		GetProgressionRequest true "Body"
+// @Success 201 {object} 	GetProgressionResponse
+// @Router /analytics/users/progression [post]
+func (c *controller) getProgression(ctx *gin.Context) {
+	var req GetProgressionRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if c.isHidden(ctx, []int{req.UserId}, users.HideProfile) {
+		return
+	}
+
+	res, err := c.service.getProgression(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
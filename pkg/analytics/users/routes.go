@@ -1,36 +1,42 @@
 package users
 
 import (
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
 	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 )
 
 func RegisterRoutes(
 	r *gin.RouterGroup,
 	service *UserAnalyticsService,
-	memoryStore *persist.MemoryStore,
+	cacheStore cachestore.Store,
 ) {
 	controller := controller{
 		service: service,
 	}
 
-	routes := r.Group("/analytics/users")
+	routes := r.Group("/analytics/users",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead), middleware.OptionalAuthMiddleWave,
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
+
+	heavy := middleware.RateLimitMiddleWave(middleware.RateLimitHeavy)
 
 	routes.POST("/", controller.getUserAnalytics)
 	routes.POST("/perks", controller.getPerksAnalytics)
-	routes.POST("/perks/playtime",
-		middleware.OptionalAuthMiddleWave, controller.getPlaytimeHist)
-	routes.POST("/perks/accuracy",
-		middleware.OptionalAuthMiddleWave, controller.getAccuracyHist)
-	routes.POST("/teammates",
-		middleware.OptionalAuthMiddleWave, controller.getTeammates)
+	routes.POST("/perks/playtime", controller.getPlaytimeHist)
+	routes.POST("/perks/accuracy", controller.getAccuracyHist)
+	routes.POST("/teammates", controller.getTeammates)
+	routes.POST("/synergy", controller.getSynergy)
+	routes.POST("/synergy/perks", controller.getPerkPairings)
+	routes.POST("/compare", controller.compareUsers)
 	routes.POST("/maps", controller.getPlayedMaps)
 	routes.POST("/difficulty", controller.getDifficultyHist)
-	routes.POST("/sessions",
-		middleware.OptionalAuthMiddleWave, controller.getUserSessions)
+	routes.POST("/progression", controller.getProgression)
+	routes.POST("/sessions", heavy, controller.getUserSessions)
 	routes.POST("/lastseen",
-		middleware.AuthMiddleWave, controller.getLastSeenUsers)
+		heavy, middleware.AuthMiddleWave, controller.getLastSeenUsers)
 	routes.POST("/lastgameswithuser",
-		middleware.AuthMiddleWave, controller.getLastGamesWithUser)
+		heavy, middleware.AuthMiddleWave, controller.getLastGamesWithUser)
 }
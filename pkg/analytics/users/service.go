@@ -3,6 +3,7 @@ package users
 import (
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 	"github.com/theggv/kf2-stats-backend/pkg/matches/filter"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
 	"github.com/theggv/kf2-stats-backend/pkg/session/difficulty"
 	"github.com/theggv/kf2-stats-backend/pkg/users"
 )
@@ -46,8 +48,10 @@ func (s *UserAnalyticsService) GetUserAnalytics(
 ) (*UserAnalyticsResponse, error) {
 	res := UserAnalyticsResponse{}
 
-	conds := []string{"aggr.user_id = ?"}
-	args := []any{req.UserId}
+	conds := []string{users.LinkedIdsCond("aggr.user_id", req.UserId)}
+	args := []any{}
+
+	conds = append(conds, moderation.NotExcludedCond)
 
 	if req.From != nil && req.To != nil {
 		conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
@@ -87,66 +91,106 @@ func (s *UserAnalyticsService) GetUserAnalytics(
 	return &res, nil
 }
 
-func (s *UserAnalyticsService) GetPerksAnalytics(
-	req UserPerksAnalyticsRequest,
-) (*UserPerksAnalyticsResponse, error) {
-	conds := []string{}
+// Returns conditions of the perk stats, shared by perks and zedtime queries
+func (s *UserAnalyticsService) getPerksAnalyticsConds(req *UserPerksAnalyticsRequest) ([]string, []any) {
+	conds := []string{
+		moderation.NotExcludedCond,
+		users.LinkedIdsCond("aggr.user_id", req.UserId), "aggr.perk > 0",
+	}
 	args := []any{}
 
 	if req.From != nil && req.To != nil {
+		conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
 		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
-		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
-		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
-	} else {
-		args = append(args, "2000-01-01", "3000-01-01")
-		args = append(args, "2000-01-01", "3000-01-01")
-		args = append(args, "2000-01-01", "3000-01-01")
 	}
 
-	conds = append(conds, "aggr.user_id = ?", "aggr.perk > 0")
-	args = append(args, req.UserId)
+	if len(req.Perks) > 0 {
+		conds = append(conds, fmt.Sprintf("aggr.perk IN (%v)", util.IntArrayToString(req.Perks, ",")))
+	}
 
-	if req.From != nil && req.To != nil {
-		conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
-		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+	if len(req.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
 	}
 
+	if len(req.MapIds) > 0 {
+		conds = append(conds, fmt.Sprintf("session.map_id IN (%v)", util.IntArrayToString(req.MapIds, ",")))
+	}
+
+	return conds, args
+}
+
+func (s *UserAnalyticsService) GetPerksAnalytics(
+	req UserPerksAnalyticsRequest,
+) (*UserPerksAnalyticsResponse, error) {
+	conds, args := s.getPerksAnalyticsConds(&req)
+
+	// Accuracy is a trimmed mean like in get_avg_acc,
+	// but computed over the same filtered games as the rest of the stats
 	sql := fmt.Sprintf(`
+		WITH perk_sessions AS (
+			SELECT
+				aggr.perk, session.status, aggr.playtime_seconds,
+				aggr.waves_played, aggr.deaths,
+				aggr.shots_fired, aggr.shots_hit, aggr.shots_hs,
+				aggr.heals_given, aggr.damage_dealt, aggr.damage_taken,
+				kills.total AS kills, kills.large AS large_kills
+			FROM session
+			INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+			INNER JOIN session_aggregated_kills kills ON aggr.id = kills.id
+			WHERE %v
+		), accuracy_dist AS (
+			SELECT
+				perk, shots_fired, shots_hit, shots_hs,
+				CASE WHEN count(*) OVER (PARTITION BY perk) < 10 THEN 0 ELSE 0.1 END AS trim_percent,
+				cume_dist() OVER (PARTITION BY perk ORDER BY shots_hit / greatest(shots_fired, 1)) AS acc_dist,
+				cume_dist() OVER (PARTITION BY perk ORDER BY shots_hs / greatest(shots_hit, 1)) AS hs_dist
+			FROM perk_sessions
+			WHERE playtime_seconds >= 30
+		), accuracy AS (
+			SELECT
+				perk,
+				coalesce(sum(CASE WHEN acc_dist BETWEEN trim_percent AND 1 - trim_percent THEN shots_hit END), 0) /
+					greatest(coalesce(sum(CASE WHEN acc_dist BETWEEN trim_percent AND 1 - trim_percent THEN shots_fired END), 0), 1)
+					AS accuracy,
+				coalesce(sum(CASE WHEN hs_dist BETWEEN trim_percent AND 1 - trim_percent THEN shots_hs END), 0) /
+					greatest(coalesce(sum(CASE WHEN hs_dist BETWEEN trim_percent AND 1 - trim_percent THEN shots_hit END), 0), 1)
+					AS hs_accuracy
+			FROM accuracy_dist
+			GROUP BY perk
+		)
 		SELECT 
-			perk,
-			total_games,
-			get_user_games_count_by_perk(user_id, perk, 2, ?, ?) as total_wins,
-			total_kills,
-			large_kills,
-			total_waves,
-			total_deaths,
-			get_avg_acc(user_id, perk, ?, ?) as accuracy,
-			get_avg_hs_acc(user_id, perk, ?, ?) as hs_accuracy,
-			heals_given,
-			damage_dealt,
-			damage_taken,
-			total_minutes
+			t.perk,
+			t.total_games,
+			t.total_wins,
+			t.total_kills,
+			t.large_kills,
+			t.total_waves,
+			t.total_deaths,
+			round(coalesce(acc.accuracy, 0), 2) as accuracy,
+			round(coalesce(acc.hs_accuracy, 0), 2) as hs_accuracy,
+			t.heals_given,
+			t.damage_dealt,
+			t.damage_taken,
+			t.total_minutes
 		FROM (
 			SELECT
 				perk,
-				min(user_id) as user_id,
 				count(*) as total_games,
-				sum(kills.total) as total_kills,
-				sum(kills.large) as large_kills,
+				count(CASE WHEN status = 2 THEN 1 END) as total_wins,
+				sum(kills) as total_kills,
+				sum(large_kills) as large_kills,
 				sum(waves_played) as total_waves,
 				sum(deaths) as total_deaths,
 				sum(heals_given) as heals_given,
 				sum(damage_dealt) as damage_dealt,
 				sum(damage_taken) as damage_taken,
 				floor(coalesce(sum(playtime_seconds), 0) / 60) as total_minutes
-			FROM session
-			INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
-			INNER JOIN session_aggregated_kills kills ON aggr.id = kills.id
-			WHERE %v
+			FROM perk_sessions
 			GROUP BY perk
 		) t
-		WHERE total_kills > 0
-		ORDER BY perk`, strings.Join(conds, " AND "),
+		LEFT JOIN accuracy acc ON acc.perk = t.perk
+		WHERE t.total_kills > 0
+		ORDER BY t.perk`, strings.Join(conds, " AND "),
 	)
 
 	rows, err := s.db.Query(sql, args...)
@@ -187,19 +231,29 @@ func (s *UserAnalyticsService) GetPerksAnalytics(
 	return &res, nil
 }
 
+// Trimmed mean of commando zedtime length like in get_avg_zt, with filters of the request
 func (s *UserAnalyticsService) getAverageZedtime(
 	req UserPerksAnalyticsRequest) (float64, error) {
 
-	args := []any{}
-	args = append(args, req.UserId)
+	conds, args := s.getPerksAnalyticsConds(&req)
+	conds = append(conds, "aggr.perk = 2", "aggr.playtime_seconds >= 30")
 
-	if req.From != nil && req.To != nil {
-		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
-	} else {
-		args = append(args, "2000-01-01", "3000-01-01")
-	}
-
-	stmt := `SELECT get_avg_zt(?, ?, ?)`
+	stmt := fmt.Sprintf(`
+		SELECT round(coalesce(avg(avg_zedtime), 0), 2)
+		FROM (
+			SELECT
+				avg_zedtime,
+				CASE WHEN count(*) OVER () < 10 THEN 0 ELSE 0.1 END AS trim_percent,
+				cume_dist() OVER (ORDER BY avg_zedtime) AS dist
+			FROM (
+				SELECT round(aggr.zedtime_length / greatest(aggr.zedtime_count, 1), 2) AS avg_zedtime
+				FROM session
+				INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+				WHERE %v
+			) t
+		) t
+		WHERE dist BETWEEN trim_percent AND 1 - trim_percent`, strings.Join(conds, " AND "),
+	)
 
 	var averageZt float64
 	err := s.db.QueryRow(stmt, args...).Scan(&averageZt)
@@ -213,8 +267,9 @@ func (s *UserAnalyticsService) getPlaytimeHist(
 	conds := []string{}
 	args := []any{}
 
-	conds = append(conds, "aggr.user_id = ?")
-	args = append(args, req.UserId)
+	conds = append(conds, moderation.NotExcludedCond)
+
+	conds = append(conds, users.LinkedIdsCond("aggr.user_id", req.UserId))
 
 	if req.AuthUser != nil && req.AuthUser.UserId == req.UserId {
 		conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
@@ -323,8 +378,9 @@ func (s *UserAnalyticsService) getAccuracyHist(
 	conds := []string{}
 	args := []any{}
 
-	conds = append(conds, "aggr.user_id = ?")
-	args = append(args, req.UserId)
+	conds = append(conds, moderation.NotExcludedCond)
+
+	conds = append(conds, users.LinkedIdsCond("aggr.user_id", req.UserId))
 
 	conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
 	if req.From != nil && req.To != nil {
@@ -434,14 +490,15 @@ func (s *UserAnalyticsService) getTeammates(
 		WITH user_sessions AS (
 			SELECT DISTINCT session_id
 			FROM session_aggregated aggr
-			WHERE user_id = %v
+			INNER JOIN session ON session.id = aggr.session_id
+			WHERE %v AND %v
 		), user_played_with AS (
 			SELECT DISTINCT 
 				aggr.user_id as user_id,
 				cte.session_id as session_id
 			FROM user_sessions cte
 			INNER JOIN session_aggregated aggr ON aggr.session_id = cte.session_id
-			WHERE user_id != %v
+			WHERE NOT %v AND %v AND %v
 		), user_stats AS (
 			SELECT DISTINCT
 				cte.user_id as user_id,
@@ -470,7 +527,12 @@ func (s *UserAnalyticsService) getTeammates(
 		FROM pagination cte
 		INNER JOIN users ON users.id = cte.user_id
 		CROSS JOIN metadata
-		`, req.UserId, req.UserId, page*limit, limit,
+		`,
+		users.LinkedIdsCond("aggr.user_id", req.UserId), moderation.NotExcludedCond,
+		users.LinkedIdsCond("aggr.user_id", req.UserId),
+		users.NotHiddenCond("aggr.user_id", users.HideProfile, users.HideSocial),
+		moderation.NotBannedCond("aggr.user_id"),
+		page*limit, limit,
 	)
 
 	rows, err := s.db.Query(stmt)
@@ -485,7 +547,7 @@ func (s *UserAnalyticsService) getTeammates(
 			ResultsPerPage: limit,
 		},
 	}
-	steamIdSet := make(map[string]bool)
+	authIdSet := make(map[string]models.AuthType)
 
 	var count int
 	for rows.Next() {
@@ -501,9 +563,7 @@ func (s *UserAnalyticsService) getTeammates(
 			return nil, err
 		}
 
-		if item.Type == models.Steam {
-			steamIdSet[item.AuthId] = true
-		}
+		authIdSet[item.AuthId] = item.Type
 
 		res.Items = append(res.Items, &item)
 	}
@@ -511,20 +571,15 @@ func (s *UserAnalyticsService) getTeammates(
 	res.Metadata.TotalResults = count
 
 	{
-		steamIds := []string{}
-		for key := range steamIdSet {
-			steamIds = append(steamIds, key)
-		}
-
-		steamData, err := s.userService.GetSteamData(steamIds)
+		profileData, err := s.userService.GetProfileData(authIdSet)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, item := range res.Items {
-			if data, ok := steamData[item.AuthId]; ok {
-				item.Avatar = &data.Avatar
-				item.ProfileUrl = &data.ProfileUrl
+			if data, ok := profileData[item.AuthId]; ok {
+				item.Avatar = data.Avatar
+				item.ProfileUrl = data.ProfileUrl
 			}
 		}
 	}
@@ -538,13 +593,13 @@ func (s *UserAnalyticsService) getPlayedMaps(
 	conds := []string{}
 	args := []any{}
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	conds = append(conds,
-		"player_id = ?",
+		users.LinkedIdsCond("player_id", req.UserId),
 		"DATE(session.updated_at) BETWEEN ? AND ?",
 	)
 
-	args = append(args, req.UserId)
-
 	if req.From != nil && req.To != nil {
 		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
 	} else {
@@ -618,12 +673,10 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 	args := []any{}
 
 	userSessionConds = append(userSessionConds,
-		"player_id = ?",
+		users.LinkedIdsCond("player_id", req.UserId),
 		"DATE(session.updated_at) BETWEEN ? AND ?",
 	)
 
-	args = append(args, req.UserId)
-
 	if req.From != nil && req.To != nil {
 		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
 	} else {
@@ -642,8 +695,10 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 		)
 	}
 
-	userRatingConds := []string{"wsp.player_id != ?"}
-	args = append(args, req.UserId)
+	userRatingConds := []string{
+		"NOT " + users.LinkedIdsCond("wsp.player_id", req.UserId),
+		users.NotHiddenCond("wsp.player_id", users.HideProfile, users.HideSocial),
+	}
 
 	req.SearchText = strings.TrimSpace(req.SearchText)
 	if len(req.SearchText) > 0 {
@@ -703,7 +758,7 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 		INNER JOIN server ON server.id = session.server_id
 		INNER JOIN maps ON maps.id = session.map_id
 		INNER JOIN wave_stats ws ON ws.session_id = cte.session_id
-		INNER JOIN wave_stats_player user_wsp ON user_wsp.stats_id = ws.id AND user_wsp.player_id = %v
+		INNER JOIN wave_stats_player user_wsp ON user_wsp.stats_id = ws.id AND %v
 		INNER JOIN wave_stats_player last_seen ON last_seen.id = cte.wsp_id
 		INNER JOIN users ON users.id = cte.user_id
 		CROSS JOIN metadata
@@ -713,7 +768,7 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 		strings.Join(userRatingConds, " AND "),
 		page*limit, limit,
 		strings.Join(fields, ", "),
-		req.UserId,
+		users.LinkedIdsCond("user_wsp.player_id", req.UserId),
 	)
 
 	rows, err := s.db.Query(stmt, args...)
@@ -721,7 +776,7 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 		return nil, err
 	}
 
-	steamIdSet := make(map[string]bool)
+	authIdSet := make(map[string]models.AuthType)
 	res := GetLastSeenUsersResponse{
 		Items: []*GetLastSeenUsersResponseItem{},
 		Metadata: &models.PaginationResponse{
@@ -754,7 +809,7 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 			continue
 		}
 
-		steamIdSet[userProfile.AuthId] = true
+		authIdSet[userProfile.AuthId] = userProfile.Type
 
 		match.Session = sessionData
 		match.Details.UserData = &userData
@@ -796,20 +851,15 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 	}
 
 	{
-		steamIds := []string{}
-		for key := range steamIdSet {
-			steamIds = append(steamIds, key)
-		}
-
-		steamData, err := s.userService.GetSteamData(steamIds)
+		profileData, err := s.userService.GetProfileData(authIdSet)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, item := range res.Items {
-			if data, ok := steamData[item.UserProfile.AuthId]; ok {
-				item.UserProfile.Avatar = &data.Avatar
-				item.UserProfile.ProfileUrl = &data.ProfileUrl
+			if data, ok := profileData[item.UserProfile.AuthId]; ok {
+				item.UserProfile.Avatar = data.Avatar
+				item.UserProfile.ProfileUrl = data.ProfileUrl
 			}
 		}
 	}
@@ -821,6 +871,14 @@ func (s *UserAnalyticsService) getLastSeenUsers(
 
 func (s *UserAnalyticsService) getLastGamesWithUser(
 	req GetLastSessionsWithUserRequest,
+) (*GetLastSessionsWithUserResponse, error) {
+	return s.getLastGamesWithUsers(req, []int{req.OtherUserId})
+}
+
+// Returns sessions where user played together with every user from otherUserIds
+func (s *UserAnalyticsService) getLastGamesWithUsers(
+	req GetLastSessionsWithUserRequest,
+	otherUserIds []int,
 ) (*GetLastSessionsWithUserResponse, error) {
 	page, limit := util.ParsePagination(req.Pager)
 
@@ -828,13 +886,13 @@ func (s *UserAnalyticsService) getLastGamesWithUser(
 	conds := []string{}
 	args := []any{}
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	conds = append(conds,
-		"player_id = ?",
+		users.LinkedIdsCond("player_id", req.UserId),
 		"DATE(session.updated_at) BETWEEN ? AND ?",
 	)
 
-	args = append(args, req.UserId)
-
 	if req.From != nil && req.To != nil {
 		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
 	} else {
@@ -870,11 +928,13 @@ func (s *UserAnalyticsService) getLastGamesWithUser(
 			INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
 			WHERE %v
 		), other_user_sessions AS (
-			SELECT DISTINCT session.id as session_id
+			SELECT session.id as session_id
 			FROM session
 			INNER JOIN wave_stats ws ON ws.session_id = session.id
 			INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
-			WHERE wsp.player_id = %v
+			WHERE wsp.player_id IN (%v)
+			GROUP BY session.id
+			HAVING count(DISTINCT wsp.player_id) = %v
 		), user_played_with AS (
 			SELECT t1.session_id as session_id 
 			FROM user_sessions t1
@@ -894,16 +954,16 @@ func (s *UserAnalyticsService) getLastGamesWithUser(
 		INNER JOIN server ON server.id = session.server_id
 		INNER JOIN maps ON maps.id = session.map_id
 		INNER JOIN wave_stats ws ON ws.session_id = cte.session_id
-		INNER JOIN wave_stats_player user_wsp ON user_wsp.stats_id = ws.id AND user_wsp.player_id = %v
+		INNER JOIN wave_stats_player user_wsp ON user_wsp.stats_id = ws.id AND %v
 		CROSS JOIN metadata
 		WINDOW w AS (partition by session.id)
 		ORDER BY last_seen DESC, user_perk ASC
 		`,
 		strings.Join(conds, " AND "),
-		req.OtherUserId,
+		util.IntArrayToString(otherUserIds, ","), len(otherUserIds),
 		page*limit, limit,
 		strings.Join(fields, ", "),
-		req.UserId,
+		users.LinkedIdsCond("user_wsp.player_id", req.UserId),
 	)
 
 	rows, err := s.db.Query(stmt, args...)
@@ -990,8 +1050,9 @@ func (s *UserAnalyticsService) getUserSessions(
 	conds := []string{}
 	args := []any{}
 
-	conds = append(conds, "aggr.user_id = ?")
-	args = append(args, userId)
+	conds = append(conds, moderation.NotExcludedCond)
+
+	conds = append(conds, users.LinkedIdsCond("aggr.user_id", userId))
 
 	conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
 	if req.From != nil && req.To != nil {
@@ -1144,7 +1205,7 @@ func (s *UserAnalyticsService) getUserSessions(
 				aggr.perk AS perk
 			FROM pagination cte
 			INNER JOIN session_aggregated aggr ON aggr.session_id = cte.session_id
-			WHERE user_id = %v
+			WHERE %v
 		)
 		SELECT DISTINCT %v
 		FROM pagination cte
@@ -1154,7 +1215,7 @@ func (s *UserAnalyticsService) getUserSessions(
 		`, strings.Join(conds, " AND "),
 		sortBy, direction,
 		page*limit, limit,
-		userId,
+		users.LinkedIdsCond("aggr.user_id", userId),
 		strings.Join(fields, ", "),
 	)
 
@@ -1225,10 +1286,11 @@ func (s *UserAnalyticsService) getDifficultyHist(
 	conds := make([]string, 0)
 	args := make([]any, 0)
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	conds = append(conds, "session.started_at is not null")
 
-	conds = append(conds, "aggr.user_id = ?")
-	args = append(args, req.UserId)
+	conds = append(conds, users.LinkedIdsCond("aggr.user_id", req.UserId))
 
 	if len(req.ServerIds) > 0 {
 		conds = append(conds, fmt.Sprintf(
@@ -1289,3 +1351,516 @@ func (s *UserAnalyticsService) getDifficultyHist(
 
 	return analytics.ExecuteHistoricalQuery(s.db, stmt, args...)
 }
+
+func (s *UserAnalyticsService) getSynergySessionConds(
+	userId int, serverIds []int, from, to *time.Time,
+) ([]string, []any) {
+	conds := []string{users.LinkedIdsCond("aggr.user_id", userId)}
+	args := []any{}
+
+	conds = append(conds, moderation.NotExcludedCond)
+
+	if len(serverIds) > 0 {
+		conds = append(conds, fmt.Sprintf(
+			"session.server_id IN (%v)", util.IntArrayToString(serverIds, ",")),
+		)
+	}
+
+	if from != nil && to != nil {
+		conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
+		args = append(args, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+
+	return conds, args
+}
+
+func newSynergyStats(games, wins int, difficulty float64) SynergyStats {
+	stats := SynergyStats{
+		Games: games,
+		Wins:  wins,
+	}
+
+	if games > 0 {
+		stats.WinRate = float64(wins) / float64(games)
+		stats.AvgDifficulty = difficulty / float64(games)
+	}
+
+	return stats
+}
+
+func (s *UserAnalyticsService) getSynergy(
+	req GetSynergyRequest,
+) (*GetSynergyResponse, error) {
+	page, limit := req.Pager.Parse()
+
+	if req.AuthUser == nil || req.AuthUser.UserId != req.UserId {
+		page = 0
+		limit = 5
+	}
+
+	minGames := max(req.MinGames, 1)
+
+	defaultSortBy := "t.games"
+	fieldsMapper := map[string]string{
+		"games":            "t.games",
+		"win_rate":         "t.wins / t.games",
+		"win_rate_delta":   "t.wins / t.games - coalesce((totals.wins - t.wins) / nullif(totals.games - t.games, 0), 0)",
+		"avg_difficulty":   "t.difficulty / t.games",
+		"difficulty_delta": "t.difficulty / t.games - coalesce((totals.difficulty - t.difficulty) / nullif(totals.games - t.games, 0), 0)",
+	}
+	sortBy, direction := req.SortBy.Transform(fieldsMapper, defaultSortBy)
+	if req.SortBy.Field == "" {
+		direction = "DESC"
+	}
+
+	conds, args := s.getSynergySessionConds(req.UserId, req.ServerIds, req.From, req.To)
+	args = append(args, minGames, page*limit, limit)
+
+	stmt := fmt.Sprintf(`
+		WITH user_sessions AS (
+			SELECT DISTINCT
+				session.id AS session_id,
+				session.status AS status,
+				coalesce(diff.final_score * diff.final_score, 0) AS difficulty
+			FROM session_aggregated aggr
+			INNER JOIN session ON session.id = aggr.session_id
+			LEFT JOIN session_diff diff ON diff.session_id = session.id
+			WHERE %v
+		), totals AS (
+			SELECT
+				count(*) AS games,
+				count(CASE WHEN status = 2 THEN 1 END) AS wins,
+				coalesce(sum(difficulty), 0) AS difficulty
+			FROM user_sessions
+		), played_with AS (
+			SELECT DISTINCT
+				aggr.user_id AS user_id,
+				cte.session_id AS session_id,
+				cte.status AS status,
+				cte.difficulty AS difficulty
+			FROM user_sessions cte
+			INNER JOIN session_aggregated aggr ON aggr.session_id = cte.session_id
+			WHERE NOT %v AND %v AND %v
+		), teammates AS (
+			SELECT
+				user_id,
+				count(*) AS games,
+				count(CASE WHEN status = 2 THEN 1 END) AS wins,
+				sum(difficulty) AS difficulty
+			FROM played_with
+			GROUP BY user_id
+			HAVING count(*) >= ?
+		)
+		SELECT
+			users.id, users.name, users.auth_type, users.auth_id,
+			t.games, t.wins, t.difficulty,
+			totals.games, totals.wins, totals.difficulty,
+			count(*) OVER () AS total_results
+		FROM teammates t
+		INNER JOIN users ON users.id = t.user_id
+		CROSS JOIN totals
+		ORDER BY %v %v, t.user_id ASC
+		LIMIT ?, ?`,
+		strings.Join(conds, " AND "), users.LinkedIdsCond("aggr.user_id", req.UserId),
+		users.NotHiddenCond("aggr.user_id", users.HideProfile, users.HideSocial),
+		moderation.NotBannedCond("aggr.user_id"),
+		sortBy, direction,
+	)
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := GetSynergyResponse{
+		Items: []*GetSynergyResponseItem{},
+		Metadata: &models.PaginationResponse{
+			Page:           page,
+			ResultsPerPage: limit,
+		},
+	}
+	authIdSet := make(map[string]models.AuthType)
+
+	for rows.Next() {
+		item := GetSynergyResponseItem{}
+
+		var games, wins, totalGames, totalWins int
+		var difficulty, totalDifficulty float64
+
+		err := rows.Scan(
+			&item.Id, &item.Name,
+			&item.Type, &item.AuthId,
+			&games, &wins, &difficulty,
+			&totalGames, &totalWins, &totalDifficulty,
+			&res.Metadata.TotalResults,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Total = newSynergyStats(totalGames, totalWins, totalDifficulty)
+
+		item.With = newSynergyStats(games, wins, difficulty)
+		item.Without = newSynergyStats(
+			totalGames-games, totalWins-wins, totalDifficulty-difficulty,
+		)
+		item.WinRateDelta = item.With.WinRate - item.Without.WinRate
+		item.DifficultyDelta = item.With.AvgDifficulty - item.Without.AvgDifficulty
+
+		authIdSet[item.AuthId] = item.Type
+
+		res.Items = append(res.Items, &item)
+	}
+
+	{
+		profileData, err := s.userService.GetProfileData(authIdSet)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range res.Items {
+			if data, ok := profileData[item.AuthId]; ok {
+				item.Avatar = data.Avatar
+				item.ProfileUrl = data.ProfileUrl
+			}
+		}
+	}
+
+	return &res, nil
+}
+
+func (s *UserAnalyticsService) getPerkPairings(
+	req GetPerkPairingsRequest,
+) (*GetPerkPairingsResponse, error) {
+	minGames := max(req.MinGames, 1)
+
+	conds, args := s.getSynergySessionConds(req.UserId, req.ServerIds, req.From, req.To)
+	args = append(args, minGames)
+
+	stmt := fmt.Sprintf(`
+		WITH user_perks AS (
+			SELECT DISTINCT
+				session.id AS session_id,
+				session.status AS status,
+				coalesce(diff.final_score * diff.final_score, 0) AS difficulty,
+				aggr.perk AS perk
+			FROM session_aggregated aggr
+			INNER JOIN session ON session.id = aggr.session_id
+			LEFT JOIN session_diff diff ON diff.session_id = session.id
+			WHERE %v
+		), pairings AS (
+			SELECT DISTINCT
+				cte.session_id AS session_id,
+				cte.status AS status,
+				cte.difficulty AS difficulty,
+				cte.perk AS perk,
+				aggr.perk AS teammate_perk
+			FROM user_perks cte
+			INNER JOIN session_aggregated aggr ON aggr.session_id = cte.session_id
+			WHERE NOT %v AND %v
+		)
+		SELECT
+			perk, teammate_perk,
+			count(*) AS games,
+			count(CASE WHEN status = 2 THEN 1 END) AS wins,
+			sum(difficulty) AS difficulty
+		FROM pairings
+		GROUP BY perk, teammate_perk
+		HAVING count(*) >= ?
+		ORDER BY wins / games DESC, games DESC`,
+		strings.Join(conds, " AND "), users.LinkedIdsCond("aggr.user_id", req.UserId),
+		moderation.NotBannedCond("aggr.user_id"),
+	)
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := GetPerkPairingsResponse{
+		Items: []*GetPerkPairingsResponseItem{},
+	}
+
+	for rows.Next() {
+		item := GetPerkPairingsResponseItem{}
+
+		var games, wins int
+		var difficulty float64
+
+		err := rows.Scan(&item.Perk, &item.TeammatePerk, &games, &wins, &difficulty)
+		if err != nil {
+			return nil, err
+		}
+
+		item.SynergyStats = newSynergyStats(games, wins, difficulty)
+
+		res.Items = append(res.Items, &item)
+	}
+
+	return &res, nil
+}
+
+func (s *UserAnalyticsService) getSharedSessionsStats(
+	req *CompareUsersRequest,
+) (*CompareUsersResponseShared, error) {
+	conds := []string{fmt.Sprintf(
+		"aggr.user_id IN (%v)", util.IntArrayToString(req.UserIds, ",")),
+	}
+	args := []any{}
+
+	conds = append(conds, moderation.NotExcludedCond)
+
+	if req.From != nil && req.To != nil {
+		conds = append(conds, "DATE(session.updated_at) BETWEEN ? AND ?")
+		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+	}
+
+	if len(req.ServerIds) > 0 {
+		conds = append(conds, fmt.Sprintf(
+			"session.server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")),
+		)
+	}
+
+	if len(req.MapIds) > 0 {
+		conds = append(conds, fmt.Sprintf(
+			"session.map_id IN (%v)", util.IntArrayToString(req.MapIds, ",")),
+		)
+	}
+
+	args = append(args, len(req.UserIds))
+
+	stmt := fmt.Sprintf(`
+		SELECT
+			count(*) AS games,
+			count(CASE WHEN status = 2 THEN 1 END) AS wins
+		FROM (
+			SELECT session.id, session.status
+			FROM session
+			INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+			WHERE %v
+			GROUP BY session.id
+			HAVING count(DISTINCT aggr.user_id) = ?
+		) t`, strings.Join(conds, " AND "),
+	)
+
+	res := CompareUsersResponseShared{}
+
+	err := s.db.QueryRow(stmt, args...).Scan(&res.Games, &res.Wins)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Games > 0 {
+		res.WinRate = float64(res.Wins) / float64(res.Games)
+	}
+
+	return &res, nil
+}
+
+func (s *UserAnalyticsService) compareUsers(
+	req CompareUsersRequest,
+) (*CompareUsersResponse, error) {
+	userIds := []int{}
+	for _, userId := range req.UserIds {
+		if !slices.Contains(userIds, userId) {
+			userIds = append(userIds, userId)
+		}
+	}
+	req.UserIds = userIds
+
+	if len(req.UserIds) < 2 || len(req.UserIds) > 6 {
+		return nil, fmt.Errorf("expected from 2 to 6 unique user ids, got %v", len(req.UserIds))
+	}
+
+	if req.Period == 0 {
+		req.Period = analytics.Date
+	}
+
+	res := CompareUsersResponse{
+		Users: []*CompareUsersResponseUser{},
+	}
+
+	for _, userId := range req.UserIds {
+		item := CompareUsersResponseUser{
+			UserId: userId,
+		}
+
+		perks, err := s.GetPerksAnalytics(UserPerksAnalyticsRequest{
+			UserId:    userId,
+			From:      req.From,
+			To:        req.To,
+			Perks:     req.Perks,
+			ServerIds: req.ServerIds,
+			MapIds:    req.MapIds,
+		})
+		if err != nil {
+			return nil, err
+		}
+		item.Perks = perks
+
+		accuracy, err := s.getAccuracyHist(UserPerkHistRequest{
+			UserId:    userId,
+			From:      req.From,
+			To:        req.To,
+			Perks:     req.Perks,
+			ServerIds: req.ServerIds,
+			MapIds:    req.MapIds,
+		})
+		if err != nil {
+			return nil, err
+		}
+		item.Accuracy = accuracy
+
+		difficulty, err := s.getDifficultyHist(GetUserDifficultyHistRequest{
+			UserId:    userId,
+			From:      req.From,
+			To:        req.To,
+			Period:    req.Period,
+			Perks:     req.Perks,
+			ServerIds: req.ServerIds,
+			MapIds:    req.MapIds,
+		})
+		if err != nil {
+			return nil, err
+		}
+		item.Difficulty = difficulty
+
+		res.Users = append(res.Users, &item)
+	}
+
+	isParticipant := req.AuthUser != nil && slices.Contains(req.UserIds, req.AuthUser.UserId)
+
+	// Shared games tell who played together, so they respect hidden social data of others
+	if !isParticipant {
+		for _, userId := range req.UserIds {
+			err := s.userService.CheckPrivacy(req.AuthUser, userId, users.HideSocial)
+			if err == users.ErrPrivateProfile {
+				return &res, nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	shared, err := s.getSharedSessionsStats(&req)
+	if err != nil {
+		return nil, err
+	}
+	res.Shared = shared
+
+	// Same as lastgameswithuser, sessions are shown to participants only
+	if isParticipant {
+		otherUserIds := []int{}
+		for _, userId := range req.UserIds {
+			if userId != req.AuthUser.UserId {
+				otherUserIds = append(otherUserIds, userId)
+			}
+		}
+
+		sessions, err := s.getLastGamesWithUsers(GetLastSessionsWithUserRequest{
+			UserId:    req.AuthUser.UserId,
+			Perks:     req.Perks,
+			ServerIds: req.ServerIds,
+			From:      req.From,
+			To:        req.To,
+			Pager:     req.Pager,
+		}, otherUserIds)
+		if err != nil {
+			return nil, err
+		}
+		res.Shared.Sessions = sessions
+	}
+
+	return &res, nil
+}
+
+func (s *UserAnalyticsService) getProgression(
+	req GetProgressionRequest,
+) (*GetProgressionResponse, error) {
+	conds := []string{users.LinkedIdsCond("wsp.player_id", req.UserId), "wsp.perk > 0"}
+	args := []any{}
+
+	if len(req.Perks) > 0 {
+		conds = append(conds, fmt.Sprintf("wsp.perk IN (%v)", util.IntArrayToString(req.Perks, ",")))
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT perk, prestige, level, created_at, session_id
+		FROM (
+			SELECT
+				wsp.perk, wsp.prestige, wsp.level, wsp.created_at, ws.session_id,
+				ROW_NUMBER() OVER (PARTITION BY wsp.perk, wsp.prestige, wsp.level ORDER BY wsp.id) AS rn
+			FROM wave_stats_player wsp
+			INNER JOIN wave_stats ws ON ws.id = wsp.stats_id
+			WHERE %v
+		) t
+		WHERE rn = 1
+		ORDER BY perk, prestige, level`, strings.Join(conds, " AND "),
+	)
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := GetProgressionResponse{
+		Items: []*ProgressionPerk{},
+	}
+
+	var current *ProgressionPerk
+	for rows.Next() {
+		var perk models.Perk
+		step := ProgressionStep{}
+
+		err := rows.Scan(&perk, &step.Prestige, &step.Level, &step.FirstSeen, &step.SessionId)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.Perk != perk {
+			current = &ProgressionPerk{
+				Perk:      perk,
+				Prestiges: []*ProgressionStep{},
+				Steps:     []*ProgressionStep{},
+			}
+			res.Items = append(res.Items, current)
+		}
+
+		current.Steps = append(current.Steps, &step)
+	}
+
+	for _, item := range res.Items {
+		first := item.Steps[0]
+		last := item.Steps[len(item.Steps)-1]
+
+		item.Prestige = last.Prestige
+		item.Level = last.Level
+		item.FirstSeen = first.FirstSeen
+
+		for _, step := range item.Steps {
+			if step.FirstSeen.Before(item.FirstSeen) {
+				item.FirstSeen = step.FirstSeen
+			}
+
+			if len(item.Prestiges) == 0 || item.Prestiges[len(item.Prestiges)-1].Prestige != step.Prestige {
+				item.Prestiges = append(item.Prestiges, step)
+			} else if step.FirstSeen.Before(item.Prestiges[len(item.Prestiges)-1].FirstSeen) {
+				item.Prestiges[len(item.Prestiges)-1] = step
+			}
+
+			if item.MaxedAt == nil && step.Level >= models.MaxPerkLevel {
+				item.MaxedAt = &step.FirstSeen
+			}
+		}
+
+		if item.MaxedAt != nil && first.Prestige == 0 && first.Level < models.MaxPerkLevel {
+			timeToMax := int(item.MaxedAt.Sub(first.FirstSeen).Seconds())
+			item.TimeToMaxSeconds = &timeToMax
+		}
+	}
+
+	return &res, nil
+}
@@ -29,6 +29,10 @@ type UserPerksAnalyticsRequest struct {
 
 	From *time.Time `json:"date_from"`
 	To   *time.Time `json:"date_to"`
+
+	Perks     []int `json:"perks"`
+	ServerIds []int `json:"server_ids"`
+	MapIds    []int `json:"map_ids"`
 }
 
 type UserPerksAnalyticsResponseItem struct {
@@ -207,3 +211,147 @@ type GetUserDifficultyHistRequest struct {
 	ServerIds []int `json:"server_ids"`
 	MapIds    []int `json:"map_ids"`
 }
+
+type GetSynergyRequest struct {
+	UserId int `json:"user_id" binding:"required"`
+
+	ServerIds []int `json:"server_ids"`
+
+	From *time.Time `json:"date_from"`
+	To   *time.Time `json:"date_to"`
+
+	MinGames int `json:"min_games"`
+
+	SortBy models.SortByRequest     `json:"sort_by"`
+	Pager  models.PaginationRequest `json:"pager"`
+
+	AuthUser *models.TokenPayload `json:"-"`
+}
+
+type SynergyStats struct {
+	Games int `json:"games"`
+	Wins  int `json:"wins"`
+
+	WinRate       float64 `json:"win_rate"`
+	AvgDifficulty float64 `json:"avg_difficulty"`
+}
+
+type GetSynergyResponseItem struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+
+	ProfileUrl *string `json:"profile_url"`
+	Avatar     *string `json:"avatar"`
+
+	With    SynergyStats `json:"with"`
+	Without SynergyStats `json:"without"`
+
+	WinRateDelta    float64 `json:"win_rate_delta"`
+	DifficultyDelta float64 `json:"difficulty_delta"`
+
+	AuthId string          `json:"-"`
+	Type   models.AuthType `json:"-"`
+}
+
+type GetSynergyResponse struct {
+	Total SynergyStats `json:"total"`
+
+	Items    []*GetSynergyResponseItem  `json:"items"`
+	Metadata *models.PaginationResponse `json:"metadata"`
+}
+
+type GetPerkPairingsRequest struct {
+	UserId int `json:"user_id" binding:"required"`
+
+	ServerIds []int `json:"server_ids"`
+
+	From *time.Time `json:"date_from"`
+	To   *time.Time `json:"date_to"`
+
+	MinGames int `json:"min_games"`
+}
+
+type GetPerkPairingsResponseItem struct {
+	Perk         models.Perk `json:"perk"`
+	TeammatePerk models.Perk `json:"teammate_perk"`
+
+	SynergyStats
+}
+
+type GetPerkPairingsResponse struct {
+	Items []*GetPerkPairingsResponseItem `json:"items"`
+}
+
+type CompareUsersRequest struct {
+	UserIds []int `json:"user_ids" binding:"required"`
+
+	From   *time.Time           `json:"date_from"`
+	To     *time.Time           `json:"date_to"`
+	Period analytics.TimePeriod `json:"period"`
+
+	Perks     []int `json:"perks"`
+	ServerIds []int `json:"server_ids"`
+	MapIds    []int `json:"map_ids"`
+
+	// Pagination of shared sessions
+	Pager models.PaginationRequest `json:"pager"`
+
+	AuthUser *models.TokenPayload `json:"-"`
+}
+
+type CompareUsersResponseUser struct {
+	UserId int `json:"user_id"`
+
+	Perks      *UserPerksAnalyticsResponse `json:"perks"`
+	Accuracy   *AccuracyHist               `json:"accuracy"`
+	Difficulty []*models.PeriodData        `json:"difficulty"`
+}
+
+type CompareUsersResponseShared struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+
+	// Available only if authorized user is one of compared users
+	Sessions *GetLastSessionsWithUserResponse `json:"sessions"`
+}
+
+type CompareUsersResponse struct {
+	Users []*CompareUsersResponseUser `json:"users"`
+	// Empty if any of compared users hides social data, unless authorized user is one of them
+	Shared *CompareUsersResponseShared `json:"shared"`
+}
+
+type GetProgressionRequest struct {
+	UserId int `json:"user_id" binding:"required"`
+
+	Perks []int `json:"perks"`
+}
+
+type ProgressionStep struct {
+	Prestige int `json:"prestige"`
+	Level    int `json:"level"`
+
+	FirstSeen time.Time `json:"first_seen"`
+	SessionId int       `json:"session_id"`
+}
+
+type ProgressionPerk struct {
+	Perk models.Perk `json:"perk"`
+
+	Prestige int `json:"prestige"`
+	Level    int `json:"level"`
+
+	FirstSeen time.Time `json:"first_seen"`
+
+	// Time from the first wave to the max level, if progression was tracked from below max
+	MaxedAt          *time.Time `json:"maxed_at"`
+	TimeToMaxSeconds *int       `json:"time_to_max_seconds"`
+
+	Prestiges []*ProgressionStep `json:"prestiges"`
+	Steps     []*ProgressionStep `json:"steps"`
+}
+
+type GetProgressionResponse struct {
+	Items []*ProgressionPerk `json:"items"`
+}
@@ -0,0 +1,92 @@
+package apitokens
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+type controller struct {
+	service *ApiTokensService
+}
+
+// @Summary Get personal api tokens of the current user
+// @Tags 	ApiTokens
+// @Produce json
+// @Success 200 {object} GetApiTokensResponse
+// @Router /api-tokens [get]
+func (c *controller) getTokens(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	items, err := c.service.GetByUser(user.UserId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetApiTokensResponse{
+		Items: items,
+	})
+}
+
+// @Summary Create personal api token, the token is returned only once
+// @Tags 	ApiTokens
+// @Produce json
+// @Param   body body 		CreateApiTokenRequest true "Body"
+// @Success 201 {object} 	CreateApiTokenResponse
+// @Router /api-tokens [post]
+func (c *controller) createToken(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	var req CreateApiTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.Create(user.UserId, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// @Summary Revoke personal api token
+// @Tags 	ApiTokens
+// @Produce json
+// @Param   id path int true "Token id"
+// @Success 200
+// @Router /api-tokens/{id} [delete]
+func (c *controller) revokeToken(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.Revoke(user.UserId, id)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,33 @@
+package apitokens
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type ApiToken struct {
+	Id     int               `json:"id"`
+	Name   string            `json:"name"`
+	Prefix string            `json:"prefix"`
+	Scopes []models.ApiScope `json:"scopes"`
+
+	// Requests per minute
+	RateLimit  int        `json:"rate_limit"`
+	UsageCount int64      `json:"usage_count"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type cachedGrant struct {
+	grant     *models.ApiTokenGrant
+	rateLimit int
+	expiresAt time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
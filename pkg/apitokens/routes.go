@@ -0,0 +1,21 @@
+package apitokens
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, service *ApiTokensService) {
+	controller := controller{
+		service: service,
+	}
+
+	middleware.SetApiTokenValidator(service)
+
+	// Tokens can't be managed with tokens
+	routes := r.Group("/api-tokens", middleware.AuthMiddleWave)
+
+	routes.GET("/", controller.getTokens)
+	routes.POST("/", controller.createToken)
+	routes.DELETE("/:id", controller.revokeToken)
+}
@@ -0,0 +1,341 @@
+package apitokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+const (
+	tokenPrefix      = "kf2_"
+	maxTokensPerUser = 10
+	defaultRateLimit = 60
+	maxRateLimit     = 600
+	// Revoked tokens stop working on other instances after the grant expires
+	grantCacheTTL = 1 * time.Minute
+)
+
+type usage struct {
+	count      int64
+	lastUsedAt time.Time
+}
+
+type ApiTokensService struct {
+	db *sql.DB
+
+	mu sync.Mutex
+	// Token hash -> validated grant
+	grants map[string]*cachedGrant
+	// Token id -> requests in the current minute
+	windows map[int]*rateWindow
+	// Token id -> requests since the last flush
+	usage map[int]*usage
+}
+
+func NewApiTokensService(db *sql.DB) *ApiTokensService {
+	service := ApiTokensService{
+		db:      db,
+		grants:  map[string]*cachedGrant{},
+		windows: map[int]*rateWindow{},
+		usage:   map[int]*usage{},
+	}
+
+	go service.initUsageFlush(30 * time.Second)
+
+	return &service
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return tokenPrefix + hex.EncodeToString(buf), nil
+}
+
+func validateScopes(scopes []models.ApiScope) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(models.ApiScopes, scope) {
+			return fmt.Errorf("unknown scope %v", scope)
+		}
+	}
+
+	return nil
+}
+
+func (s *ApiTokensService) Create(userId int, req CreateApiTokenRequest) (*CreateApiTokenResponse, error) {
+	if len(req.Name) > 64 {
+		return nil, errors.New("name is too long")
+	}
+
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+
+	if req.RateLimit == 0 {
+		req.RateLimit = defaultRateLimit
+	}
+
+	if req.RateLimit < 0 || req.RateLimit > maxRateLimit {
+		return nil, fmt.Errorf("rate_limit should be between 1 and %v", maxRateLimit)
+	}
+
+	var count int
+	err := s.db.QueryRow(`
+		SELECT count(*) FROM users_api_token
+		WHERE user_id = ? AND revoked_at IS NULL`, userId,
+	).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= maxTokensPerUser {
+		return nil, fmt.Errorf("you can't have more than %v active tokens", maxTokensPerUser)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+
+	res, err := s.db.Exec(`
+		INSERT INTO users_api_token (user_id, name, prefix, token_hash, scopes, rate_limit)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		userId, req.Name, token[:len(tokenPrefix)+6], hashToken(token),
+		strings.Join(scopes, ","), req.RateLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateApiTokenResponse{
+		Id:    int(id),
+		Token: token,
+	}, nil
+}
+
+func (s *ApiTokensService) GetByUser(userId int) ([]*ApiToken, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, prefix, scopes, rate_limit, usage_count, last_used_at, created_at, revoked_at
+		FROM users_api_token
+		WHERE user_id = ?
+		ORDER BY revoked_at IS NOT NULL, created_at DESC`, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*ApiToken{}
+	for rows.Next() {
+		item := ApiToken{}
+		var scopes string
+
+		err := rows.Scan(
+			&item.Id, &item.Name, &item.Prefix, &scopes,
+			&item.RateLimit, &item.UsageCount, &item.LastUsedAt,
+			&item.CreatedAt, &item.RevokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		item.Scopes = strings.Split(scopes, ",")
+
+		items = append(items, &item)
+	}
+
+	// Include requests which are not flushed yet
+	s.mu.Lock()
+	for _, item := range items {
+		if pending, ok := s.usage[item.Id]; ok {
+			item.UsageCount += pending.count
+			item.LastUsedAt = &pending.lastUsedAt
+		}
+	}
+	s.mu.Unlock()
+
+	return items, nil
+}
+
+func (s *ApiTokensService) Revoke(userId, id int) error {
+	res, err := s.db.Exec(`
+		UPDATE users_api_token SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL`, id, userId,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("token %v not found", id)
+	}
+
+	s.mu.Lock()
+	for hash, item := range s.grants {
+		if item.grant.TokenId == id {
+			delete(s.grants, hash)
+		}
+	}
+	delete(s.windows, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *ApiTokensService) getGrant(tokenHash string) (*cachedGrant, error) {
+	s.mu.Lock()
+	item, ok := s.grants[tokenHash]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(item.expiresAt) {
+		return item, nil
+	}
+
+	grant := models.ApiTokenGrant{}
+	var scopes string
+	var rateLimit int
+
+	err := s.db.QueryRow(`
+		SELECT t.id, t.scopes, t.rate_limit, users.id, users.name, users.auth_type, users.auth_id
+		FROM users_api_token t
+		INNER JOIN users ON users.id = t.user_id
+		WHERE t.token_hash = ? AND t.revoked_at IS NULL`, tokenHash,
+	).Scan(
+		&grant.TokenId, &scopes, &rateLimit,
+		&grant.User.UserId, &grant.User.Name, &grant.User.AuthType, &grant.User.AuthId,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid token")
+	} else if err != nil {
+		return nil, err
+	}
+
+	grant.Scopes = strings.Split(scopes, ",")
+	if grant.User.AuthType == models.Steam {
+		grant.User.SteamId = grant.User.AuthId
+	}
+
+	item = &cachedGrant{
+		grant:     &grant,
+		rateLimit: rateLimit,
+		expiresAt: time.Now().Add(grantCacheTTL),
+	}
+
+	s.mu.Lock()
+	s.grants[tokenHash] = item
+	s.mu.Unlock()
+
+	return item, nil
+}
+
+// Checks the token has the scope and is within its rate limit, counts accepted requests
+func (s *ApiTokensService) ValidateApiToken(token string, scope models.ApiScope) (*models.ApiTokenGrant, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, errors.New("invalid token")
+	}
+
+	item, err := s.getGrant(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if !item.grant.HasScope(scope) {
+		return nil, middleware.ErrApiTokenScope
+	}
+
+	now := time.Now()
+	tokenId := item.grant.TokenId
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	window, ok := s.windows[tokenId]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		s.windows[tokenId] = window
+	}
+
+	if window.count >= item.rateLimit {
+		return nil, middleware.ErrApiTokenRateLimited
+	}
+
+	window.count += 1
+
+	if _, ok := s.usage[tokenId]; !ok {
+		s.usage[tokenId] = &usage{}
+	}
+	s.usage[tokenId].count += 1
+	s.usage[tokenId].lastUsedAt = now
+
+	return item.grant, nil
+}
+
+func (s *ApiTokensService) initUsageFlush(updateTime time.Duration) {
+	for range time.Tick(updateTime) {
+		s.flushUsage()
+	}
+}
+
+func (s *ApiTokensService) flushUsage() {
+	now := time.Now()
+
+	s.mu.Lock()
+	items := s.usage
+	s.usage = map[int]*usage{}
+
+	for id, window := range s.windows {
+		if now.Sub(window.start) >= time.Minute {
+			delete(s.windows, id)
+		}
+	}
+	for hash, item := range s.grants {
+		if now.After(item.expiresAt) {
+			delete(s.grants, hash)
+		}
+	}
+	s.mu.Unlock()
+
+	for id, item := range items {
+		_, err := s.db.Exec(`
+			UPDATE users_api_token
+			SET usage_count = usage_count + ?, last_used_at = ?
+			WHERE id = ?`, item.count, item.lastUsedAt, id,
+		)
+		if err != nil {
+			fmt.Printf("[apitokens] token %v: %v\n", id, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package apitokens
+
+import "github.com/theggv/kf2-stats-backend/pkg/common/models"
+
+type CreateApiTokenRequest struct {
+	Name   string            `json:"name" binding:"required"`
+	Scopes []models.ApiScope `json:"scopes" binding:"required"`
+
+	// Requests per minute, defaults to 60
+	RateLimit int `json:"rate_limit"`
+}
+
+type CreateApiTokenResponse struct {
+	Id int `json:"id"`
+
+	// Shown only once, only the hash is stored
+	Token string `json:"token"`
+}
+
+type GetApiTokensResponse struct {
+	Items []*ApiToken `json:"items"`
+}
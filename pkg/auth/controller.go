@@ -2,9 +2,11 @@ package auth
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/theggv/kf2-stats-backend/pkg/common/config"
+	"github.com/theggv/kf2-stats-backend/pkg/common/egsapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/steamapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 )
@@ -13,6 +15,13 @@ type authController struct {
 	service *AuthService
 }
 
+func getClientInfo(ctx *gin.Context) ClientInfo {
+	return ClientInfo{
+		Device: ctx.Request.UserAgent(),
+		Ip:     ctx.ClientIP(),
+	}
+}
+
 // @Summary Ping
 // @Tags 	Auth
 // @Produce json
@@ -35,7 +44,37 @@ func (c *authController) login(ctx *gin.Context) {
 		return
 	}
 
-	res, err := c.service.Login(req)
+	res, err := c.service.Login(req, getClientInfo(ctx))
+	if err != nil {
+		ctx.String(http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = util.SetCookies(ctx, res.RefreshToken, config.Instance.JwtRefreshExpiresIn)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, &AuthResponse{
+		AccessToken: res.AccessToken,
+	})
+}
+
+// @Summary Login via Epic Account Services authorization code
+// @Tags 	Auth
+// @Produce json
+// @Param   user body    egsapi.ValidateAuthCodeRequest true "User JSON"
+// @Success 201 {object} AuthResponse
+// @Router /auth/login/egs [post]
+func (c *authController) loginEgs(ctx *gin.Context) {
+	var req egsapi.ValidateAuthCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.LoginEgs(req, getClientInfo(ctx))
 	if err != nil {
 		ctx.String(http.StatusUnauthorized, err.Error())
 		return
@@ -64,7 +103,7 @@ func (c *authController) refresh(ctx *gin.Context) {
 		return
 	}
 
-	res, err := c.service.Refresh(cookie.Value)
+	res, err := c.service.Refresh(cookie.Value, getClientInfo(ctx))
 	if err != nil {
 		ctx.String(http.StatusUnauthorized, err.Error())
 		return
@@ -101,3 +140,141 @@ func (c *authController) logout(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusCreated, nil)
 }
+
+// @Summary Logout from every device
+// @Tags 	Auth
+// @Produce json
+// @Success 201
+// @Router /auth/logout/all [post]
+func (c *authController) logoutEverywhere(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	err := c.service.LogoutEverywhere(user.UserId)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, nil)
+}
+
+// @Summary Get active device sessions of the current user
+// @Tags 	Auth
+// @Produce json
+// @Success 200 {object} GetSessionsResponse
+// @Router /auth/sessions [get]
+func (c *authController) getSessions(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	refreshToken := ""
+	if cookie, err := ctx.Request.Cookie("refreshToken"); err == nil {
+		refreshToken = cookie.Value
+	}
+
+	items, err := c.service.GetSessions(user.UserId, refreshToken)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetSessionsResponse{
+		Items: items,
+	})
+}
+
+// @Summary Revoke device session of the current user
+// @Tags 	Auth
+// @Produce json
+// @Param   id path int true "Session id"
+// @Success 200
+// @Router /auth/sessions/{id} [delete]
+func (c *authController) revokeSession(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.RevokeSession(user.UserId, id)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Get all granted roles
+// @Tags 	Auth
+// @Produce json
+// @Success 200 {object} GetRolesResponse
+// @Router /auth/roles [get]
+func (c *authController) getRoles(ctx *gin.Context) {
+	items, err := c.service.GetRoles()
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetRolesResponse{
+		Items: items,
+	})
+}
+
+// @Summary Grant role to user
+// @Tags 	Auth
+// @Produce json
+// @Param   body body    GrantRoleRequest true "Body"
+// @Success 201
+// @Router /auth/roles [post]
+func (c *authController) grantRole(ctx *gin.Context) {
+	var req GrantRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := c.service.GrantRole(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{})
+}
+
+// @Summary Revoke role from user
+// @Tags 	Auth
+// @Produce json
+// @Param   body body    GrantRoleRequest true "Body"
+// @Success 200
+// @Router /auth/roles [delete]
+func (c *authController) revokeRole(ctx *gin.Context) {
+	var req GrantRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := c.service.RevokeRole(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
@@ -1,6 +1,33 @@
 package auth
 
+import (
+	"database/sql"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
 type Token struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type UserRole struct {
+	UserId   int    `json:"user_id"`
+	UserName string `json:"user_name"`
+
+	models.RoleGrant
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Device the refresh token is issued to
+type ClientInfo struct {
+	Device string
+	Ip     string
+}
+
+// Implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 )
 
 func RegisterRoutes(r *gin.RouterGroup, service *AuthService) {
@@ -13,6 +15,16 @@ func RegisterRoutes(r *gin.RouterGroup, service *AuthService) {
 
 	routes.GET("/ping", controller.ping)
 	routes.POST("/login", controller.login)
+	routes.POST("/login/egs", controller.loginEgs)
 	routes.POST("/refresh", controller.refresh)
 	routes.POST("/logout", controller.logout)
+	routes.POST("/logout/all", middleware.AuthMiddleWave, controller.logoutEverywhere)
+
+	routes.GET("/sessions", middleware.AuthMiddleWave, controller.getSessions)
+	routes.DELETE("/sessions/:id", middleware.AuthMiddleWave, controller.revokeSession)
+
+	// First superadmin can only be granted with the api key
+	routes.GET("/roles", middleware.RoleMiddleWave(models.SuperAdmin), controller.getRoles)
+	routes.POST("/roles", middleware.RoleMiddleWave(models.SuperAdmin), controller.grantRole)
+	routes.DELETE("/roles", middleware.RoleMiddleWave(models.SuperAdmin), controller.revokeRole)
 }
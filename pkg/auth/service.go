@@ -3,8 +3,10 @@ package auth
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/theggv/kf2-stats-backend/pkg/common/config"
+	"github.com/theggv/kf2-stats-backend/pkg/common/egsapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/steamapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
@@ -16,6 +18,7 @@ type AuthService struct {
 
 	userService     *users.UserService
 	steamApiService *steamapi.SteamApiUserService
+	egsApiService   egsapi.EgsApi
 }
 
 func NewAuthService(db *sql.DB) *AuthService {
@@ -29,83 +32,107 @@ func NewAuthService(db *sql.DB) *AuthService {
 func (s *AuthService) Inject(
 	userService *users.UserService,
 	steamApiService *steamapi.SteamApiUserService,
+	egsApiService egsapi.EgsApi,
 ) {
 	s.userService = userService
 	s.steamApiService = steamApiService
+	s.egsApiService = egsApiService
 }
 
 func (s *AuthService) Login(
-	req steamapi.ValidateOpenIdRequest,
+	req steamapi.ValidateOpenIdRequest, client ClientInfo,
 ) (*Token, error) {
 	steamData, err := s.steamApiService.ValidateOpenId(req)
 	if err != nil {
 		return nil, err
 	}
 
-	userId, err := s.userService.FindCreateFind(users.CreateUserRequest{
-		AuthId:   steamData.SteamId,
-		AuthType: models.Steam,
-		Name:     steamData.Name,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	tokens, err := s.generateTokens(&models.TokenPayload{
-		UserId:     userId,
+	return s.login(&models.TokenPayload{
 		Name:       steamData.Name,
+		AuthType:   models.Steam,
+		AuthId:     steamData.SteamId,
 		SteamId:    steamData.SteamId,
 		Avatar:     steamData.Avatar,
 		ProfileUrl: steamData.ProfileUrl,
+	}, client)
+}
+
+func (s *AuthService) LoginEgs(
+	req egsapi.ValidateAuthCodeRequest, client ClientInfo,
+) (*Token, error) {
+	egsData, err := s.egsApiService.ValidateAuthCode(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.login(&models.TokenPayload{
+		Name:       egsData.Name,
+		AuthType:   models.EGS,
+		AuthId:     egsData.AccountId,
+		Avatar:     egsData.Avatar,
+		ProfileUrl: egsData.ProfileUrl,
+	}, client)
+}
+
+func (s *AuthService) login(payload *models.TokenPayload, client ClientInfo) (*Token, error) {
+	userId, err := s.userService.FindCreateFind(users.CreateUserRequest{
+		AuthId:   payload.AuthId,
+		AuthType: payload.AuthType,
+		Name:     payload.Name,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	{
-		tx, err := s.db.Begin()
-		if err != nil {
-			return nil, err
-		}
+	payload.UserId = userId
 
-		defer tx.Rollback()
+	payload.Roles, err = s.GetUserRoles(userId)
+	if err != nil {
+		return nil, err
+	}
 
-		// save token
-		_, err = tx.Exec(
-			`INSERT INTO users_token (user_id, token) VALUES (?, ?)`,
-			userId, tokens.RefreshToken,
-		)
-		if err != nil {
-			return nil, err
-		}
+	tokens, err := s.generateTokens(payload)
+	if err != nil {
+		return nil, err
+	}
 
-		// update steam data
-		_, err = tx.Exec(`
-			INSERT INTO users_steam_data 
-				(user_id, steam_id, name, avatar, profile_url) 
-			VALUES (?, ?, ?, ?, ?)
-				ON DUPLICATE KEY UPDATE
-				name = ?, avatar = ?, profile_url = ?, updated_at = CURRENT_TIMESTAMP
-			`,
-			userId, steamData.SteamId, steamData.Name, steamData.Avatar, steamData.ProfileUrl,
-			steamData.Name, steamData.Avatar, steamData.ProfileUrl,
-		)
+	err = util.Transact(s.db, func(tx *sql.Tx) error {
+		err := s.createFamily(tx, userId, client, tokens.RefreshToken)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		tx.Commit()
+		return s.saveProfileData(tx, payload)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return tokens, nil
 }
 
-func (s *AuthService) Refresh(refreshToken string) (*Token, error) {
-	userId, err := s.findToken(refreshToken)
+func (s *AuthService) Refresh(refreshToken string, client ClientInfo) (*Token, error) {
+	token, err := s.findToken(refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
+	if token.RevokedAt != nil {
+		return nil, errors.New("session is revoked")
+	}
+
+	// Already rotated token is presented again, either copy of it may be stolen
+	if token.UsedAt != nil {
+		err := s.revokeFamily(token.FamilyId)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, errTokenReuse
+	}
+
+	userId := token.UserId
+
 	payload, err := util.ValidateToken(refreshToken, config.Instance.JwtRefreshSecretKey, 0)
 	if err != nil {
 		return nil, err
@@ -116,81 +143,211 @@ func (s *AuthService) Refresh(refreshToken string) (*Token, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	tokenPayload, err := s.getSteamDataFromDB(userId)
+	tokenPayload, err := s.getProfileFromDB(userId)
 	if err != nil {
 		return nil, err
 	}
 
-	// try to update steam info
-	summary, err := s.steamApiService.GetUserSummary([]string{tokenPayload.SteamId})
-	if err == nil && len(summary) == 1 {
-		tokenPayload.Name = summary[0].Name
-		tokenPayload.Avatar = summary[0].Avatar
-		tokenPayload.ProfileUrl = summary[0].ProfileUrl
+	// try to update profile info
+	profileData, err := s.userService.GetProfileData(
+		map[string]models.AuthType{tokenPayload.AuthId: tokenPayload.AuthType},
+	)
+	if data, ok := profileData[tokenPayload.AuthId]; err == nil && ok {
+		tokenPayload.Name = data.Name
+		if data.Avatar != nil {
+			tokenPayload.Avatar = *data.Avatar
+		}
+		if data.ProfileUrl != nil {
+			tokenPayload.ProfileUrl = *data.ProfileUrl
+		}
 
-		s.db.Exec(`
-			UPDATE users_steam_data 
-			SET name = ?, avatar = ?, profile_url = ? WHERE user_id = ?`,
-			tokenPayload.Name, tokenPayload.Avatar, tokenPayload.ProfileUrl, tokenPayload.UserId,
-		)
+		s.saveProfileData(s.db, tokenPayload)
 	}
 
-	tokens, err := s.generateTokens(tokenPayload)
+	tokenPayload.Roles, err = s.GetUserRoles(userId)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.updateToken(refreshToken, tokens.RefreshToken)
+	tokens, err := s.generateTokens(tokenPayload)
 	if err != nil {
 		return nil, err
 	}
 
+	err = s.rotateToken(token, client, tokens.RefreshToken)
+	if err == errTokenReuse {
+		if err := s.revokeFamily(token.FamilyId); err != nil {
+			return nil, err
+		}
+
+		return nil, errTokenReuse
+	} else if err != nil {
+		return nil, err
+	}
+
 	return tokens, nil
 }
 
+// Revokes the device session of the token
 func (s *AuthService) Logout(refreshToken string) error {
-	return s.deleteToken(refreshToken)
+	token, err := s.findToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return s.revokeFamily(token.FamilyId)
 }
 
-func (s *AuthService) GetSteamData(id int) (*models.TokenPayload, error) {
-	return s.getSteamDataFromDB(id)
+func (s *AuthService) GetProfile(id int) (*models.TokenPayload, error) {
+	return s.getProfileFromDB(id)
 }
 
-func (s *AuthService) getSteamDataFromDB(userId int) (*models.TokenPayload, error) {
-	stmt := `SELECT steam_id, name, avatar, profile_url FROM users_steam_data WHERE user_id = ?`
-	row := s.db.QueryRow(stmt, userId)
+func (s *AuthService) GetUserRoles(userId int) ([]models.RoleGrant, error) {
+	rows, err := s.db.Query(`
+		SELECT role, server_id FROM users_role WHERE user_id = ? ORDER BY role, server_id`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	payload := models.TokenPayload{UserId: userId}
-	err := row.Scan(&payload.SteamId, &payload.Name, &payload.Avatar, &payload.ProfileUrl)
+	items := []models.RoleGrant{}
+	for rows.Next() {
+		item := models.RoleGrant{}
+
+		err := rows.Scan(&item.Role, &item.ServerId)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (s *AuthService) GetRoles() ([]*UserRole, error) {
+	rows, err := s.db.Query(`
+		SELECT users_role.user_id, users.name, role, server_id, users_role.created_at
+		FROM users_role
+		INNER JOIN users ON users.id = users_role.user_id
+		ORDER BY role, server_id, users_role.user_id`,
+	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &payload, nil
+	items := []*UserRole{}
+	for rows.Next() {
+		item := UserRole{}
+
+		err := rows.Scan(&item.UserId, &item.UserName, &item.Role, &item.ServerId, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
 }
 
-func (s *AuthService) findToken(refreshToken string) (int, error) {
-	stmt := `SELECT user_id FROM users_token WHERE token = ?`
-	row := s.db.QueryRow(stmt, refreshToken)
+// Roles are embedded into access tokens, so changes apply after the next token refresh
+func (s *AuthService) GrantRole(req GrantRoleRequest) error {
+	switch req.Role {
+	case models.SuperAdmin:
+		if req.ServerId != 0 {
+			return errors.New("superadmin role can't be scoped to a server")
+		}
+	case models.ServerAdmin, models.Moderator:
+	default:
+		return fmt.Errorf("expected Role enum, got %v", req.Role)
+	}
+
+	if req.ServerId != 0 {
+		var exists bool
+		err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM server WHERE id = ?)`, req.ServerId).
+			Scan(&exists)
+		if err != nil {
+			return err
+		}
 
-	var userId int
-	err := row.Scan(&userId)
-	if err != nil {
-		return 0, err
+		if !exists {
+			return fmt.Errorf("server %v not found", req.ServerId)
+		}
 	}
 
-	return userId, nil
+	_, err := s.db.Exec(`
+		INSERT IGNORE INTO users_role (user_id, role, server_id) VALUES (?, ?, ?)`,
+		req.UserId, req.Role, req.ServerId,
+	)
+
+	return err
 }
 
-func (s *AuthService) updateToken(oldToken string, newToken string) error {
-	stmt := `UPDATE users_token SET token = ? WHERE token = ?`
-	_, err := s.db.Exec(stmt, newToken, oldToken)
+func (s *AuthService) RevokeRole(req GrantRoleRequest) error {
+	_, err := s.db.Exec(`
+		DELETE FROM users_role WHERE user_id = ? AND role = ? AND server_id = ?`,
+		req.UserId, req.Role, req.ServerId,
+	)
+
 	return err
 }
 
-func (s *AuthService) deleteToken(refreshToken string) error {
-	stmt := `DELETE FROM users_token WHERE token = ?`
-	_, err := s.db.Exec(stmt, refreshToken)
+func (s *AuthService) getProfileFromDB(userId int) (*models.TokenPayload, error) {
+	stmt := `
+		SELECT
+			users.auth_type, users.auth_id,
+			coalesce(steam.name, egs.name, users.name),
+			coalesce(steam.avatar, egs.avatar, ''),
+			coalesce(steam.profile_url, egs.profile_url, '')
+		FROM users
+		LEFT JOIN users_steam_data steam ON steam.user_id = users.id
+		LEFT JOIN users_egs_data egs ON egs.user_id = users.id
+		WHERE users.id = ?`
+	row := s.db.QueryRow(stmt, userId)
+
+	payload := models.TokenPayload{UserId: userId}
+	err := row.Scan(
+		&payload.AuthType, &payload.AuthId,
+		&payload.Name, &payload.Avatar, &payload.ProfileUrl,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.AuthType == models.Steam {
+		payload.SteamId = payload.AuthId
+	}
+
+	return &payload, nil
+}
+
+// Stores profile data in the table of the user's auth provider
+func (s *AuthService) saveProfileData(db execer, payload *models.TokenPayload) error {
+	var table, idColumn string
+	switch payload.AuthType {
+	case models.Steam:
+		table, idColumn = "users_steam_data", "steam_id"
+	case models.EGS:
+		table, idColumn = "users_egs_data", "account_id"
+	default:
+		return fmt.Errorf("expected AuthType enum, got %v", payload.AuthType)
+	}
+
+	_, err := db.Exec(fmt.Sprintf(`
+		INSERT INTO %v
+			(user_id, %v, name, avatar, profile_url) 
+		VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+			name = ?, avatar = ?, profile_url = ?, updated_at = CURRENT_TIMESTAMP
+		`, table, idColumn),
+		payload.UserId, payload.AuthId, payload.Name, payload.Avatar, payload.ProfileUrl,
+		payload.Name, payload.Avatar, payload.ProfileUrl,
+	)
+
 	return err
 }
 
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/config"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+var errTokenReuse = errors.New("refresh token reuse detected, session is revoked")
+
+// Refresh tokens are stored as sha256 hashes only
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func truncate(s string, length int) string {
+	if len(s) > length {
+		return s[:length]
+	}
+	return s
+}
+
+type storedToken struct {
+	Id        int
+	UserId    int
+	FamilyId  int
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+func (s *AuthService) findToken(refreshToken string) (*storedToken, error) {
+	item := storedToken{}
+
+	err := s.db.QueryRow(`
+		SELECT t.id, t.user_id, t.family_id, t.used_at, f.revoked_at
+		FROM users_token t
+		INNER JOIN users_token_family f ON f.id = t.family_id
+		WHERE t.token_hash = ?`, hashToken(refreshToken),
+	).Scan(&item.Id, &item.UserId, &item.FamilyId, &item.UsedAt, &item.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid token")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// Starts a new token family for the device
+func (s *AuthService) createFamily(tx *sql.Tx, userId int, client ClientInfo, refreshToken string) error {
+	res, err := tx.Exec(`
+		INSERT INTO users_token_family (user_id, device, ip) VALUES (?, ?, ?)`,
+		userId, truncate(client.Device, 256), truncate(client.Ip, 64),
+	)
+	if err != nil {
+		return err
+	}
+
+	familyId, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO users_token (user_id, family_id, token_hash) VALUES (?, ?, ?)`,
+		userId, familyId, hashToken(refreshToken),
+	)
+
+	return err
+}
+
+// Marks old token as used and adds the new one to the same family.
+// Used tokens are kept until they expire to detect reuse.
+func (s *AuthService) rotateToken(token *storedToken, client ClientInfo, refreshToken string) error {
+	expiresIn, err := time.ParseDuration(config.Instance.JwtRefreshExpiresIn)
+	if err != nil {
+		return err
+	}
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			UPDATE users_token SET used_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND used_at IS NULL`, token.Id,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		// Token was rotated concurrently
+		if affected == 0 {
+			return errTokenReuse
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO users_token (user_id, family_id, token_hash) VALUES (?, ?, ?)`,
+			token.UserId, token.FamilyId, hashToken(refreshToken),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			DELETE FROM users_token
+			WHERE family_id = ? AND used_at IS NOT NULL AND created_at < ?`,
+			token.FamilyId, time.Now().Add(-expiresIn),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE users_token_family
+			SET last_used_at = CURRENT_TIMESTAMP, device = ?, ip = ?
+			WHERE id = ?`,
+			truncate(client.Device, 256), truncate(client.Ip, 64), token.FamilyId,
+		)
+
+		return err
+	})
+}
+
+func (s *AuthService) revokeFamily(familyId int) error {
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE users_token_family SET revoked_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND revoked_at IS NULL`, familyId,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM users_token WHERE family_id = ?`, familyId)
+
+		return err
+	})
+}
+
+// Returns not revoked device sessions of the user, current session is detected by refresh token
+func (s *AuthService) GetSessions(userId int, refreshToken string) ([]*DeviceSession, error) {
+	currentFamilyId := 0
+	if refreshToken != "" {
+		if token, err := s.findToken(refreshToken); err == nil && token.UserId == userId {
+			currentFamilyId = token.FamilyId
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, device, ip, created_at, last_used_at
+		FROM users_token_family
+		WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY last_used_at DESC`, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*DeviceSession{}
+	for rows.Next() {
+		item := DeviceSession{}
+
+		err := rows.Scan(&item.Id, &item.Device, &item.Ip, &item.CreatedAt, &item.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		item.IsCurrent = item.Id == currentFamilyId
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+func (s *AuthService) RevokeSession(userId, familyId int) error {
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM users_token_family WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+		)`, familyId, userId,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("session %v not found", familyId)
+	}
+
+	return s.revokeFamily(familyId)
+}
+
+// Revokes every device session of the user
+func (s *AuthService) LogoutEverywhere(userId int) error {
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE users_token_family SET revoked_at = CURRENT_TIMESTAMP
+			WHERE user_id = ? AND revoked_at IS NULL`, userId,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM users_token WHERE user_id = ?`, userId)
+
+		return err
+	})
+}
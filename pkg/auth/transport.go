@@ -1,5 +1,36 @@
 package auth
 
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
 type AuthResponse struct {
 	AccessToken string `json:"access_token"`
 }
+
+type GrantRoleRequest struct {
+	UserId   int         `json:"user_id" binding:"required"`
+	Role     models.Role `json:"role" binding:"required"`
+	ServerId int         `json:"server_id"`
+}
+
+type GetRolesResponse struct {
+	Items []*UserRole `json:"items"`
+}
+
+type DeviceSession struct {
+	Id     int    `json:"id"`
+	Device string `json:"device"`
+	Ip     string `json:"ip"`
+
+	IsCurrent bool `json:"is_current"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+type GetSessionsResponse struct {
+	Items []*DeviceSession `json:"items"`
+}
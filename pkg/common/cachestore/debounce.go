@@ -0,0 +1,54 @@
+package cachestore
+
+import (
+	"sync"
+	"time"
+)
+
+// Collects invalidated tags and drops them at most once per interval.
+// Used for tags shared by many responses, which would be flushed on every ingest event otherwise.
+type Debouncer struct {
+	invalidator Invalidator
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func NewDebouncer(invalidator Invalidator, interval time.Duration) *Debouncer {
+	debouncer := &Debouncer{
+		invalidator: invalidator,
+		pending:     map[string]bool{},
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			debouncer.Flush()
+		}
+	}()
+
+	return debouncer
+}
+
+func (d *Debouncer) InvalidateTags(tags ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, tag := range tags {
+		d.pending[tag] = true
+	}
+
+	return nil
+}
+
+// Invalidates pending tags
+func (d *Debouncer) Flush() {
+	d.mu.Lock()
+	tags := []string{}
+	for tag := range d.pending {
+		tags = append(tags, tag)
+	}
+	d.pending = map[string]bool{}
+	d.mu.Unlock()
+
+	Invalidate(d.invalidator, tags...)
+}
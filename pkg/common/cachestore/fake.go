@@ -0,0 +1,330 @@
+package cachestore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimal number of arguments
+var fakeRedisArity = map[string]int{
+	"GET": 1, "SET": 2, "DEL": 1, "SADD": 2,
+	"SMEMBERS": 1, "EXPIRE": 2, "PEXPIRE": 2,
+}
+
+type fakeRedisEntry struct {
+	value     []byte
+	set       map[string]bool
+	expiresAt time.Time
+}
+
+// Embedded Redis compatible server for local runs and tests.
+// Supports only commands used by RedisStore: PING, GET, SET, DEL, SADD, SMEMBERS, EXPIRE and PEXPIRE.
+type FakeRedisServer struct {
+	listener net.Listener
+	done     chan struct{}
+
+	mu   sync.Mutex
+	data map[string]*fakeRedisEntry
+}
+
+func NewFakeRedisServer() (*FakeRedisServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := FakeRedisServer{
+		listener: listener,
+		done:     make(chan struct{}),
+		data:     map[string]*fakeRedisEntry{},
+	}
+
+	go server.serve()
+	go server.initCleanup(time.Minute)
+
+	return &server, nil
+}
+
+func (s *FakeRedisServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *FakeRedisServer) Close() error {
+	close(s.done)
+	return s.listener.Close()
+}
+
+// Removes expired keys which are never requested again
+func (s *FakeRedisServer) initCleanup(updateTime time.Duration) {
+	ticker := time.NewTicker(updateTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			for key := range s.data {
+				s.get(key)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *FakeRedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *FakeRedisServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if err != io.EOF {
+				writeError(writer, err.Error())
+				writer.Flush()
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		s.exec(writer, strings.ToUpper(args[0]), args[1:])
+
+		// Pipelined commands are answered together
+		if reader.Buffered() == 0 {
+			if err := writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Reads RESP array of bulk strings or inline command
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, errors.New("ERR invalid multibulk length")
+	}
+
+	args := make([]string, 0, count)
+	for range count {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("ERR expected '$', got '%v'", line)
+		}
+
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, errors.New("ERR invalid bulk length")
+		}
+
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+
+		args = append(args, string(buf[:length]))
+	}
+
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeError(w *bufio.Writer, message string) {
+	fmt.Fprintf(w, "-%v\r\n", message)
+}
+
+func writeStatus(w *bufio.Writer, status string) {
+	fmt.Fprintf(w, "+%v\r\n", status)
+}
+
+func writeInt(w *bufio.Writer, value int) {
+	fmt.Fprintf(w, ":%v\r\n", value)
+}
+
+func writeBulk(w *bufio.Writer, value []byte) {
+	if value == nil {
+		w.WriteString("$-1\r\n")
+		return
+	}
+
+	fmt.Fprintf(w, "$%v\r\n", len(value))
+	w.Write(value)
+	w.WriteString("\r\n")
+}
+
+func writeArray(w *bufio.Writer, values []string) {
+	fmt.Fprintf(w, "*%v\r\n", len(values))
+	for _, value := range values {
+		writeBulk(w, []byte(value))
+	}
+}
+
+// Returns not expired entry, expired ones are removed lazily
+func (s *FakeRedisServer) get(key string) *fakeRedisEntry {
+	entry, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(s.data, key)
+		return nil
+	}
+
+	return entry
+}
+
+func (s *FakeRedisServer) exec(w *bufio.Writer, cmd string, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if minArgs, ok := fakeRedisArity[cmd]; ok && len(args) < minArgs {
+		writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%v' command", strings.ToLower(cmd)))
+		return
+	}
+
+	switch cmd {
+	case "PING":
+		writeStatus(w, "PONG")
+	case "AUTH", "SELECT":
+		writeStatus(w, "OK")
+	case "GET":
+		entry := s.get(args[0])
+		if entry == nil {
+			writeBulk(w, nil)
+		} else if entry.set != nil {
+			writeError(w, "WRONGTYPE Operation against a key holding the wrong kind of value")
+		} else {
+			writeBulk(w, entry.value)
+		}
+	case "SET":
+		entry := fakeRedisEntry{value: []byte(args[1])}
+
+		for i := 2; i < len(args); i += 2 {
+			if i+1 >= len(args) {
+				writeError(w, "ERR syntax error")
+				return
+			}
+
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil || value <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+
+			switch strings.ToUpper(args[i]) {
+			case "EX":
+				entry.expiresAt = time.Now().Add(time.Duration(value) * time.Second)
+			case "PX":
+				entry.expiresAt = time.Now().Add(time.Duration(value) * time.Millisecond)
+			default:
+				writeError(w, "ERR syntax error")
+				return
+			}
+		}
+
+		s.data[args[0]] = &entry
+		writeStatus(w, "OK")
+	case "DEL":
+		count := 0
+		for _, key := range args {
+			if s.get(key) != nil {
+				delete(s.data, key)
+				count += 1
+			}
+		}
+		writeInt(w, count)
+	case "SADD":
+		entry := s.get(args[0])
+		if entry == nil {
+			entry = &fakeRedisEntry{set: map[string]bool{}}
+			s.data[args[0]] = entry
+		} else if entry.set == nil {
+			writeError(w, "WRONGTYPE Operation against a key holding the wrong kind of value")
+			return
+		}
+
+		count := 0
+		for _, member := range args[1:] {
+			if !entry.set[member] {
+				entry.set[member] = true
+				count += 1
+			}
+		}
+		writeInt(w, count)
+	case "SMEMBERS":
+		members := []string{}
+		if entry := s.get(args[0]); entry != nil {
+			for member := range entry.set {
+				members = append(members, member)
+			}
+		}
+		writeArray(w, members)
+	case "EXPIRE", "PEXPIRE":
+		value, err := strconv.Atoi(args[1])
+		if err != nil {
+			writeError(w, "ERR value is not an integer or out of range")
+			return
+		}
+
+		entry := s.get(args[0])
+		if entry == nil {
+			writeInt(w, 0)
+			return
+		}
+
+		unit := time.Second
+		if cmd == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		entry.expiresAt = time.Now().Add(time.Duration(value) * unit)
+		writeInt(w, 1)
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%v'", strings.ToLower(cmd)))
+	}
+}
@@ -0,0 +1,92 @@
+package cachestore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chenyahui/gin-cache/persist"
+)
+
+// How often expired keys are removed from tags
+const pruneInterval = 1 * time.Minute
+
+type MemoryStore struct {
+	*persist.MemoryStore
+
+	mu sync.Mutex
+	// Tag -> key -> expiration time
+	tags map[string]map[string]time.Time
+}
+
+func NewMemoryStore(defaultExpiration time.Duration) *MemoryStore {
+	store := &MemoryStore{
+		MemoryStore: persist.NewMemoryStore(defaultExpiration),
+		tags:        map[string]map[string]time.Time{},
+	}
+
+	go func() {
+		for range time.Tick(pruneInterval) {
+			store.prune()
+		}
+	}()
+
+	return store
+}
+
+// Drops expired keys, so tags of rarely invalidated data don't grow forever
+func (s *MemoryStore) prune() {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tag, keys := range s.tags {
+		for key, expiresAt := range keys {
+			if now.After(expiresAt) {
+				delete(keys, key)
+			}
+		}
+
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+func (s *MemoryStore) SetTags(key string, tags []string, expire time.Duration) error {
+	expiresAt := time.Now().Add(expire)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = map[string]time.Time{}
+			s.tags[tag] = keys
+		}
+
+		keys[key] = expiresAt
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) InvalidateTags(tags ...string) error {
+	s.mu.Lock()
+	keys := map[string]bool{}
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			keys[key] = true
+		}
+		delete(s.tags, tag)
+	}
+	s.mu.Unlock()
+
+	// Keys which are already expired return not found error
+	for key := range keys {
+		_ = s.Delete(key)
+	}
+
+	return nil
+}
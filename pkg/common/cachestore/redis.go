@@ -0,0 +1,76 @@
+package cachestore
+
+import (
+	"context"
+	"time"
+
+	"github.com/chenyahui/gin-cache/persist"
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis backend, tags are stored as sets of keys
+type RedisStore struct {
+	*persist.RedisStore
+
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{
+		RedisStore: persist.NewRedisStore(client),
+		prefix:     prefix,
+	}
+}
+
+func (s *RedisStore) key(key string) string {
+	return s.prefix + key
+}
+
+func (s *RedisStore) tagKey(tag string) string {
+	return s.prefix + "tag:" + tag
+}
+
+func (s *RedisStore) Get(key string, value interface{}) error {
+	return s.RedisStore.Get(s.key(key), value)
+}
+
+func (s *RedisStore) Set(key string, value interface{}, expire time.Duration) error {
+	return s.RedisStore.Set(s.key(key), value, expire)
+}
+
+func (s *RedisStore) Delete(key string) error {
+	return s.RedisStore.Delete(s.key(key))
+}
+
+func (s *RedisStore) SetTags(key string, tags []string, expire time.Duration) error {
+	ctx := context.TODO()
+
+	_, err := s.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.SAdd(ctx, s.tagKey(tag), s.key(key))
+			pipe.Expire(ctx, s.tagKey(tag), max(expire, minTagTTL))
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func (s *RedisStore) InvalidateTags(tags ...string) error {
+	ctx := context.TODO()
+
+	for _, tag := range tags {
+		keys, err := s.RedisClient.SMembers(ctx, s.tagKey(tag)).Result()
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, s.tagKey(tag))
+		if err := s.RedisClient.Del(ctx, keys...).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
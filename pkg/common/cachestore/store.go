@@ -0,0 +1,101 @@
+package cachestore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chenyahui/gin-cache/persist"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	MemoryBackend    = "memory"
+	RedisBackend     = "redis"
+	FakeRedisBackend = "fake-redis"
+)
+
+// Tag sets live at least this long, cache durations should be shorter
+const minTagTTL = 1 * time.Hour
+
+// Implemented by services which need to drop cached responses after ingest events
+type Invalidator interface {
+	InvalidateTags(tags ...string) error
+}
+
+// Response cache with tag based invalidation
+type Store interface {
+	persist.CacheStore
+	Invalidator
+
+	// Registers the key under the tags, so it's removed once any of them is invalidated
+	SetTags(key string, tags []string, expire time.Duration) error
+}
+
+type Options struct {
+	Backend string
+	// Prepended to every key, so several deployments can share one redis
+	Prefix string
+
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+}
+
+// Creates backend from CACHE_BACKEND.
+// Memory backend is local to the instance, use redis to share cache between replicas.
+func New(opts Options) (Store, error) {
+	switch opts.Backend {
+	case MemoryBackend:
+		return NewMemoryStore(5 * time.Minute), nil
+	case RedisBackend:
+		return NewRedisStore(redis.NewClient(&redis.Options{
+			Addr:     opts.RedisAddr,
+			Password: opts.RedisPassword,
+			DB:       opts.RedisDB,
+		}), opts.Prefix), nil
+	case FakeRedisBackend:
+		server, err := NewFakeRedisServer()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRedisStore(redis.NewClient(&redis.Options{
+			Addr: server.Addr(),
+		}), opts.Prefix), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %v", opts.Backend)
+	}
+}
+
+// Invalidates tags and logs errors, ingest shouldn't fail because of the cache
+func Invalidate(invalidator Invalidator, tags ...string) {
+	if invalidator == nil || len(tags) == 0 {
+		return
+	}
+
+	if err := invalidator.InvalidateTags(tags...); err != nil {
+		fmt.Printf("[cache] invalidate %v: %v\n", tags, err)
+	}
+}
+
+type taggedStore struct {
+	Store
+
+	tags []string
+}
+
+// Store view which registers every stored key under the tags
+func Tagged(store Store, tags ...string) persist.CacheStore {
+	return &taggedStore{
+		Store: store,
+		tags:  tags,
+	}
+}
+
+func (s *taggedStore) Set(key string, value interface{}, expire time.Duration) error {
+	if err := s.Store.Set(key, value, expire); err != nil {
+		return err
+	}
+
+	return s.Store.SetTags(key, s.tags, expire)
+}
@@ -0,0 +1,139 @@
+package cachestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chenyahui/gin-cache/persist"
+	"github.com/go-redis/redis/v8"
+)
+
+func newFakeRedisStore(t *testing.T) *RedisStore {
+	server, err := NewFakeRedisServer()
+	if err != nil {
+		t.Fatalf("NewFakeRedisServer: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisStore(client, "test:")
+}
+
+func testTagInvalidation(t *testing.T, store Store) {
+	servers := Tagged(store, ServerTags(1, 2)...)
+	global := Tagged(store, ServerTags()...)
+	other := Tagged(store, ServerTag(3), TagUsers)
+
+	for key, tagged := range map[string]persist.CacheStore{
+		"servers": servers,
+		"global":  global,
+		"other":   other,
+	} {
+		if err := tagged.Set(key, key, time.Minute); err != nil {
+			t.Fatalf("Set %v: %v", key, err)
+		}
+	}
+
+	exists := func(key string) bool {
+		var value string
+		return store.Get(key, &value) == nil && value == key
+	}
+
+	if err := store.InvalidateTags(ServerTag(2)); err != nil {
+		t.Fatalf("InvalidateTags: %v", err)
+	}
+
+	if exists("servers") {
+		t.Error("key of invalidated server is not removed")
+	}
+	if !exists("global") || !exists("other") {
+		t.Error("keys of other tags are removed")
+	}
+
+	if err := store.InvalidateTags(TagSessions); err != nil {
+		t.Fatalf("InvalidateTags: %v", err)
+	}
+
+	if exists("global") {
+		t.Error("global key is not removed")
+	}
+	if !exists("other") {
+		t.Error("server scoped key is removed with global aggregates")
+	}
+
+	if err := store.InvalidateTags(TagUsers); err != nil {
+		t.Fatalf("InvalidateTags: %v", err)
+	}
+
+	if exists("other") {
+		t.Error("key with several tags is not removed")
+	}
+
+	// Invalidated tags are empty, keys stored again aren't removed by previous invalidations
+	if err := servers.Set("servers", "servers", time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.InvalidateTags(ServerTag(3)); err != nil {
+		t.Fatalf("InvalidateTags: %v", err)
+	}
+	if !exists("servers") {
+		t.Error("key is removed by unrelated tag")
+	}
+}
+
+func TestMemoryStoreTags(t *testing.T) {
+	testTagInvalidation(t, NewMemoryStore(time.Minute))
+}
+
+func TestFakeRedisStoreTags(t *testing.T) {
+	testTagInvalidation(t, newFakeRedisStore(t))
+}
+
+func TestMemoryStorePrune(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+
+	store.SetTags("expired", []string{TagSessions}, -time.Second)
+	store.SetTags("alive", []string{TagSessions}, time.Minute)
+	store.SetTags("expired", []string{TagUsers}, -time.Second)
+
+	store.prune()
+
+	if len(store.tags[TagSessions]) != 1 {
+		t.Errorf("expected only alive key, got %v", store.tags[TagSessions])
+	}
+	if _, ok := store.tags[TagUsers]; ok {
+		t.Error("empty tag is not removed")
+	}
+}
+
+func TestServerTags(t *testing.T) {
+	if tags := ServerTags(0); len(tags) != 1 || tags[0] != TagSessions {
+		t.Errorf("request without server should use global tag, got %v", tags)
+	}
+
+	if tags := ServerTags(0, 1, 2); len(tags) != 2 || tags[0] != ServerTag(1) || tags[1] != ServerTag(2) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+func TestDebouncer(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	debouncer := NewDebouncer(store, time.Hour)
+
+	if err := Tagged(store, TagSessions).Set("global", "global", time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	var value string
+	Invalidate(debouncer, TagSessions)
+	if err := store.Get("global", &value); err != nil {
+		t.Error("key is removed before flush")
+	}
+
+	debouncer.Flush()
+	if err := store.Get("global", &value); err == nil {
+		t.Error("key is not removed after flush")
+	}
+}
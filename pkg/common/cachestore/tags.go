@@ -0,0 +1,38 @@
+package cachestore
+
+import "fmt"
+
+const (
+	// Aggregates over all servers: global analytics, leaderboards, dashboards.
+	// Invalidated with debounce, server scoped responses use ServerTag instead.
+	TagSessions = "sessions"
+	// Responses listing players, depend on privacy settings and bans
+	TagUsers = "users"
+)
+
+// Match data of the session
+func SessionTag(id int) string {
+	return fmt.Sprintf("session:%v", id)
+}
+
+// Data of the server, including its current session
+func ServerTag(id int) string {
+	return fmt.Sprintf("server:%v", id)
+}
+
+// Tags of the servers the request is filtered by,
+// requests without server filter are tagged with TagSessions
+func ServerTags(serverIds ...int) []string {
+	tags := []string{}
+	for _, id := range serverIds {
+		if id > 0 {
+			tags = append(tags, ServerTag(id))
+		}
+	}
+
+	if len(tags) == 0 {
+		return []string{TagSessions}
+	}
+
+	return tags
+}
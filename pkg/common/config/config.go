@@ -4,12 +4,24 @@ import (
 	"github.com/joho/godotenv"
 )
 
+const (
+	// Serves api and runs background workers in the same process
+	AllMode = "all"
+	// Serves api only, queued jobs are processed by worker processes
+	ApiMode    = "api"
+	WorkerMode = "worker"
+)
+
 type AppConfig struct {
+	Mode        string
 	ServerAddr  string
 	Token       string
 	SteamApiKey string
 	Domain      string
 
+	EgsClientId     string
+	EgsClientSecret string
+
 	DBUser     string
 	DBPassword string
 	DBHost     string
@@ -20,6 +32,23 @@ type AppConfig struct {
 	JwtAccessExpiresIn  string
 	JwtRefreshSecretKey string
 	JwtRefreshExpiresIn string
+
+	ErasedBanSecretKey string
+
+	RateLimitEnabled            bool
+	RateLimitAnalyticsPerMinute int
+	RateLimitAnalyticsBurst     int
+	RateLimitHeavyPerMinute     int
+	RateLimitHeavyBurst         int
+	RateLimitExemptIps          []string
+	RateLimitExemptKeys         []string
+	TrustedProxies              []string
+
+	CacheBackend  string
+	CachePrefix   string
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
 }
 
 var Instance *AppConfig = new()
@@ -28,11 +57,15 @@ func new() *AppConfig {
 	godotenv.Load(".env")
 
 	config := AppConfig{
+		Mode:        getEnv("APP_MODE", AllMode),
 		ServerAddr:  getEnv("SERVER_ADDR", "127.0.0.1:3000"),
 		Token:       getEnv("SECRET_TOKEN", ""),
 		SteamApiKey: getEnv("STEAM_API_KEY", ""),
 		Domain:      getEnv("DOMAIN", "localhost"),
 
+		EgsClientId:     getEnv("EGS_CLIENT_ID", ""),
+		EgsClientSecret: getEnv("EGS_CLIENT_SECRET", ""),
+
 		DBUser:     getEnv("DB_USER", "user"),
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBHost:     getEnv("DB_HOST", "db"),
@@ -43,6 +76,27 @@ func new() *AppConfig {
 		JwtAccessExpiresIn:  getEnv("JWT_ACCESS_EXPIRES_IN", "15m"),
 		JwtRefreshSecretKey: getEnv("JWT_REFRESH_SECRET_KEY", ""),
 		JwtRefreshExpiresIn: getEnv("JWT_REFRESH_EXPIRES_IN", "30d"),
+
+		ErasedBanSecretKey: getEnv("ERASED_BAN_SECRET_KEY", ""),
+
+		RateLimitEnabled:            getEnvAsBool("RATE_LIMIT_ENABLED", true),
+		RateLimitAnalyticsPerMinute: getEnvAsInt("RATE_LIMIT_ANALYTICS_PER_MINUTE", 120),
+		RateLimitAnalyticsBurst:     getEnvAsInt("RATE_LIMIT_ANALYTICS_BURST", 60),
+		RateLimitHeavyPerMinute:     getEnvAsInt("RATE_LIMIT_HEAVY_PER_MINUTE", 20),
+		RateLimitHeavyBurst:         getEnvAsInt("RATE_LIMIT_HEAVY_BURST", 10),
+		RateLimitExemptIps:          getEnvAsSlice("RATE_LIMIT_EXEMPT_IPS", []string{}, ","),
+		RateLimitExemptKeys:         getEnvAsSlice("RATE_LIMIT_EXEMPT_KEYS", []string{}, ","),
+		TrustedProxies:              getEnvAsSlice("TRUSTED_PROXIES", []string{}, ","),
+
+		CacheBackend:  getEnv("CACHE_BACKEND", "memory"),
+		CachePrefix:   getEnv("CACHE_PREFIX", "kf2stats:"),
+		RedisAddr:     getEnv("REDIS_ADDR", "redis:6379"),
+		RedisPassword: getEnv("REDIS_PASSWORD", ""),
+		RedisDB:       getEnvAsInt("REDIS_DB", 0),
+	}
+
+	if config.Mode != AllMode && config.Mode != ApiMode && config.Mode != WorkerMode {
+		panic("APP_MODE should be all, api or worker. Check your .env file.")
 	}
 
 	if config.Token == "" {
@@ -57,5 +111,18 @@ func new() *AppConfig {
 		panic("JWT_REFRESH_SECRET_KEY is not set. Check your .env file.")
 	}
 
+	// Changing the key makes bans of erased accounts unrecoverable, so it falls back to a stable secret
+	if config.ErasedBanSecretKey == "" {
+		config.ErasedBanSecretKey = config.JwtRefreshSecretKey
+	}
+
 	return &config
 }
+
+func (c *AppConfig) RunsApi() bool {
+	return c.Mode == AllMode || c.Mode == ApiMode
+}
+
+func (c *AppConfig) RunsWorker() bool {
+	return c.Mode == AllMode || c.Mode == WorkerMode
+}
@@ -2,8 +2,6 @@ package cron
 
 import (
 	"database/sql"
-	"fmt"
-	"time"
 
 	"github.com/theggv/kf2-stats-backend/pkg/common/demorecord"
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
@@ -11,28 +9,6 @@ import (
 	"github.com/theggv/kf2-stats-backend/pkg/session"
 )
 
-func setupProcessDemosTask(s *store.Store) {
-	for range time.Tick(15 * time.Second) {
-		err := processDemos(s)
-		if err != nil {
-			fmt.Printf("[processDemos] error: %v\n", err)
-		}
-	}
-}
-
-func getDemoCount(db *sql.DB) (int, error) {
-	var count int
-	{
-		row := db.QueryRow(`SELECT COUNT(*) FROM session_demo WHERE processed = 0`)
-		err := row.Scan(&count)
-		if err != nil {
-			return 0, err
-		}
-	}
-
-	return count, nil
-}
-
 func getDemo(sessionId int, s *session.SessionService) (*demorecord.DemoRecordParsed, error) {
 	rawDemo, err := s.GetDemo(sessionId)
 	if err != nil {
@@ -106,51 +82,28 @@ func processDemo(sessionId int, analysis *demorecord.DemoRecordAnalysis, db *sql
 	return nil
 }
 
-func processDemos(s *store.Store) error {
-	count, err := getDemoCount(s.Db)
-	if err != nil {
-		return err
-	}
-
-	if count > 0 {
-		fmt.Printf("[processDemos] found %v demos\n", count)
-
-	}
-
-	for count > 0 {
-		rows, err := s.Db.Query(`
-			SELECT session_id 
-			FROM session_demo WHERE processed = 0 LIMIT 100`,
-		)
-
+func processDemos(s *store.Store, sessionIds []int) error {
+	for _, sessionId := range sessionIds {
+		demo, err := getDemo(sessionId, s.Sessions)
 		if err != nil {
 			return err
 		}
 
-		for rows.Next() {
-			var sessionId int
-			err = rows.Scan(&sessionId)
-			if err != nil {
-				return err
-			}
-
-			demo, err := getDemo(sessionId, s.Sessions)
-			if err != nil {
-				return err
-			}
-
-			analysis := demo.Analyze()
+		analysis := demo.Analyze()
 
-			count -= 1
+		err = processDemo(sessionId, analysis, s.Db)
+		if err != nil {
+			return err
+		}
 
-			err = processDemo(sessionId, analysis, s.Db)
-			if err != nil {
-				return err
-			}
+		err = s.Achievements.EvaluateDemo(analysis)
+		if err != nil {
+			return err
+		}
 
-			if count > 0 && count%100 == 0 {
-				fmt.Printf("[processDemos] demos %v left to process\n", count)
-			}
+		err = s.Records.UpdateFromDemo(analysis)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -2,18 +2,12 @@ package cron
 
 import (
 	"database/sql"
-	"fmt"
-	"time"
 )
 
-func handleDanglingSessions(db *sql.DB) {
+func handleDanglingSessions(db *sql.DB) error {
 	olderThanMinutes := 15
 
-	for range time.Tick(1 * time.Minute) {
-		_, err := db.Exec(`CALL handle_dangling_sessions(?)`, olderThanMinutes)
+	_, err := db.Exec(`CALL handle_dangling_sessions(?)`, olderThanMinutes)
 
-		if err != nil {
-			fmt.Printf("[handleDanglingSessions] error: %v\n", err)
-		}
-	}
+	return err
 }
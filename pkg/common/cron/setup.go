@@ -1,11 +1,111 @@
 package cron
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/theggv/kf2-stats-backend/pkg/common/store"
+	"github.com/theggv/kf2-stats-backend/pkg/jobs"
 )
 
+// Starts background processing of the worker. Any number of workers can run:
+// queued jobs are leased by one of them, periodic tasks are run by the elected leader only.
 func SetupTasks(s *store.Store) {
-	go handleDanglingSessions(s.Db)
+	workerId := jobs.NewWorkerId()
+	queue := jobs.NewQueue(s.Db, workerId)
+	leader := jobs.NewLeader(s.Db, "periodic", workerId)
+
+	fmt.Printf("[worker] started as %v\n", workerId)
+
+	go leader.Run()
+
+	go consumeQueue(queue, jobs.DifficultyJob, 30*time.Second, 100, s.Difficulty.ProcessQueue)
+	go consumeQueue(queue, jobs.AchievementsJob, 30*time.Second, 100, s.Achievements.ProcessQueue)
+	// Difficulty records depend on calculated session difficulty
+	go consumeQueue(queue, jobs.RecordsJob, 30*time.Second, 100, s.Records.UpdateBySessionIds,
+		jobs.DifficultyJob,
+	)
+	// Demos are heavy and one broken demo shouldn't hold others back
+	go consumeQueue(queue, jobs.DemoJob, 15*time.Second, 1, func(sessionIds []int) error {
+		return processDemos(s, sessionIds)
+	})
+
+	go runPeriodic(leader, "handleDanglingSessions", 1*time.Minute, func() error {
+		return handleDanglingSessions(s.Db)
+	})
+	go runPeriodic(leader, "health", 1*time.Minute, s.Health.Monitor)
+	go runPeriodic(leader, "calibration", 6*time.Hour, s.Difficulty.CalibrateMaps)
+}
+
+// Leases batches until the queue is drained.
+// If a batch fails, its sessions are handled one by one, so only failed sessions are retried later.
+func consumeQueue(
+	queue *jobs.Queue,
+	jobType jobs.JobType,
+	updateTime time.Duration,
+	batchSize int,
+	handle func(sessionIds []int) error,
+	waitFor ...jobs.JobType,
+) {
+	for range time.Tick(updateTime) {
+		for {
+			items, err := queue.Lease(jobType, batchSize, waitFor...)
+			if err != nil {
+				fmt.Printf("[%v] lease: %v\n", jobType, err)
+				break
+			}
+
+			if len(items) == 0 {
+				break
+			}
+
+			sessionIds := []int{}
+			for _, item := range items {
+				sessionIds = append(sessionIds, item.SessionId)
+			}
+
+			if len(items) == 1 {
+				finishJob(queue, items[0], handle(sessionIds))
+				continue
+			}
+
+			if err := handle(sessionIds); err == nil {
+				for _, item := range items {
+					finishJob(queue, item, nil)
+				}
+				continue
+			}
+
+			for _, item := range items {
+				finishJob(queue, item, handle([]int{item.SessionId}))
+			}
+		}
+	}
+}
+
+// Completes the job or returns it to the queue, depending on the handle error
+func finishJob(queue *jobs.Queue, item *jobs.Job, handleErr error) {
+	var err error
+	if handleErr != nil {
+		fmt.Printf("[%v] session %v: %v\n", item.Type, item.SessionId, handleErr)
+		err = queue.Fail(item)
+	} else {
+		err = queue.Complete(item)
+	}
+
+	if err != nil {
+		fmt.Printf("[%v] job %v: %v\n", item.Type, item.Id, err)
+	}
+}
+
+func runPeriodic(leader *jobs.Leader, name string, updateTime time.Duration, task func() error) {
+	for range time.Tick(updateTime) {
+		if !leader.IsLeader() {
+			continue
+		}
 
-	go setupProcessDemosTask(s)
+		if err := task(); err != nil {
+			fmt.Printf("[%v] error: %v\n", name, err)
+		}
+	}
 }
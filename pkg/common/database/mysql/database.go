@@ -1,8 +1,10 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -37,3 +39,32 @@ func (c *DBConnection) InitTables() {
 		panic(err)
 	}
 }
+
+// Runs fn while holding the named MySQL lock, so replicas started together don't run it concurrently.
+// The lock is bound to the connection and is released even if fn panics.
+func (c *DBConnection) WithLock(name string, timeout time.Duration, fn func()) error {
+	ctx := context.Background()
+
+	conn, err := c.Conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx,
+		`SELECT GET_LOCK(?, ?)`, name, int(timeout.Seconds()),
+	).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return fmt.Errorf("lock %v is not acquired in %v", name, timeout)
+	}
+	defer conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, name)
+
+	fn()
+
+	return nil
+}
@@ -16,17 +16,88 @@ func initSchema(db *sql.DB) error {
 			id INTEGER PRIMARY KEY AUTO_INCREMENT, 
 			name VARCHAR(64) NOT NULL, 
 			preview TEXT,
+
+			display_name VARCHAR(128),
+			is_official BOOLEAN NOT NULL DEFAULT 0,
+			workshop_id BIGINT UNSIGNED,
+			author VARCHAR(128),
 			
 			UNIQUE INDEX idx_uniq_maps_name (name) 
 		)`,
 	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS map_aliases (
+			alias VARCHAR(64) PRIMARY KEY,
+			map_id INTEGER NOT NULL,
+
+			FOREIGN KEY (map_id) REFERENCES maps(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS map_tags (
+			map_id INTEGER NOT NULL,
+			tag VARCHAR(32) NOT NULL,
+
+			PRIMARY KEY (map_id, tag),
+
+			FOREIGN KEY (map_id) REFERENCES maps(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+			INDEX idx_map_tags_tag (tag)
+		)`,
+	)
 	tx.Exec(`
 		CREATE TABLE IF NOT EXISTS server (
 			id INTEGER PRIMARY KEY AUTO_INCREMENT,
 			name VARCHAR(128) NOT NULL, 
 			address VARCHAR(64) NOT NULL,
+			guid VARCHAR(64),
+
+			display_name VARCHAR(128),
+			region VARCHAR(32),
+			description TEXT,
+			discord_url VARCHAR(512),
 		
-			UNIQUE INDEX idx_uniq_server_address (address)
+			INDEX idx_server_address (address),
+			INDEX idx_server_region (region),
+			UNIQUE INDEX idx_uniq_server_guid (guid)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS server_address_history (
+			server_id INTEGER NOT NULL,
+			address VARCHAR(64) NOT NULL,
+
+			first_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY (server_id, address),
+
+			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS server_name_history (
+			server_id INTEGER NOT NULL,
+			name VARCHAR(128) NOT NULL,
+
+			first_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY (server_id, name),
+
+			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS server_tags (
+			server_id INTEGER NOT NULL,
+			tag VARCHAR(32) NOT NULL,
+
+			PRIMARY KEY (server_id, tag),
+
+			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+			INDEX idx_server_tags_tag (tag)
 		)`,
 	)
 	tx.Exec(`
@@ -38,6 +109,7 @@ func initSchema(db *sql.DB) error {
 			name VARCHAR(64) NOT NULL,
 			
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+			erased_at TIMESTAMP NULL,
 		
 			UNIQUE INDEX idx_uniq_users_auth (auth_id, auth_type)
 		)`,
@@ -58,17 +130,184 @@ func initSchema(db *sql.DB) error {
 			UNIQUE INDEX idx_uniq_users_steam_data (steam_id)
 		)`,
 	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_egs_data (
+			user_id INTEGER PRIMARY KEY NOT NULL,
+
+			account_id VARCHAR(64) NOT NULL,
+			name VARCHAR(128) NOT NULL,
+			avatar VARCHAR(256) NOT NULL DEFAULT '',
+			profile_url VARCHAR(256) NOT NULL DEFAULT '',
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+		
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			UNIQUE INDEX idx_uniq_users_egs_data (account_id)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_link (
+			user_id INTEGER PRIMARY KEY NOT NULL,
+			primary_user_id INTEGER NOT NULL,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+		
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (primary_user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			INDEX idx_users_link_primary (primary_user_id)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_link_code (
+			code VARCHAR(16) PRIMARY KEY NOT NULL,
+			user_id INTEGER NOT NULL,
+
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+		
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_token_family (
+			id INTEGER PRIMARY KEY AUTO_INCREMENT,
+			user_id INTEGER NOT NULL,
+
+			device VARCHAR(256) NOT NULL DEFAULT '',
+			ip VARCHAR(64) NOT NULL DEFAULT '',
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+			last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+			revoked_at TIMESTAMP NULL,
+		
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			INDEX idx_users_token_family_user (user_id, revoked_at)
+		)`,
+	)
 	tx.Exec(`
 		CREATE TABLE IF NOT EXISTS users_token (
 			id INTEGER PRIMARY KEY AUTO_INCREMENT,
 			user_id INTEGER NOT NULL,
+			family_id INTEGER NOT NULL,
 			
-			token TEXT NOT NULL,
+			token_hash CHAR(64) NOT NULL,
+			used_at TIMESTAMP NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
 		
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (family_id) REFERENCES users_token_family(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			UNIQUE INDEX idx_uniq_users_token_hash (token_hash)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_privacy (
+			user_id INTEGER PRIMARY KEY,
+
+			hide_profile BOOLEAN NOT NULL DEFAULT 0,
+			hide_from_leaderboards BOOLEAN NOT NULL DEFAULT 0,
+			hide_social BOOLEAN NOT NULL DEFAULT 0,
+			anonymize_name BOOLEAN NOT NULL DEFAULT 0,
+
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		
 			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
 		)`,
 	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_follow (
+			user_id INTEGER NOT NULL,
+			followed_user_id INTEGER NOT NULL,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY (user_id, followed_user_id),
+
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (followed_user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			INDEX idx_users_follow_followed (followed_user_id)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_api_token (
+			id INTEGER PRIMARY KEY AUTO_INCREMENT,
+			user_id INTEGER NOT NULL,
+
+			name VARCHAR(64) NOT NULL,
+			prefix VARCHAR(16) NOT NULL,
+			token_hash CHAR(64) NOT NULL,
+			scopes VARCHAR(256) NOT NULL,
+			rate_limit INTEGER NOT NULL,
+
+			usage_count BIGINT NOT NULL DEFAULT 0,
+			last_used_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+			revoked_at TIMESTAMP NULL,
+		
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			UNIQUE INDEX idx_uniq_users_api_token_hash (token_hash),
+			INDEX idx_users_api_token_user (user_id, revoked_at)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_role (
+			user_id INTEGER NOT NULL,
+			role INTEGER NOT NULL,
+			server_id INTEGER NOT NULL DEFAULT 0,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY (user_id, role, server_id),
+
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_ban (
+			id INTEGER PRIMARY KEY AUTO_INCREMENT,
+			user_id INTEGER NOT NULL,
+			moderator_id INTEGER,
+
+			reason TEXT NOT NULL,
+			expires_at TIMESTAMP NULL,
+			revoked_at TIMESTAMP NULL,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (moderator_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE SET NULL,
+
+			INDEX idx_users_ban_user_id (user_id)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS moderation_log (
+			id INTEGER PRIMARY KEY AUTO_INCREMENT,
+			moderator_id INTEGER,
+			action INTEGER NOT NULL,
+
+			user_id INTEGER,
+			session_id INTEGER,
+			reason TEXT,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			FOREIGN KEY (moderator_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE SET NULL,
+
+			INDEX idx_moderation_log_user_id (user_id),
+			INDEX idx_moderation_log_session_id (session_id)
+		)`,
+	)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS users_ban_erased (
+			auth_hash CHAR(64) PRIMARY KEY,
+
+			reason TEXT NOT NULL,
+			expires_at TIMESTAMP NULL,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	)
 	tx.Exec(`
 		CREATE TABLE IF NOT EXISTS session (
 			id INTEGER PRIMARY KEY AUTO_INCREMENT,
@@ -88,6 +327,7 @@ func initSchema(db *sql.DB) error {
 			completed_at TIMESTAMP,
 
 			is_completed BOOLEAN GENERATED ALWAYS AS (status IN (-1,2,3,4)) STORED,
+			is_excluded BOOLEAN NOT NULL DEFAULT 0,
 
 			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE,
 			FOREIGN KEY (map_id) REFERENCES maps(id) ON UPDATE CASCADE ON DELETE CASCADE,
@@ -158,6 +398,24 @@ func initSchema(db *sql.DB) error {
 			FOREIGN KEY (session_id) REFERENCES session(id) ON UPDATE CASCADE ON DELETE CASCADE
 		)
 	`)
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS map_difficulty (
+			map_id INTEGER PRIMARY KEY NOT NULL,
+
+			factor REAL NOT NULL DEFAULT 1,
+			factor_low REAL NOT NULL DEFAULT 1,
+			factor_high REAL NOT NULL DEFAULT 1,
+			sessions INTEGER NOT NULL DEFAULT 0,
+
+			win_rate REAL NOT NULL DEFAULT 0,
+			death_rate REAL NOT NULL DEFAULT 0,
+			duration_ratio REAL NOT NULL DEFAULT 1,
+
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			FOREIGN KEY (map_id) REFERENCES maps(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)
+	`)
 	tx.Exec(`
 		CREATE TABLE IF NOT EXISTS wave_stats (
 			id INTEGER PRIMARY KEY AUTO_INCREMENT,
@@ -455,5 +713,157 @@ func initSchema(db *sql.DB) error {
 		)
 	`)
 
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS user_achievements (
+			user_id INTEGER NOT NULL,
+			achievement_id VARCHAR(64) NOT NULL,
+			session_id INTEGER,
+
+			unlocked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY (user_id, achievement_id),
+
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (session_id) REFERENCES session(id) ON UPDATE SET NULL ON DELETE SET NULL,
+
+			INDEX idx_user_achievements_achievement_id (achievement_id)
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS user_records (
+			user_id INTEGER NOT NULL,
+			perk INTEGER NOT NULL,
+			record_type INTEGER NOT NULL,
+
+			value REAL NOT NULL,
+
+			session_id INTEGER,
+			wave INTEGER,
+
+			achieved_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY (user_id, perk, record_type),
+
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (session_id) REFERENCES session(id) ON UPDATE SET NULL ON DELETE SET NULL
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS organization (
+			id INTEGER PRIMARY KEY AUTO_INCREMENT,
+			slug VARCHAR(64) NOT NULL UNIQUE,
+			name VARCHAR(128) NOT NULL,
+
+			description TEXT,
+			logo_url VARCHAR(512),
+			banner_url VARCHAR(512),
+			accent_color VARCHAR(16),
+			website_url VARCHAR(512),
+			discord_url VARCHAR(512),
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS organization_member (
+			organization_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			role INTEGER NOT NULL,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			PRIMARY KEY (organization_id, user_id),
+
+			FOREIGN KEY (organization_id) REFERENCES organization(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS organization_server (
+			server_id INTEGER PRIMARY KEY,
+			organization_id INTEGER NOT NULL,
+
+			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (organization_id) REFERENCES organization(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS server_heartbeat (
+			server_id INTEGER NOT NULL,
+			minute DATETIME NOT NULL,
+
+			PRIMARY KEY (server_id, minute),
+
+			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+			INDEX idx_server_heartbeat_minute (minute)
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS session_aborted (
+			session_id INTEGER PRIMARY KEY,
+			server_id INTEGER NOT NULL,
+
+			aborted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			FOREIGN KEY (session_id) REFERENCES session(id) ON UPDATE CASCADE ON DELETE CASCADE,
+			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+			INDEX idx_session_aborted_server_id_aborted_at (server_id, aborted_at)
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS server_alert (
+			id INTEGER PRIMARY KEY AUTO_INCREMENT,
+			server_id INTEGER NOT NULL,
+			webhook VARCHAR(512) NOT NULL,
+
+			silent_minutes INTEGER NOT NULL DEFAULT 0,
+			aborted_threshold INTEGER NOT NULL DEFAULT 0,
+			aborted_window_minutes INTEGER NOT NULL DEFAULT 0,
+
+			silent_notified_at TIMESTAMP NULL,
+			aborted_notified_at TIMESTAMP NULL,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			FOREIGN KEY (server_id) REFERENCES server(id) ON UPDATE CASCADE ON DELETE CASCADE
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS job_queue (
+			id BIGINT PRIMARY KEY AUTO_INCREMENT,
+			type VARCHAR(32) NOT NULL,
+			session_id INTEGER NOT NULL,
+
+			generation INTEGER NOT NULL DEFAULT 0,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			run_after TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			locked_by VARCHAR(64),
+			locked_until TIMESTAMP NULL,
+
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+			UNIQUE INDEX idx_uniq_job_queue_type_session (type, session_id),
+			INDEX idx_job_queue_type_run_after (type, run_after)
+		)
+	`)
+
+	tx.Exec(`
+		CREATE TABLE IF NOT EXISTS worker_leader (
+			name VARCHAR(64) PRIMARY KEY,
+			holder VARCHAR(64) NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)
+	`)
+
 	return err
 }
@@ -47,6 +47,23 @@ func initStored(db *sql.DB) error {
 				);
 
 			-- Otherwise cancel sessions with existed player data.
+			-- Aborted sessions are tracked for server health monitoring.
+
+			INSERT IGNORE INTO session_aborted (session_id, server_id)
+			WITH current_sessions AS (
+				SELECT server_id, max(id) as max_id 
+				FROM session
+				GROUP BY server_id
+			)
+			SELECT s.id, s.server_id
+			FROM session s
+			INNER JOIN current_sessions cte on s.server_id = cte.server_id
+			WHERE 
+				s.status IN (0, 1) AND 
+				(
+					timestampdiff(MINUTE, s.updated_at, CURRENT_TIMESTAMP) > minutes OR 
+					s.id != cte.max_id
+				);
 			
 			WITH current_sessions AS (
 				SELECT server_id, max(id) as max_id 
@@ -335,7 +352,7 @@ func initStored(db *sql.DB) error {
 				INNER JOIN wave_stats ws ON ws.session_id = session.id
 				INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
 				INNER JOIN wave_stats_player_kills k ON k.player_stats_id = wsp.id
-				WHERE session.id = session_id
+				WHERE session.id = session_id AND NOT session.is_excluded
 				GROUP BY session.id, wsp.player_id
 			) as new
 			ON DUPLICATE KEY UPDATE 
@@ -403,7 +420,7 @@ func initStored(db *sql.DB) error {
 				INNER JOIN wave_stats ws ON ws.session_id = session.id
 				INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
 				INNER JOIN wave_stats_player_kills k ON k.player_stats_id = wsp.id
-				WHERE session.id = session_id
+				WHERE session.id = session_id AND NOT session.is_excluded
 				GROUP BY session.id, wsp.player_id, wsp.perk
 			) as new
 			ON DUPLICATE KEY UPDATE
@@ -67,7 +67,7 @@ func initTriggers(db *sql.DB) error {
 					weekly.user_id = old.user_id
 				SET weekly.buffs_active_length = weekly.buffs_active_length + new.buffs_active_length, 
 					weekly.buffs_total_length = weekly.buffs_total_length + new.buffs_total_length
-				WHERE session.id = old.session_id;
+				WHERE session.id = old.session_id AND NOT session.is_excluded;
 			END IF;
 		END;
 	`)
@@ -0,0 +1,75 @@
+package egsapi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// In-memory profile provider for tests, doesn't call Epic services
+type FakeEgsApiUserService struct {
+	mu sync.RWMutex
+
+	players map[string]GetUserSummaryPlayer
+	// Authorization code -> account id
+	codes map[string]string
+}
+
+func NewFakeEgsApiUserService() *FakeEgsApiUserService {
+	return &FakeEgsApiUserService{
+		players: map[string]GetUserSummaryPlayer{},
+		codes:   map[string]string{},
+	}
+}
+
+func (s *FakeEgsApiUserService) AddUser(player GetUserSummaryPlayer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if player.ProfileUrl == "" {
+		player.ProfileUrl = fmt.Sprintf("%v/%v", profileUrl, player.AccountId)
+	}
+
+	s.players[player.AccountId] = player
+}
+
+// Registers single use authorization code which logs in as the account
+func (s *FakeEgsApiUserService) AddAuthCode(code, accountId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.codes[code] = accountId
+}
+
+func (s *FakeEgsApiUserService) GetUserSummary(accountIds []string) ([]GetUserSummaryPlayer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summaries := []GetUserSummaryPlayer{}
+	for _, accountId := range accountIds {
+		if player, ok := s.players[accountId]; ok {
+			summaries = append(summaries, player)
+		}
+	}
+
+	return summaries, nil
+}
+
+func (s *FakeEgsApiUserService) ValidateAuthCode(req ValidateAuthCodeRequest) (*GetUserSummaryPlayer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accountId, ok := s.codes[req.Code]
+	if !ok {
+		return nil, errors.New("invalid authorization code")
+	}
+
+	delete(s.codes, req.Code)
+
+	player, ok := s.players[accountId]
+	if !ok {
+		return nil, fmt.Errorf("account %v not found", accountId)
+	}
+
+	return &player, nil
+}
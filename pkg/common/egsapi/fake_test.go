@@ -0,0 +1,49 @@
+package egsapi
+
+import "testing"
+
+func TestFakeValidateAuthCode(t *testing.T) {
+	service := NewFakeEgsApiUserService()
+	service.AddUser(GetUserSummaryPlayer{AccountId: "epic-account", Name: "Epic Player"})
+	service.AddAuthCode("valid-code", "epic-account")
+	service.AddAuthCode("orphan-code", "missing-account")
+
+	var api EgsApi = service
+
+	player, err := api.ValidateAuthCode(ValidateAuthCodeRequest{Code: "valid-code"})
+	if err != nil {
+		t.Fatalf("ValidateAuthCode: %v", err)
+	}
+
+	if player.AccountId != "epic-account" || player.Name != "Epic Player" {
+		t.Errorf("unexpected player %+v", player)
+	}
+
+	if player.ProfileUrl != profileUrl+"/epic-account" {
+		t.Errorf("unexpected profile url %v", player.ProfileUrl)
+	}
+
+	// Codes are single use like real authorization codes
+	if _, err := api.ValidateAuthCode(ValidateAuthCodeRequest{Code: "valid-code"}); err == nil {
+		t.Error("code is accepted twice")
+	}
+
+	if _, err := api.ValidateAuthCode(ValidateAuthCodeRequest{Code: "orphan-code"}); err == nil {
+		t.Error("code of unknown account is accepted")
+	}
+}
+
+func TestFakeGetUserSummary(t *testing.T) {
+	service := NewFakeEgsApiUserService()
+	service.AddUser(GetUserSummaryPlayer{AccountId: "first", Name: "First"})
+	service.AddUser(GetUserSummaryPlayer{AccountId: "second", Name: "Second"})
+
+	summaries, err := service.GetUserSummary([]string{"first", "unknown", "second"})
+	if err != nil {
+		t.Fatalf("GetUserSummary: %v", err)
+	}
+
+	if len(summaries) != 2 || summaries[0].Name != "First" || summaries[1].Name != "Second" {
+		t.Errorf("unexpected summaries %+v", summaries)
+	}
+}
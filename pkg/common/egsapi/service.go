@@ -0,0 +1,222 @@
+package egsapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chenyahui/gin-cache/persist"
+)
+
+const (
+	defaultBaseUrl = "https://api.epicgames.dev"
+	profileUrl     = "https://store.epicgames.com/u"
+)
+
+// Epic Games Store profile provider, implemented by EgsApiUserService
+// and by FakeEgsApiUserService in tests
+type EgsApi interface {
+	GetUserSummary(accountIds []string) ([]GetUserSummaryPlayer, error)
+	ValidateAuthCode(req ValidateAuthCodeRequest) (*GetUserSummaryPlayer, error)
+}
+
+type EgsApiUserService struct {
+	baseUrl      string
+	clientId     string
+	clientSecret string
+	client       *http.Client
+
+	memoryStore *persist.MemoryStore
+
+	// Client credentials token used for account lookups
+	tokenMu        sync.Mutex
+	token          string
+	tokenExpiresAt time.Time
+}
+
+func NewEgsApiUserService(clientId, clientSecret string) *EgsApiUserService {
+	return &EgsApiUserService{
+		baseUrl:      defaultBaseUrl,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		memoryStore:  persist.NewMemoryStore(5 * time.Minute),
+	}
+}
+
+func (s *EgsApiUserService) isConfigured() bool {
+	return s.clientId != "" && s.clientSecret != ""
+}
+
+func (s *EgsApiUserService) GetUserSummary(accountIds []string) ([]GetUserSummaryPlayer, error) {
+	summaries := []GetUserSummaryPlayer{}
+	if !s.isConfigured() {
+		return summaries, nil
+	}
+
+	chunkSize := 50
+
+	var cached GetUserSummaryPlayer
+	chunk := []string{}
+	for _, accountId := range accountIds {
+		if err := s.memoryStore.Get(accountId, &cached); err == nil {
+			summaries = append(summaries, cached)
+			continue
+		}
+
+		chunk = append(chunk, accountId)
+
+		if len(chunk) == chunkSize {
+			data, err := s.getAccountsInternal(chunk)
+			if err != nil {
+				fmt.Printf("warn: %v\n", err)
+				return summaries, nil
+			}
+
+			summaries = append(summaries, data...)
+			chunk = chunk[:0]
+		}
+	}
+
+	if len(chunk) > 0 {
+		data, err := s.getAccountsInternal(chunk)
+		if err != nil {
+			fmt.Printf("warn: %v\n", err)
+			return summaries, nil
+		}
+
+		summaries = append(summaries, data...)
+	}
+
+	for _, item := range summaries {
+		s.memoryStore.Set(item.AccountId, item, 5*time.Minute)
+	}
+
+	return summaries, nil
+}
+
+func (s *EgsApiUserService) getAccountsInternal(accountIds []string) ([]GetUserSummaryPlayer, error) {
+	token, err := s.getClientToken()
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for _, accountId := range accountIds {
+		query.Add("accountId", accountId)
+	}
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("%v/epic/id/v2/accounts?%v", s.baseUrl, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header.Add("Authorization", "Bearer "+token)
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("getAccountsInternal: %v", res.Status)
+	}
+
+	var players []GetUserSummaryPlayer
+	if err := json.NewDecoder(res.Body).Decode(&players); err != nil {
+		return nil, err
+	}
+
+	for i := range players {
+		players[i].ProfileUrl = fmt.Sprintf("%v/%v", profileUrl, players[i].AccountId)
+	}
+
+	return players, nil
+}
+
+func (s *EgsApiUserService) requestToken(form url.Values) (*tokenResponse, error) {
+	r, err := http.NewRequest("POST", s.baseUrl+"/epic/oauth/v2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	r.SetBasicAuth(s.clientId, s.clientSecret)
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("requestToken: %v", res.Status)
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *EgsApiUserService) getClientToken() (string, error) {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.tokenExpiresAt) {
+		return s.token, nil
+	}
+
+	token, err := s.requestToken(url.Values{"grant_type": {"client_credentials"}})
+	if err != nil {
+		return "", err
+	}
+
+	// Refresh a minute before expiration
+	s.token = token.AccessToken
+	s.tokenExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn-60) * time.Second)
+
+	return s.token, nil
+}
+
+// Exchanges authorization code of Epic Account Services login for the account
+func (s *EgsApiUserService) ValidateAuthCode(req ValidateAuthCodeRequest) (*GetUserSummaryPlayer, error) {
+	if !s.isConfigured() {
+		return nil, errors.New("egs login is not configured")
+	}
+
+	if req.Code == "" {
+		return nil, errors.New("empty authorization code")
+	}
+
+	token, err := s.requestToken(url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {req.Code},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if token.AccountId == "" {
+		return nil, errors.New("invalid account id")
+	}
+
+	summary, err := s.GetUserSummary([]string{token.AccountId})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(summary) == 0 {
+		return nil, fmt.Errorf("account %v not found", token.AccountId)
+	}
+
+	return &summary[0], nil
+}
@@ -0,0 +1,96 @@
+package egsapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Minimal Epic Account Services api: token endpoint and account lookup
+func newEgsServer(t *testing.T, code, accountId, name string) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /epic/oauth/v2/token", func(w http.ResponseWriter, r *http.Request) {
+		if clientId, secret, ok := r.BasicAuth(); !ok || clientId != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.PostFormValue("grant_type") {
+		case "client_credentials":
+			json.NewEncoder(w).Encode(tokenResponse{AccessToken: "client-token", ExpiresIn: 3600})
+		case "authorization_code":
+			if r.PostFormValue("code") != code {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			json.NewEncoder(w).Encode(tokenResponse{
+				AccessToken: "user-token", ExpiresIn: 3600, AccountId: accountId,
+			})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	mux.HandleFunc("GET /epic/id/v2/accounts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer client-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		players := []GetUserSummaryPlayer{}
+		for _, id := range r.URL.Query()["accountId"] {
+			if id == accountId {
+				players = append(players, GetUserSummaryPlayer{AccountId: id, Name: name})
+			}
+		}
+
+		json.NewEncoder(w).Encode(players)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestValidateAuthCode(t *testing.T) {
+	server := newEgsServer(t, "valid-code", "epic-account", "Epic Player")
+
+	service := NewEgsApiUserService("client", "secret")
+	service.baseUrl = server.URL
+
+	player, err := service.ValidateAuthCode(ValidateAuthCodeRequest{Code: "valid-code"})
+	if err != nil {
+		t.Fatalf("ValidateAuthCode: %v", err)
+	}
+
+	if player.AccountId != "epic-account" || player.Name != "Epic Player" {
+		t.Errorf("unexpected player %+v", player)
+	}
+
+	if player.ProfileUrl != profileUrl+"/epic-account" {
+		t.Errorf("unexpected profile url %v", player.ProfileUrl)
+	}
+
+	if _, err := service.ValidateAuthCode(ValidateAuthCodeRequest{Code: "wrong-code"}); err == nil {
+		t.Error("wrong code is accepted")
+	}
+
+	if _, err := service.ValidateAuthCode(ValidateAuthCodeRequest{}); err == nil {
+		t.Error("empty code is accepted")
+	}
+}
+
+func TestValidateAuthCodeNotConfigured(t *testing.T) {
+	server := newEgsServer(t, "valid-code", "epic-account", "Epic Player")
+
+	service := NewEgsApiUserService("", "")
+	service.baseUrl = server.URL
+
+	if _, err := service.ValidateAuthCode(ValidateAuthCodeRequest{Code: "valid-code"}); err == nil {
+		t.Error("login works without client credentials")
+	}
+}
@@ -0,0 +1,18 @@
+package egsapi
+
+type GetUserSummaryPlayer struct {
+	AccountId  string `json:"accountId"`
+	Name       string `json:"displayName"`
+	ProfileUrl string `json:"-"`
+	Avatar     string `json:"-"`
+}
+
+type ValidateAuthCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	AccountId   string `json:"account_id"`
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+const (
+	ApiTokenHeader = "X-Api-Token"
+	apiTokenIdKey  = "apiTokenId"
+)
+
+var (
+	ErrApiTokenScope       = errors.New("api token doesn't have required scope")
+	ErrApiTokenRateLimited = errors.New("api token rate limit exceeded")
+)
+
+// Implemented by personal api tokens service, returns ErrApiTokenScope
+// and ErrApiTokenRateLimited for valid tokens which can't be used for the request
+type ApiTokenValidator interface {
+	ValidateApiToken(token string, scope models.ApiScope) (*models.ApiTokenGrant, error)
+}
+
+var apiTokenValidator ApiTokenValidator
+
+func SetApiTokenValidator(validator ApiTokenValidator) {
+	apiTokenValidator = validator
+}
+
+// Validates personal api token from X-Api-Token header, requests without the header pass anonymously.
+// Tokens with profile scope authorize the request as the token owner.
+func ApiTokenMiddleWave(scope models.ApiScope) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.GetHeader(ApiTokenHeader)
+		if token == "" || apiTokenValidator == nil {
+			ctx.Next()
+			return
+		}
+
+		grant, err := apiTokenValidator.ValidateApiToken(token, scope)
+		if err == ErrApiTokenRateLimited {
+			ctx.String(http.StatusTooManyRequests, err.Error())
+			ctx.Abort()
+			return
+		} else if err == ErrApiTokenScope {
+			ctx.String(http.StatusForbidden, err.Error())
+			ctx.Abort()
+			return
+		} else if err != nil {
+			ctx.JSON(401, gin.H{})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set(apiTokenIdKey, grant.TokenId)
+
+		if grant.HasScope(models.ApiScopeProfileRead) {
+			ctx.Set("user", grant.User)
+		}
+
+		ctx.Next()
+	}
+}
@@ -16,6 +16,12 @@ type authHeader struct {
 }
 
 func AuthMiddleWave(ctx *gin.Context) {
+	// Already authorized with personal api token
+	if _, ok := ctx.Get("user"); ok {
+		ctx.Next()
+		return
+	}
+
 	accessToken, err := retrieveAccessToken(ctx)
 	if err != nil {
 		ctx.JSON(401, gin.H{})
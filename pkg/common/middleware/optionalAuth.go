@@ -10,6 +10,12 @@ import (
 )
 
 func OptionalAuthMiddleWave(ctx *gin.Context) {
+	// Already authorized with personal api token
+	if _, ok := ctx.Get("user"); ok {
+		ctx.Next()
+		return
+	}
+
 	accessToken, err := retrieveAccessToken(ctx)
 	if err != nil {
 		ctx.Next()
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/config"
+	"github.com/theggv/kf2-stats-backend/pkg/common/ratelimit"
+)
+
+// Header with one of RATE_LIMIT_EXEMPT_KEYS, set by the frontend for server side requests
+const RateLimitKeyHeader = "X-Rate-Limit-Key"
+
+type RateLimitClass string
+
+const (
+	// Cached analytics endpoints
+	RateLimitAnalytics RateLimitClass = "analytics"
+	// Endpoints running large queries for every request body
+	RateLimitHeavy RateLimitClass = "heavy"
+)
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = map[RateLimitClass]*ratelimit.Limiter{}
+)
+
+func getRateLimiter(class RateLimitClass) *ratelimit.Limiter {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	if limiter, ok := rateLimiters[class]; ok {
+		return limiter
+	}
+
+	var limiter *ratelimit.Limiter
+	switch class {
+	case RateLimitHeavy:
+		limiter = ratelimit.NewLimiter(config.Instance.RateLimitHeavyPerMinute, config.Instance.RateLimitHeavyBurst)
+	default:
+		limiter = ratelimit.NewLimiter(config.Instance.RateLimitAnalyticsPerMinute, config.Instance.RateLimitAnalyticsBurst)
+	}
+
+	rateLimiters[class] = limiter
+
+	return limiter
+}
+
+func isRateLimitExempt(ctx *gin.Context) bool {
+	if key := ctx.GetHeader(RateLimitKeyHeader); key != "" &&
+		slices.Contains(config.Instance.RateLimitExemptKeys, key) {
+		return true
+	}
+
+	ip := net.ParseIP(ctx.ClientIP())
+	if ip == nil {
+		return false
+	}
+
+	for _, item := range config.Instance.RateLimitExemptIps {
+		item = strings.TrimSpace(item)
+
+		if _, network, err := net.ParseCIDR(item); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if exemptIp := net.ParseIP(item); exemptIp != nil && exemptIp.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Requests with validated personal api token share the bucket of the token, others are limited per ip.
+// Should go after ApiTokenMiddleWave, so random token headers can't be used to get a fresh bucket.
+func rateLimitKey(ctx *gin.Context) string {
+	if tokenId, ok := ctx.Get(apiTokenIdKey); ok {
+		return fmt.Sprintf("token:%v", tokenId)
+	}
+
+	return "ip:" + ctx.ClientIP()
+}
+
+// Limits requests with a token bucket per client and route class.
+// Responds with 429 and Retry-After header when the bucket is empty.
+func RateLimitMiddleWave(class RateLimitClass) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !config.Instance.RateLimitEnabled || isRateLimitExempt(ctx) {
+			ctx.Next()
+			return
+		}
+
+		res := getRateLimiter(class).Allow(rateLimitKey(ctx))
+
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+
+		if !res.Allowed {
+			retryAfter := int(math.Ceil(res.RetryAfter.Seconds()))
+
+			ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+			ctx.String(http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry in %v seconds", retryAfter))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/config"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+// Allows requests from users having one of the roles globally.
+// Requests with the api key in the "key" query are allowed as well.
+func RoleMiddleWave(roles ...models.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		checkRole(ctx, 0, roles)
+	}
+}
+
+// Same as RoleMiddleWave, but also allows roles scoped to the server from the path param
+func ServerRoleMiddleWave(param string, roles ...models.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		serverId, err := strconv.Atoi(ctx.Params.ByName(param))
+		if err != nil {
+			ctx.String(400, err.Error())
+			ctx.Abort()
+			return
+		}
+
+		checkRole(ctx, serverId, roles)
+	}
+}
+
+func checkRole(ctx *gin.Context, serverId int, roles []models.Role) {
+	if key := ctx.Query("key"); key != "" && key == config.Instance.Token {
+		ctx.Next()
+		return
+	}
+
+	accessToken, err := retrieveAccessToken(ctx)
+	if err != nil {
+		ctx.JSON(401, gin.H{})
+		ctx.Abort()
+		return
+	}
+
+	payload, err := util.ValidateToken(accessToken, config.Instance.JwtAccessSecretKey, models.TokenVersion)
+	if err != nil {
+		ctx.JSON(401, gin.H{})
+		ctx.Abort()
+		return
+	}
+
+	var user models.TokenPayload
+	jsonData, _ := json.Marshal(payload)
+	json.Unmarshal(jsonData, &user)
+
+	if !user.HasRole(serverId, roles...) {
+		ctx.JSON(403, gin.H{})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("user", user)
+
+	ctx.Next()
+}
@@ -2,11 +2,81 @@ package models
 
 const TokenVersion = 1
 
+type Role = int
+
+const (
+	SuperAdmin Role = iota + 1
+	ServerAdmin
+	Moderator
+)
+
+type RoleGrant struct {
+	Role Role `json:"role"`
+	// Server the role is scoped to, 0 means the role is global
+	ServerId int `json:"server_id,omitempty"`
+}
+
 type TokenPayload struct {
 	UserId int    `json:"user_id"`
 	Name   string `json:"name"`
 
+	AuthType AuthType `json:"auth_type"`
+	AuthId   string   `json:"auth_id"`
+
+	// Set for steam users only, kept for older clients
 	SteamId    string `json:"steam_id"`
 	Avatar     string `json:"avatar"`
 	ProfileUrl string `json:"profile_url"`
+
+	Roles []RoleGrant `json:"roles"`
+}
+
+// Checks if user has one of the roles globally or scoped to the server.
+// Superadmin has every role.
+func (p *TokenPayload) HasRole(serverId int, roles ...Role) bool {
+	for _, grant := range p.Roles {
+		if grant.Role == SuperAdmin {
+			return true
+		}
+
+		if grant.ServerId != 0 && grant.ServerId != serverId {
+			continue
+		}
+
+		for _, role := range roles {
+			if grant.Role == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type ApiScope = string
+
+const (
+	// Read-only access to analytics, leaderboards and matches
+	ApiScopeAnalyticsRead ApiScope = "analytics:read"
+	// Requests are authorized as the token owner on endpoints showing own profile data
+	ApiScopeProfileRead ApiScope = "profile:read"
+)
+
+var ApiScopes = []ApiScope{ApiScopeAnalyticsRead, ApiScopeProfileRead}
+
+// Result of personal api token validation
+type ApiTokenGrant struct {
+	TokenId int
+	User    TokenPayload
+	Scopes  []ApiScope
+}
+
+func (g *ApiTokenGrant) HasScope(scope ApiScope) bool {
+	for _, item := range g.Scopes {
+		if item == scope {
+			return true
+		}
+	}
+
+	return false
 }
@@ -22,6 +22,8 @@ const (
 	Survivalist
 )
 
+const MaxPerkLevel = 25
+
 type GameMode = int
 
 const (
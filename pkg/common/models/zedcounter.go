@@ -31,6 +31,29 @@ func (c ZedCounter) GetTotalLarges() int {
 	return c.Scrake + c.FP + c.QP
 }
 
+func (c *ZedCounter) Add(other ZedCounter) {
+	c.Cyst += other.Cyst
+	c.AlphaClot += other.AlphaClot
+	c.Slasher += other.Slasher
+	c.Stalker += other.Stalker
+	c.Crawler += other.Crawler
+	c.Gorefast += other.Gorefast
+	c.Rioter += other.Rioter
+	c.EliteCrawler += other.EliteCrawler
+	c.Gorefiend += other.Gorefiend
+
+	c.Siren += other.Siren
+	c.Bloat += other.Bloat
+	c.Edar += other.Edar
+	c.Husk += other.Husk
+
+	c.Scrake += other.Scrake
+	c.FP += other.FP
+	c.QP += other.QP
+	c.Boss += other.Boss
+	c.Custom += other.Custom
+}
+
 func (c ZedCounter) ToMap() ZedsMap {
 	data := ZedsMap{
 		"cyst":          c.Cyst,
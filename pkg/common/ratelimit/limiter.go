@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// In-memory token bucket per key. Buckets refill with the rate per minute up to the burst size.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+
+	// Tokens per second
+	rate  float64
+	burst float64
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+func NewLimiter(perMinute, burst int) *Limiter {
+	limiter := Limiter{
+		buckets: map[string]*bucket{},
+		rate:    float64(max(perMinute, 1)) / 60,
+		burst:   float64(max(burst, 1)),
+	}
+
+	go limiter.initCleanup(time.Minute)
+
+	return &limiter
+}
+
+func (l *Limiter) Allow(key string) Result {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	item, ok := l.buckets[key]
+	if !ok {
+		item = &bucket{tokens: l.burst, updatedAt: now}
+		l.buckets[key] = item
+	}
+
+	item.tokens = math.Min(l.burst, item.tokens+now.Sub(item.updatedAt).Seconds()*l.rate)
+	item.updatedAt = now
+
+	res := Result{
+		Limit: int(l.burst),
+	}
+
+	if item.tokens < 1 {
+		res.RetryAfter = time.Duration((1 - item.tokens) / l.rate * float64(time.Second))
+		return res
+	}
+
+	item.tokens -= 1
+
+	res.Allowed = true
+	res.Remaining = int(item.tokens)
+
+	return res
+}
+
+func (l *Limiter) initCleanup(updateTime time.Duration) {
+	for range time.Tick(updateTime) {
+		l.cleanup()
+	}
+}
+
+// Drops buckets which are full again, they are recreated on the next request
+func (l *Limiter) cleanup() {
+	refillTime := time.Duration(l.burst / l.rate * float64(time.Second))
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, item := range l.buckets {
+		if now.Sub(item.updatedAt) >= refillTime {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package steamapi
+
+import "sync"
+
+// In-memory friends provider for tests, doesn't call Steam
+type FakeSteamFriendsService struct {
+	mu sync.RWMutex
+
+	friends map[string]map[string]bool
+}
+
+func NewFakeSteamFriendsService() *FakeSteamFriendsService {
+	return &FakeSteamFriendsService{
+		friends: map[string]map[string]bool{},
+	}
+}
+
+// Friendship is mutual like in Steam
+func (s *FakeSteamFriendsService) AddFriends(steamId string, friendIds ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, friendId := range friendIds {
+		for _, pair := range [][2]string{{steamId, friendId}, {friendId, steamId}} {
+			if _, ok := s.friends[pair[0]]; !ok {
+				s.friends[pair[0]] = map[string]bool{}
+			}
+
+			s.friends[pair[0]][pair[1]] = true
+		}
+	}
+}
+
+func (s *FakeSteamFriendsService) GetFriendList(steamId string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	friends := []string{}
+	for friendId := range s.friends[steamId] {
+		friends = append(friends, friendId)
+	}
+
+	return friends, nil
+}
@@ -0,0 +1,31 @@
+package steamapi
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFakeGetFriendList(t *testing.T) {
+	service := NewFakeSteamFriendsService()
+	service.AddFriends("first", "second", "third")
+	service.AddFriends("second", "third")
+
+	var api FriendsApi = service
+
+	for steamId, expected := range map[string][]string{
+		"first":   {"second", "third"},
+		"second":  {"first", "third"},
+		"third":   {"first", "second"},
+		"unknown": {},
+	} {
+		friends, err := api.GetFriendList(steamId)
+		if err != nil {
+			t.Fatalf("GetFriendList: %v", err)
+		}
+
+		slices.Sort(friends)
+		if !slices.Equal(friends, expected) {
+			t.Errorf("friends of %v: expected %v, got %v", steamId, expected, friends)
+		}
+	}
+}
@@ -14,12 +14,19 @@ import (
 )
 
 const (
-	baseUrl = "https://api.steampowered.com"
+	defaultBaseUrl = "https://api.steampowered.com"
 )
 
+// Steam friends provider, implemented by SteamApiUserService
+// and by FakeSteamFriendsService in tests
+type FriendsApi interface {
+	GetFriendList(steamId string) ([]string, error)
+}
+
 type SteamApiUserService struct {
-	apiKey string
-	client *http.Client
+	baseUrl string
+	apiKey  string
+	client  *http.Client
 
 	memoryStore *persist.MemoryStore
 }
@@ -32,6 +39,7 @@ type SteamUser struct {
 
 func NewSteamApiUserService(apiKey string) *SteamApiUserService {
 	return &SteamApiUserService{
+		baseUrl:     defaultBaseUrl,
 		apiKey:      apiKey,
 		client:      &http.Client{},
 		memoryStore: persist.NewMemoryStore(5 * time.Minute),
@@ -83,7 +91,7 @@ func (s *SteamApiUserService) GetUserSummary(steamIds []string) ([]GetUserSummar
 
 func (s *SteamApiUserService) getUsersSummaryInternal(steamIds []string) ([]GetUserSummaryPlayer, error) {
 	url := fmt.Sprintf("%v/ISteamUser/GetPlayerSummaries/v0002/?key=%v&steamids=%v",
-		baseUrl, s.apiKey, strings.Join(steamIds, ","),
+		s.baseUrl, s.apiKey, strings.Join(steamIds, ","),
 	)
 
 	res, err := s.client.Get(url)
@@ -152,3 +160,40 @@ func (s *SteamApiUserService) ValidateOpenId(req ValidateOpenIdRequest) (*GetUse
 
 	return &summary[0], nil
 }
+
+// Returns steam ids of friends, fails if the friend list is private
+func (s *SteamApiUserService) GetFriendList(steamId string) ([]string, error) {
+	url := fmt.Sprintf("%v/ISteamUser/GetFriendList/v0001/?key=%v&steamid=%v&relationship=friend",
+		s.baseUrl, s.apiKey, steamId,
+	)
+
+	res, err := s.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("steam friend list is private")
+	}
+
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("GetFriendList: %v", res.Status)
+	}
+
+	var resJson GetFriendListResponse
+	if err := json.NewDecoder(res.Body).Decode(&resJson); err != nil {
+		return nil, err
+	}
+
+	friends := []string{}
+	if resJson.FriendsList == nil {
+		return friends, nil
+	}
+
+	for _, friend := range resJson.FriendsList.Friends {
+		friends = append(friends, friend.SteamId)
+	}
+
+	return friends, nil
+}
@@ -0,0 +1,75 @@
+package steamapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newSteamServer(t *testing.T, friends map[string][]string) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /ISteamUser/GetFriendList/v0001/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// Steam responds with 401 if the friend list is private
+		items, ok := friends[r.URL.Query().Get("steamid")]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		res := GetFriendListResponse{}
+		if len(items) > 0 {
+			res.FriendsList = &GetFriendListResponseFriends{}
+			for _, steamId := range items {
+				res.FriendsList.Friends = append(res.FriendsList.Friends, GetFriendListFriend{
+					SteamId: steamId, Relationship: "friend",
+				})
+			}
+		}
+
+		json.NewEncoder(w).Encode(res)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetFriendList(t *testing.T) {
+	server := newSteamServer(t, map[string][]string{
+		"76561198000000001": {"76561198000000002", "76561198000000003"},
+		"76561198000000004": {},
+	})
+
+	service := NewSteamApiUserService("key")
+	service.baseUrl = server.URL
+
+	friends, err := service.GetFriendList("76561198000000001")
+	if err != nil {
+		t.Fatalf("GetFriendList: %v", err)
+	}
+
+	if len(friends) != 2 || friends[0] != "76561198000000002" || friends[1] != "76561198000000003" {
+		t.Errorf("unexpected friends %v", friends)
+	}
+
+	friends, err = service.GetFriendList("76561198000000004")
+	if err != nil {
+		t.Fatalf("GetFriendList: %v", err)
+	}
+
+	if len(friends) != 0 {
+		t.Errorf("unexpected friends %v", friends)
+	}
+
+	if _, err := service.GetFriendList("76561198000000005"); err == nil {
+		t.Error("private friend list is returned")
+	}
+}
@@ -18,3 +18,17 @@ type GetUserSummaryResponse struct {
 type ValidateOpenIdRequest struct {
 	Params string `json:"params"`
 }
+
+type GetFriendListFriend struct {
+	SteamId      string `json:"steamid"`
+	Relationship string `json:"relationship"`
+	FriendSince  int64  `json:"friend_since"`
+}
+
+type GetFriendListResponseFriends struct {
+	Friends []GetFriendListFriend `json:"friends"`
+}
+
+type GetFriendListResponse struct {
+	FriendsList *GetFriendListResponseFriends `json:"friendslist"`
+}
@@ -3,26 +3,39 @@ package store
 import (
 	"database/sql"
 
+	analyticsCD "github.com/theggv/kf2-stats-backend/pkg/analytics/cd"
 	analyticsMaps "github.com/theggv/kf2-stats-backend/pkg/analytics/maps"
 	analyticsPerks "github.com/theggv/kf2-stats-backend/pkg/analytics/perks"
 	analyticsServer "github.com/theggv/kf2-stats-backend/pkg/analytics/server"
+	analyticsSquads "github.com/theggv/kf2-stats-backend/pkg/analytics/squads"
 	analyticsUsers "github.com/theggv/kf2-stats-backend/pkg/analytics/users"
+	"github.com/theggv/kf2-stats-backend/pkg/apitokens"
 	"github.com/theggv/kf2-stats-backend/pkg/auth"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
 	"github.com/theggv/kf2-stats-backend/pkg/common/config"
+	"github.com/theggv/kf2-stats-backend/pkg/common/egsapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/steamapi"
 	"github.com/theggv/kf2-stats-backend/pkg/leaderboards"
 	"github.com/theggv/kf2-stats-backend/pkg/maps"
 	"github.com/theggv/kf2-stats-backend/pkg/matches"
 	matchesFilter "github.com/theggv/kf2-stats-backend/pkg/matches/filter"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
+	"github.com/theggv/kf2-stats-backend/pkg/organizations"
 	"github.com/theggv/kf2-stats-backend/pkg/server"
+	"github.com/theggv/kf2-stats-backend/pkg/server/health"
 	"github.com/theggv/kf2-stats-backend/pkg/session"
 	"github.com/theggv/kf2-stats-backend/pkg/session/difficulty"
 	"github.com/theggv/kf2-stats-backend/pkg/stats"
 	"github.com/theggv/kf2-stats-backend/pkg/users"
+	"github.com/theggv/kf2-stats-backend/pkg/users/achievements"
+	"github.com/theggv/kf2-stats-backend/pkg/users/follows"
+	"github.com/theggv/kf2-stats-backend/pkg/users/gdpr"
+	"github.com/theggv/kf2-stats-backend/pkg/users/records"
 )
 
 type Store struct {
-	Db *sql.DB
+	Db    *sql.DB
+	Cache cachestore.Store
 
 	Auth     *auth.AuthService
 	Servers  *server.ServerService
@@ -32,21 +45,33 @@ type Store struct {
 	Users    *users.UserService
 	Matches  *matches.MatchesService
 	SteamApi *steamapi.SteamApiUserService
+	EgsApi   egsapi.EgsApi
 
 	MatchesFilter *matchesFilter.MatchesFilterService
 	Difficulty    *difficulty.DifficultyCalculatorService
+	Achievements  *achievements.AchievementsService
+	Records       *records.RecordsService
+	Health        *health.HealthService
 
 	AnalyticsMaps   *analyticsMaps.MapAnalyticsService
 	AnalyticsServer *analyticsServer.ServerAnalyticsService
 	AnalyticsPerks  *analyticsPerks.PerksAnalyticsService
 	AnalyticsUsers  *analyticsUsers.UserAnalyticsService
+	AnalyticsSquads *analyticsSquads.SquadsAnalyticsService
+	AnalyticsCD     *analyticsCD.CDAnalyticsService
 
-	LeaderBoards *leaderboards.LeaderBoardsService
+	LeaderBoards  *leaderboards.LeaderBoardsService
+	Organizations *organizations.OrganizationsService
+	Moderation    *moderation.ModerationService
+	ApiTokens     *apitokens.ApiTokensService
+	Gdpr          *gdpr.GdprService
+	Follows       *follows.FollowsService
 }
 
-func New(db *sql.DB, config *config.AppConfig) *Store {
+func New(db *sql.DB, config *config.AppConfig, cache cachestore.Store) *Store {
 	store := Store{
-		Db: db,
+		Db:    db,
+		Cache: cache,
 
 		Auth:     auth.NewAuthService(db),
 		Servers:  server.NewServerService(db),
@@ -56,22 +81,42 @@ func New(db *sql.DB, config *config.AppConfig) *Store {
 		Users:    users.NewUserService(db),
 		Matches:  matches.NewMatchesService(db),
 		SteamApi: steamapi.NewSteamApiUserService(config.SteamApiKey),
+		EgsApi:   egsapi.NewEgsApiUserService(config.EgsClientId, config.EgsClientSecret),
 
 		MatchesFilter: matchesFilter.NewMatchesFilterService(db),
 		Difficulty:    difficulty.NewDifficultyCalculator(db),
+		Achievements:  achievements.NewAchievementsService(db),
+		Records:       records.NewRecordsService(db),
+		Health:        health.NewHealthService(db),
 
 		AnalyticsMaps:   analyticsMaps.NewMapAnalyticsService(db),
 		AnalyticsServer: analyticsServer.NewServerAnalyticsService(db),
 		AnalyticsPerks:  analyticsPerks.NewPerksAnalyticsService(db),
 		AnalyticsUsers:  analyticsUsers.NewUserAnalyticsService(db),
+		AnalyticsSquads: analyticsSquads.NewSquadsAnalyticsService(db),
+		AnalyticsCD:     analyticsCD.NewCDAnalyticsService(db),
 
-		LeaderBoards: leaderboards.NewLeaderBoardsService(db),
+		LeaderBoards:  leaderboards.NewLeaderBoardsService(db),
+		Organizations: organizations.NewOrganizationsService(db),
+		Moderation:    moderation.NewModerationService(db),
+		ApiTokens:     apitokens.NewApiTokensService(db),
+		Gdpr:          gdpr.NewGdprService(db),
+		Follows:       follows.NewFollowsService(db),
 	}
 
-	store.Auth.Inject(store.Users, store.SteamApi)
-	store.Servers.Inject(store.Users, store.Difficulty)
-	store.Stats.Inject(store.Users, store.Difficulty)
-	store.Sessions.Inject(store.Maps, store.Servers, store.Users, store.Difficulty)
+	store.Auth.Inject(store.Users, store.SteamApi, store.EgsApi)
+	store.Servers.Inject(store.Users, store.Difficulty, store.Cache)
+	store.Stats.Inject(
+		store.Users, store.Difficulty,
+		store.Achievements, store.Records,
+		store.Cache,
+	)
+	store.Sessions.Inject(
+		store.Maps, store.Servers,
+		store.Users, store.Difficulty,
+		store.Achievements, store.Records,
+		store.Health, store.Cache,
+	)
 	store.Matches.Inject(
 		store.Users, store.Sessions,
 		store.Difficulty, store.Maps,
@@ -82,9 +127,20 @@ func New(db *sql.DB, config *config.AppConfig) *Store {
 		store.Difficulty, store.Maps,
 		store.Servers, store.SteamApi,
 	)
-	store.Users.Inject(store.SteamApi, store.Difficulty)
+	store.Users.Inject(store.SteamApi, store.EgsApi, store.Difficulty, store.Cache)
 	store.AnalyticsUsers.Inject(store.Users, store.Difficulty, store.MatchesFilter)
+	store.AnalyticsServer.Inject(store.Users)
+	store.AnalyticsSquads.Inject(store.Users)
 	store.LeaderBoards.Inject(store.Users)
+	store.Achievements.Inject(store.Users)
+	store.Records.Inject(store.Users)
+	store.Moderation.Inject(store.Servers, store.Cache)
+	store.Gdpr.Inject(store.Cache)
+	store.Follows.Inject(store.Users, store.MatchesFilter, store.SteamApi)
+	store.Organizations.Inject(
+		store.Users, store.AnalyticsServer,
+		store.AnalyticsMaps, store.LeaderBoards,
+	)
 
 	return &store
 }
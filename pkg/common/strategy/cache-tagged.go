@@ -0,0 +1,53 @@
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+
+	cache "github.com/chenyahui/gin-cache"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+)
+
+// Caches by request uri, stored keys are registered under the tags of the request
+func CacheByRequestURITagged(store cachestore.Store, getTags func(*gin.Context) []string) cache.Option {
+	return cache.WithCacheStrategyByRequest(func(ctx *gin.Context) (bool, cache.Strategy) {
+		return true, cache.Strategy{
+			CacheKey:   ctx.Request.RequestURI,
+			CacheStore: cachestore.Tagged(store, getTags(ctx)...),
+		}
+	})
+}
+
+// Caches by request body, stored keys are registered under the tags of the request
+func CacheByRequestBodyTagged[T interface{}](
+	store cachestore.Store,
+	getKey func(T) string,
+	getTags func(T) []string,
+) cache.Option {
+	return cache.WithCacheStrategyByRequest(func(ctx *gin.Context) (bool, cache.Strategy) {
+		var req T
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			return false, cache.Strategy{}
+		}
+
+		key := fmt.Sprintf("%v/%v", ctx.Request.RequestURI, getKey(req))
+
+		return true, cache.Strategy{
+			CacheKey:   key,
+			CacheStore: cachestore.Tagged(store, getTags(req)...),
+		}
+	})
+}
+
+// Tags request with the tag of the id from the path param, if the param is valid
+func TagByParam(param string, getTag func(int) string, tags ...string) func(*gin.Context) []string {
+	return func(ctx *gin.Context) []string {
+		if id, err := strconv.Atoi(ctx.Params.ByName(param)); err == nil {
+			return append([]string{getTag(id)}, tags...)
+		}
+
+		return tags
+	}
+}
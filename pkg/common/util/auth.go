@@ -1,6 +1,8 @@
 package util
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"time"
@@ -17,8 +19,15 @@ func SignToken(payload any, key string, expiresIn string, version int) (string,
 		return "", err
 	}
 
+	// Unique id keeps tokens issued within the same second distinct
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
 		"payload": payload,
+		"jti":     hex.EncodeToString(jti),
 		"iat":     time.Now().Unix(),
 		"exp":     time.Now().Add(duration).Unix(),
 	}
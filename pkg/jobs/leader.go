@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	leaderTTL           = 1 * time.Minute
+	leaderRenewInterval = 15 * time.Second
+)
+
+// Lease based leader election, periodic tasks are run by one worker only.
+// Leadership moves to another worker when the leader stops renewing the lease.
+type Leader struct {
+	db       *sql.DB
+	name     string
+	workerId string
+
+	isLeader atomic.Bool
+}
+
+func NewLeader(db *sql.DB, name, workerId string) *Leader {
+	return &Leader{
+		db:       db,
+		name:     name,
+		workerId: workerId,
+	}
+}
+
+func (l *Leader) IsLeader() bool {
+	return l.isLeader.Load()
+}
+
+func (l *Leader) Run() {
+	l.renew()
+
+	for range time.Tick(leaderRenewInterval) {
+		l.renew()
+	}
+}
+
+func (l *Leader) renew() {
+	isLeader, err := l.acquire()
+	if err != nil {
+		fmt.Printf("[leader] %v\n", err)
+		isLeader = false
+	}
+
+	if isLeader != l.isLeader.Load() {
+		fmt.Printf("[leader] %v is leader of %v: %v\n", l.workerId, l.name, isLeader)
+	}
+
+	l.isLeader.Store(isLeader)
+}
+
+// Takes the lease if it's expired, or extends it if it's already held
+func (l *Leader) acquire() (bool, error) {
+	// Assignments are evaluated in order, so expires_at sees the new holder
+	_, err := l.db.Exec(`
+		INSERT INTO worker_leader (name, holder, expires_at)
+		VALUES (?, ?, TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP))
+		ON DUPLICATE KEY UPDATE
+			holder = IF(holder = VALUES(holder) OR expires_at < CURRENT_TIMESTAMP, VALUES(holder), holder),
+			expires_at = IF(holder = VALUES(holder), VALUES(expires_at), expires_at)`,
+		l.name, l.workerId, int(leaderTTL.Seconds()),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	var holder string
+	err = l.db.QueryRow(`SELECT holder FROM worker_leader WHERE name = ?`, l.name).Scan(&holder)
+	if err != nil {
+		return false, err
+	}
+
+	return holder == l.workerId, nil
+}
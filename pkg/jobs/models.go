@@ -0,0 +1,28 @@
+package jobs
+
+import "time"
+
+type JobType string
+
+const (
+	DifficultyJob   JobType = "difficulty"
+	AchievementsJob JobType = "achievements"
+	RecordsJob      JobType = "records"
+	DemoJob         JobType = "demo"
+)
+
+const (
+	// Leased jobs are returned to the queue if the worker doesn't finish them in time
+	leaseTime   = 10 * time.Minute
+	maxAttempts = 5
+	retryDelay  = 30 * time.Second
+)
+
+type Job struct {
+	Id        int64
+	Type      JobType
+	SessionId int
+	Attempts  int
+
+	generation int
+}
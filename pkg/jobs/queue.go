@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+// Unique id of the process, used as lease holder
+func NewWorkerId() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+
+	buf := make([]byte, 4)
+	rand.Read(buf)
+
+	id := fmt.Sprintf("%v-%v-%v", hostname, os.Getpid(), hex.EncodeToString(buf))
+	if len(id) > 64 {
+		id = id[len(id)-64:]
+	}
+
+	return id
+}
+
+// Adds the session to the queue. Queued job is deduplicated, if it's being processed right now
+// it stays in the queue and runs again after the current run.
+func Enqueue(db *sql.DB, jobType JobType, sessionId int) error {
+	_, err := db.Exec(`
+		INSERT INTO job_queue (type, session_id) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE generation = generation + 1`,
+		jobType, sessionId,
+	)
+
+	return err
+}
+
+func IsQueued(db *sql.DB, jobType JobType, sessionId int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM job_queue WHERE type = ? AND session_id = ?)`,
+		jobType, sessionId,
+	).Scan(&exists)
+
+	return exists, err
+}
+
+// DB backed job queue shared by all workers
+type Queue struct {
+	db       *sql.DB
+	workerId string
+}
+
+func NewQueue(db *sql.DB, workerId string) *Queue {
+	return &Queue{
+		db:       db,
+		workerId: workerId,
+	}
+}
+
+// Leases up to limit due jobs. Rows locked by other workers are skipped, so every job goes to one worker only.
+// Jobs of sessions which still have jobs of waitFor types are left for later.
+func (q *Queue) Lease(jobType JobType, limit int, waitFor ...JobType) ([]*Job, error) {
+	items := []*Job{}
+
+	conds := []string{
+		"job.type = ?",
+		"job.run_after <= CURRENT_TIMESTAMP",
+		"(job.locked_until IS NULL OR job.locked_until < CURRENT_TIMESTAMP)",
+	}
+	args := []any{jobType}
+
+	if len(waitFor) > 0 {
+		placeholders := []string{}
+		for _, item := range waitFor {
+			placeholders = append(placeholders, "?")
+			args = append(args, item)
+		}
+
+		conds = append(conds, fmt.Sprintf(`NOT EXISTS (
+			SELECT 1 FROM job_queue dep
+			WHERE dep.session_id = job.session_id AND dep.type IN (%v)
+		)`, strings.Join(placeholders, ",")))
+	}
+
+	args = append(args, limit)
+
+	err := util.Transact(q.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query(fmt.Sprintf(`
+			SELECT job.id, job.session_id, job.generation, job.attempts
+			FROM job_queue job
+			WHERE %v
+			ORDER BY job.id
+			LIMIT ?
+			FOR UPDATE OF job SKIP LOCKED`,
+			strings.Join(conds, " AND "),
+		), args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		ids := []int{}
+		for rows.Next() {
+			item := Job{Type: jobType}
+
+			err := rows.Scan(&item.Id, &item.SessionId, &item.generation, &item.Attempts)
+			if err != nil {
+				return err
+			}
+
+			item.Attempts += 1
+
+			ids = append(ids, int(item.Id))
+			items = append(items, &item)
+		}
+		rows.Close()
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(fmt.Sprintf(`
+			UPDATE job_queue
+			SET locked_by = ?,
+				locked_until = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP),
+				attempts = attempts + 1
+			WHERE id IN (%v)`, util.IntArrayToString(ids, ","),
+		), q.workerId, int(leaseTime.Seconds()))
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Removes the job, unless it was queued again while being processed
+func (q *Queue) Complete(job *Job) error {
+	return q.remove(job)
+}
+
+// Returns the job to the queue with a growing delay, drops it after too many attempts
+func (q *Queue) Fail(job *Job) error {
+	if job.Attempts >= maxAttempts {
+		return q.remove(job)
+	}
+
+	delay := retryDelay * (1 << (job.Attempts - 1))
+
+	_, err := q.db.Exec(`
+		UPDATE job_queue
+		SET locked_by = NULL, locked_until = NULL,
+			run_after = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP)
+		WHERE id = ?`, int(delay.Seconds()), job.Id,
+	)
+
+	return err
+}
+
+// Deletes the job if its generation is unchanged,
+// otherwise the session was queued again and the job is released for a fresh run
+func (q *Queue) remove(job *Job) error {
+	res, err := q.db.Exec(`
+		DELETE FROM job_queue WHERE id = ? AND generation = ?`, job.Id, job.generation,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	_, err = q.db.Exec(`
+		UPDATE job_queue
+		SET locked_by = NULL, locked_until = NULL,
+			run_after = CURRENT_TIMESTAMP, attempts = 0
+		WHERE id = ?`, job.Id,
+	)
+
+	return err
+}
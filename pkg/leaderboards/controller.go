@@ -24,7 +24,7 @@ func (c *controller) getLeaderBoard(ctx *gin.Context) {
 		return
 	}
 
-	res, err := c.service.getLeaderBoard(req)
+	res, err := c.service.GetLeaderBoard(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
@@ -6,8 +6,10 @@ import (
 	"time"
 
 	cache "github.com/chenyahui/gin-cache"
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 )
@@ -15,25 +17,33 @@ import (
 func RegisterRoutes(
 	r *gin.RouterGroup,
 	service *LeaderBoardsService,
-	memoryStore *persist.MemoryStore,
+	cacheStore cachestore.Store,
 ) {
 	controller := controller{
 		service: service,
 	}
 
-	routes := r.Group("/leaderboards/")
+	routes := r.Group("/leaderboards/",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
 
 	routes.POST("/",
-		cache.Cache(memoryStore, 5*time.Minute,
-			strategy.CacheByRequestBody(func(req LeaderBoardsRequest) string {
-				slices.Sort(req.ServerIds)
+		cache.Cache(cacheStore, 5*time.Minute,
+			strategy.CacheByRequestBodyTagged(cacheStore,
+				func(req LeaderBoardsRequest) string {
+					slices.Sort(req.ServerIds)
 
-				return fmt.Sprintf("%v/%v/%v/%v/%v/%v",
-					util.IntArrayToString(req.ServerIds, ","),
-					req.OrderBy, req.Perk, req.Page,
-					req.From.Format("2006-01-02"), req.To.Format("2006-01-02"),
-				)
-			}),
+					return fmt.Sprintf("%v/%v/%v/%v/%v/%v",
+						util.IntArrayToString(req.ServerIds, ","),
+						req.OrderBy, req.Perk, req.Page,
+						req.From.Format("2006-01-02"), req.To.Format("2006-01-02"),
+					)
+				},
+				func(req LeaderBoardsRequest) []string {
+					return append(cachestore.ServerTags(req.ServerIds...), cachestore.TagUsers)
+				},
+			),
 		),
 		controller.getLeaderBoard)
 }
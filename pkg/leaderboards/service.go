@@ -7,6 +7,7 @@ import (
 
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
 	"github.com/theggv/kf2-stats-backend/pkg/users"
 )
 
@@ -30,12 +31,21 @@ func (s *LeaderBoardsService) Inject(
 	s.userService = userService
 }
 
+// Weekly stats with linked accounts attributed to the primary account of the player
+func playerStatsTable(tableName string) string {
+	return fmt.Sprintf(`(
+		SELECT coalesce(link.primary_user_id, stats.user_id) AS player_id, stats.*
+		FROM %v stats
+		LEFT JOIN users_link link ON link.user_id = stats.user_id
+	) %v`, tableName, tableName)
+}
+
 type userIdResponse struct {
 	Ids   []int
 	Total int
 }
 
-func (s *LeaderBoardsService) getLeaderBoard(
+func (s *LeaderBoardsService) GetLeaderBoard(
 	req LeaderBoardsRequest,
 ) (*LeaderBoardsResponse, error) {
 	var (
@@ -119,7 +129,7 @@ func (s *LeaderBoardsService) getLeaderBoard(
 			"floor(coalesce(sum(playtime_seconds), 0) / 3600) as total_playtime",
 		}
 
-		conds = append(conds, fmt.Sprintf("user_id IN (%v)", util.IntArrayToString(userData.Ids, ",")))
+		conds = append(conds, fmt.Sprintf("player_id IN (%v)", util.IntArrayToString(userData.Ids, ",")))
 
 		conds = append(conds, "period BETWEEN yearweek(?) AND yearweek(?)")
 		args = append(args, req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
@@ -141,11 +151,11 @@ func (s *LeaderBoardsService) getLeaderBoard(
 		}
 
 		sq = fmt.Sprintf(`
-			SELECT user_id, %v
+			SELECT player_id AS user_id, %v
 			FROM %v
 			WHERE %v
-			GROUP BY user_id
-		`, strings.Join(fields, ", "), tableName, strings.Join(conds, " AND "))
+			GROUP BY player_id
+		`, strings.Join(fields, ", "), playerStatsTable(tableName), strings.Join(conds, " AND "))
 	}
 
 	stmt := fmt.Sprintf(`
@@ -170,7 +180,7 @@ func (s *LeaderBoardsService) getLeaderBoard(
 			TotalResults:   userData.Total,
 		},
 	}
-	steamIdSet := make(map[string]bool)
+	authIdSet := make(map[string]models.AuthType)
 
 	for rows.Next() {
 		item := LeaderBoardsResponseItem{}
@@ -196,9 +206,7 @@ func (s *LeaderBoardsService) getLeaderBoard(
 			return nil, err
 		}
 
-		if item.Type == models.Steam {
-			steamIdSet[item.AuthId] = true
-		}
+		authIdSet[item.AuthId] = item.Type
 
 		res.Items = append(res.Items, &item)
 	}
@@ -220,7 +228,7 @@ func (s *LeaderBoardsService) getLeaderBoard(
 			conds = append(conds, fmt.Sprintf("server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
 		}
 
-		conds = append(conds, fmt.Sprintf("user_id IN (%v)", util.IntArrayToString(userData.Ids, ",")))
+		conds = append(conds, fmt.Sprintf("player_id IN (%v)", util.IntArrayToString(userData.Ids, ",")))
 
 		stmt := fmt.Sprintf(`
 			SELECT
@@ -229,15 +237,15 @@ func (s *LeaderBoardsService) getLeaderBoard(
 				max(max_damage) as metric
 			FROM (
 				SELECT
-					user_id,
+					player_id AS user_id,
 					max_damage_session_id,
 					max(max_damage) as max_damage
 				FROM %v
 				WHERE %v
-				GROUP BY user_id, max_damage_session_id
+				GROUP BY player_id, max_damage_session_id
 				ORDER BY max_damage DESC
 			) t
-			GROUP BY user_id`, tableName, strings.Join(conds, " AND "),
+			GROUP BY user_id`, playerStatsTable(tableName), strings.Join(conds, " AND "),
 		)
 
 		rows, err := s.db.Query(stmt, args...)
@@ -264,22 +272,17 @@ func (s *LeaderBoardsService) getLeaderBoard(
 		}
 	}
 
-	// Join steam data
+	// Join profile data
 	{
-		steamIds := []string{}
-		for key := range steamIdSet {
-			steamIds = append(steamIds, key)
-		}
-
-		steamData, err := s.userService.GetSteamData(steamIds)
+		profileData, err := s.userService.GetProfileData(authIdSet)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, item := range res.Items {
-			if data, ok := steamData[item.AuthId]; ok {
-				item.Avatar = &data.Avatar
-				item.ProfileUrl = &data.ProfileUrl
+			if data, ok := profileData[item.AuthId]; ok {
+				item.Avatar = data.Avatar
+				item.ProfileUrl = data.ProfileUrl
 			}
 		}
 	}
@@ -307,23 +310,30 @@ func (s *LeaderBoardsService) getMostDamageTop(
 		args = append(args, req.Perk)
 	}
 
+	conds = append(conds,
+		moderation.NotBannedCond(tableName+".user_id"),
+		moderation.NotBannedCond(tableName+".player_id"),
+		users.NotHiddenCond(tableName+".user_id", users.HideFromLeaderboards),
+		users.NotHiddenCond(tableName+".player_id", users.HideFromLeaderboards),
+	)
+
 	stmt := fmt.Sprintf(`
 		SELECT
 			user_id,
 			max(max_damage) as metric
 		FROM (
 			SELECT
-				user_id,
+				player_id AS user_id,
 				max_damage_session_id,
 				max(max_damage) as max_damage
 			FROM %v
 			WHERE %v
-			GROUP BY user_id, max_damage_session_id
+			GROUP BY player_id, max_damage_session_id
 			ORDER BY max_damage DESC
 		) t
 		GROUP BY user_id
 		ORDER BY metric desc
-		LIMIT %v, 50`, tableName, strings.Join(conds, " AND "), req.Page*50,
+		LIMIT %v, 50`, playerStatsTable(tableName), strings.Join(conds, " AND "), req.Page*50,
 	)
 
 	rows, err := s.db.Query(stmt, args...)
@@ -400,6 +410,13 @@ func (s *LeaderBoardsService) getLeaderboardIds(
 		args = append(args, req.Perk)
 	}
 
+	conds = append(conds,
+		moderation.NotBannedCond(tableName+".user_id"),
+		moderation.NotBannedCond(tableName+".player_id"),
+		users.NotHiddenCond(tableName+".user_id", users.HideFromLeaderboards),
+		users.NotHiddenCond(tableName+".player_id", users.HideFromLeaderboards),
+	)
+
 	restrictByGamesCond := ""
 	if req.To.Sub(req.From).Hours()/24 >= 81 {
 		// 3 Month leaderboard requires at least 25 recent games
@@ -414,17 +431,17 @@ func (s *LeaderBoardsService) getLeaderboardIds(
 	stmt := fmt.Sprintf(`
 		SELECT users.id, t.metric
 		FROM (
-			SELECT user_id, %v
+			SELECT player_id AS user_id, %v
 			FROM %v
 			WHERE %v
-			GROUP BY user_id
+			GROUP BY player_id
 			%v
 			ORDER BY metric DESC
 			LIMIT %v, 50
 		) t
 		INNER JOIN users ON users.id = t.user_id
 		ORDER BY metric DESC, name ASC
-		`, metric, tableName, strings.Join(conds, " AND "), restrictByGamesCond, req.Page*50,
+		`, metric, playerStatsTable(tableName), strings.Join(conds, " AND "), restrictByGamesCond, req.Page*50,
 	)
 
 	rows, err := s.db.Query(stmt, args...)
@@ -483,6 +500,13 @@ func (s *LeaderBoardsService) getTotalRows(
 		args = append(args, req.Perk)
 	}
 
+	conds = append(conds,
+		moderation.NotBannedCond(tableName+".user_id"),
+		moderation.NotBannedCond(tableName+".player_id"),
+		users.NotHiddenCond(tableName+".user_id", users.HideFromLeaderboards),
+		users.NotHiddenCond(tableName+".player_id", users.HideFromLeaderboards),
+	)
+
 	if len(req.ServerIds) > 0 {
 		conds = append(conds, fmt.Sprintf("server_id IN (%v)", util.IntArrayToString(req.ServerIds, ",")))
 	}
@@ -504,14 +528,14 @@ func (s *LeaderBoardsService) getTotalRows(
 	stmt := fmt.Sprintf(`
 		SELECT count(*)
 		FROM (
-			SELECT user_id, sum(total_games) as metric
+			SELECT player_id, sum(total_games) as metric
 			FROM %v
 			WHERE %v
-			GROUP BY user_id
+			GROUP BY player_id
 			%v
 			ORDER BY metric DESC
 		) t
-		`, tableName, strings.Join(conds, " AND "), restrictByGamesCond,
+		`, playerStatsTable(tableName), strings.Join(conds, " AND "), restrictByGamesCond,
 	)
 
 	row := s.db.QueryRow(stmt, args...)
@@ -36,13 +36,19 @@ func (c *mapsController) getById(ctx *gin.Context) {
 // @Summary Get maps by pattern
 // @Tags 	Maps
 // @Produce json
-// @Param   pattern query string false "Get maps by pattern"
+// @Param   pattern query string false "Get maps by name, display name or alias pattern"
+// @Param   official query bool false "Official or workshop maps only"
+// @Param   tags query []string false "Map must have all of the tags"
 // @Success 200 {array} Map
 // @Router /maps [get]
 func (c *mapsController) getByPattern(ctx *gin.Context) {
-	pattern := ctx.Query("pattern")
+	var req GetByPatternRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
 
-	items, err := c.service.GetByPattern(pattern)
+	items, err := c.service.GetByPattern(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
@@ -74,3 +80,110 @@ func (c *mapsController) updatePreview(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
+
+// @Summary Update map catalog data
+// @Tags 	Maps
+// @Produce json
+// @Param   id path   	 int true "Map id"
+// @Param   key query string false "Api key, alternative to the admin role"
+// @Param   body body UpdateMapRequest true "Body"
+// @Success 200
+// @Router /maps/{id} [put]
+func (c *mapsController) update(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req UpdateMapRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.Update(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Add map alias, sessions with this map name will be linked to the map
+// @Tags 	Maps
+// @Produce json
+// @Param   id path   	 int true "Map id"
+// @Param   key query string false "Api key, alternative to the admin role"
+// @Param   body body AddAliasRequest true "Body"
+// @Success 200
+// @Router /maps/{id}/aliases [post]
+func (c *mapsController) addAlias(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req AddAliasRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.AddAlias(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Remove map alias
+// @Tags 	Maps
+// @Produce json
+// @Param   id path   	 int true "Map id"
+// @Param   key query string false "Api key, alternative to the admin role"
+// @Param   alias query string true "Alias"
+// @Success 200
+// @Router /maps/{id}/aliases [delete]
+func (c *mapsController) removeAlias(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.RemoveAlias(id, ctx.Query("alias"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Merge source map into target map
+// @Tags 	Maps
+// @Produce json
+// @Param   key query string false "Api key, alternative to the admin role"
+// @Param   body body MergeMapsRequest true "Body"
+// @Success 200
+// @Router /maps/merge [post]
+func (c *mapsController) merge(ctx *gin.Context) {
+	var req MergeMapsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := c.service.Merge(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
@@ -4,4 +4,11 @@ type Map struct {
 	Id      int    `json:"id"`
 	Name    string `json:"name"`
 	Preview string `json:"preview"`
+
+	DisplayName *string  `json:"display_name"`
+	IsOfficial  bool     `json:"is_official"`
+	WorkshopId  *int64   `json:"workshop_id"`
+	Author      *string  `json:"author"`
+	Tags        []string `json:"tags"`
+	Aliases     []string `json:"aliases"`
 }
@@ -3,6 +3,7 @@ package maps
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 )
 
 func RegisterRoutes(r *gin.RouterGroup, mapsService *MapsService) {
@@ -15,4 +16,9 @@ func RegisterRoutes(r *gin.RouterGroup, mapsService *MapsService) {
 	routes.GET("/", controller.getByPattern)
 	routes.GET("/:id", controller.getById)
 	routes.PUT("/preview", middleware.MutatorAuthMiddleWave, controller.updatePreview)
+
+	routes.PUT("/:id", middleware.RoleMiddleWave(models.Moderator), controller.update)
+	routes.POST("/:id/aliases", middleware.RoleMiddleWave(models.Moderator), controller.addAlias)
+	routes.DELETE("/:id/aliases", middleware.RoleMiddleWave(models.Moderator), controller.removeAlias)
+	routes.POST("/merge", middleware.RoleMiddleWave(models.SuperAdmin), controller.merge)
 }
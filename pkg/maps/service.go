@@ -2,6 +2,10 @@ package maps
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 )
 
 type MapsService struct {
@@ -16,8 +20,20 @@ func NewMapsService(db *sql.DB) *MapsService {
 	return &service
 }
 
+// Returns id of the map, aliases are resolved to the map they belong to
 func (s *MapsService) Create(req AddMapRequest) (int, error) {
-	_, err := s.db.Exec(`
+	var id int
+
+	err := s.db.QueryRow(`SELECT map_id FROM map_aliases WHERE alias = ?`, req.Name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	_, err = s.db.Exec(`
 		INSERT INTO maps (name, preview) VALUES (?, ?)
 			ON DUPLICATE KEY UPDATE preview = ?`,
 		req.Name, req.Preview, req.Preview)
@@ -34,22 +50,83 @@ func (s *MapsService) Create(req AddMapRequest) (int, error) {
 	return data.Id, err
 }
 
-func (s *MapsService) GetByPattern(pattern string) ([]Map, error) {
-	rows, err := s.db.Query(`
-		SELECT id, name, preview FROM maps 
-		WHERE (name LIKE ?)`,
-		"%"+pattern+"%")
+const mapFields = `
+	maps.id, maps.name, coalesce(maps.preview, ''),
+	maps.display_name, maps.is_official, maps.workshop_id, maps.author,
+	(SELECT GROUP_CONCAT(tag ORDER BY tag) FROM map_tags WHERE map_id = maps.id),
+	(SELECT GROUP_CONCAT(alias ORDER BY alias) FROM map_aliases WHERE map_id = maps.id)`
+
+func scanMap(row interface{ Scan(...any) error }) (*Map, error) {
+	item := Map{
+		Tags:    []string{},
+		Aliases: []string{},
+	}
+
+	var tags, aliases *string
+
+	err := row.Scan(
+		&item.Id, &item.Name, &item.Preview,
+		&item.DisplayName, &item.IsOfficial, &item.WorkshopId, &item.Author,
+		&tags, &aliases,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tags != nil {
+		item.Tags = strings.Split(*tags, ",")
+	}
+
+	if aliases != nil {
+		item.Aliases = strings.Split(*aliases, ",")
+	}
+
+	return &item, nil
+}
+
+func (s *MapsService) GetByPattern(req GetByPatternRequest) ([]*Map, error) {
+	sqlPattern := "%" + req.Pattern + "%"
+
+	conds := []string{`(
+		maps.name LIKE ? OR maps.display_name LIKE ? OR
+		EXISTS (SELECT 1 FROM map_aliases WHERE map_id = maps.id AND alias LIKE ?)
+	)`}
+	args := []any{sqlPattern, sqlPattern, sqlPattern}
+
+	if req.IsOfficial != nil {
+		conds = append(conds, "maps.is_official = ?")
+		args = append(args, *req.IsOfficial)
+	}
+
+	if len(req.Tags) > 0 {
+		placeholders := []string{}
+		for _, tag := range req.Tags {
+			placeholders = append(placeholders, "?")
+			args = append(args, tag)
+		}
+		args = append(args, len(req.Tags))
+
+		conds = append(conds, fmt.Sprintf(`maps.id IN (
+			SELECT map_id FROM map_tags
+			WHERE tag IN (%v)
+			GROUP BY map_id
+			HAVING count(DISTINCT tag) = ?
+		)`, strings.Join(placeholders, ", ")))
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT %v FROM maps 
+		WHERE %v`, mapFields, strings.Join(conds, " AND "),
+	), args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
-	items := []Map{}
+	items := []*Map{}
 
 	for rows.Next() {
-		item := Map{}
-
-		err := rows.Scan(&item.Id, &item.Name, &item.Preview)
+		item, err := scanMap(rows)
 		if err != nil {
 			continue
 		}
@@ -61,34 +138,159 @@ func (s *MapsService) GetByPattern(pattern string) ([]Map, error) {
 }
 
 func (s *MapsService) GetById(id int) (*Map, error) {
-	row := s.db.QueryRow(`SELECT id, name, preview FROM maps WHERE id = ?`, id)
+	row := s.db.QueryRow(fmt.Sprintf(`SELECT %v FROM maps WHERE id = ?`, mapFields), id)
 
-	item := Map{}
+	return scanMap(row)
+}
 
-	err := row.Scan(&item.Id, &item.Name, &item.Preview)
-	if err != nil {
-		return nil, err
-	}
+func (s *MapsService) getByName(name string) (*Map, error) {
+	row := s.db.QueryRow(fmt.Sprintf(`SELECT %v FROM maps WHERE name = ?`, mapFields), name)
 
-	return &item, nil
+	return scanMap(row)
 }
 
-func (s *MapsService) getByName(name string) (*Map, error) {
-	row := s.db.QueryRow(`SELECT id, name, preview FROM maps WHERE name = ?`, name)
+func (s *MapsService) UpdatePreview(data UpdatePreviewRequest) error {
+	_, err := s.db.Exec(`UPDATE maps SET preview = ? WHERE id = ?`,
+		data.Preview, data.Id)
 
-	item := Map{}
+	return err
+}
 
-	err := row.Scan(&item.Id, &item.Name, &item.Preview)
-	if err != nil {
-		return nil, err
+func (s *MapsService) Update(id int, req UpdateMapRequest) error {
+	tags := []string{}
+	lookup := map[string]bool{}
+
+	for _, tag := range req.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || lookup[strings.ToLower(tag)] {
+			continue
+		}
+
+		if len(tag) > 32 || strings.Contains(tag, ",") {
+			return fmt.Errorf("invalid tag %v", tag)
+		}
+
+		lookup[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
 	}
 
-	return &item, nil
+	if _, err := s.GetById(id); err != nil {
+		return err
+	}
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE maps
+			SET display_name = ?, is_official = ?, workshop_id = ?, author = ?
+			WHERE id = ?`,
+			req.DisplayName, req.IsOfficial, req.WorkshopId, req.Author, id,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM map_tags WHERE map_id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			_, err := tx.Exec(`INSERT INTO map_tags (map_id, tag) VALUES (?, ?)`, id, tag)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
-func (s *MapsService) UpdatePreview(data UpdatePreviewRequest) error {
-	_, err := s.db.Exec(`UPDATE maps SET preview = ? WHERE ? = $2`,
-		data.Preview, data.Id)
+func (s *MapsService) AddAlias(id int, req AddAliasRequest) error {
+	alias := strings.TrimSpace(req.Alias)
+	if alias == "" || len(alias) > 64 || strings.Contains(alias, ",") {
+		return fmt.Errorf("invalid alias %v", req.Alias)
+	}
+
+	// Sessions are already linked to the map with such name, it should be merged instead
+	if data, err := s.getByName(alias); err == nil {
+		return fmt.Errorf("map %v already exists with id %v, merge it instead", alias, data.Id)
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO map_aliases (alias, map_id) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE map_id = VALUES(map_id)`,
+		alias, id,
+	)
 
 	return err
 }
+
+func (s *MapsService) RemoveAlias(id int, alias string) error {
+	_, err := s.db.Exec(`DELETE FROM map_aliases WHERE alias = ? AND map_id = ?`,
+		alias, id)
+
+	return err
+}
+
+// Moves sessions, aliases and tags of the source map to the target one.
+// Source map name becomes an alias of the target map.
+func (s *MapsService) Merge(req MergeMapsRequest) error {
+	if req.SourceId == req.TargetId {
+		return fmt.Errorf("source and target maps are the same")
+	}
+
+	source, err := s.GetById(req.SourceId)
+	if err != nil {
+		return fmt.Errorf("source map: %v", err)
+	}
+
+	target, err := s.GetById(req.TargetId)
+	if err != nil {
+		return fmt.Errorf("target map: %v", err)
+	}
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		stmts := []string{
+			`UPDATE session SET map_id = ? WHERE map_id = ?`,
+			`UPDATE map_aliases SET map_id = ? WHERE map_id = ?`,
+			`INSERT IGNORE INTO map_tags (map_id, tag)
+				SELECT ?, tag FROM map_tags WHERE map_id = ?`,
+		}
+
+		for _, stmt := range stmts {
+			_, err := tx.Exec(stmt, target.Id, source.Id)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`DELETE FROM maps WHERE id = ?`, source.Id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO map_aliases (alias, map_id) VALUES (?, ?)
+				ON DUPLICATE KEY UPDATE map_id = VALUES(map_id)`,
+			source.Name, target.Id,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Keep curated data of the source if target doesn't have it
+		_, err = tx.Exec(`
+			UPDATE maps
+			SET preview = coalesce(nullif(preview, ''), ?),
+				display_name = coalesce(display_name, ?),
+				workshop_id = coalesce(workshop_id, ?),
+				author = coalesce(author, ?),
+				is_official = is_official OR ?
+			WHERE id = ?`,
+			source.Preview, source.DisplayName, source.WorkshopId, source.Author,
+			source.IsOfficial, target.Id,
+		)
+
+		return err
+	})
+}
@@ -9,11 +9,34 @@ type AddMapResponse struct {
 	Id int `json:"id"`
 }
 
+type GetByPatternRequest struct {
+	Pattern    string   `form:"pattern"`
+	IsOfficial *bool    `form:"official"`
+	Tags       []string `form:"tags"`
+}
+
 type GetByPatternResponse struct {
-	Items []Map `json:"items"`
+	Items []*Map `json:"items"`
 }
 
 type UpdatePreviewRequest struct {
 	Id      int    `json:"id"`
 	Preview string `json:"preview"`
 }
+
+type UpdateMapRequest struct {
+	DisplayName *string  `json:"display_name"`
+	IsOfficial  bool     `json:"is_official"`
+	WorkshopId  *int64   `json:"workshop_id"`
+	Author      *string  `json:"author"`
+	Tags        []string `json:"tags"`
+}
+
+type AddAliasRequest struct {
+	Alias string `json:"alias" binding:"required"`
+}
+
+type MergeMapsRequest struct {
+	SourceId int `json:"source_id" binding:"required"`
+	TargetId int `json:"target_id" binding:"required"`
+}
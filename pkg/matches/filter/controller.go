@@ -4,6 +4,9 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
 )
 
 type controller struct {
@@ -24,6 +27,39 @@ func (c *controller) filter(ctx *gin.Context) {
 		return
 	}
 
+	// Sessions of private profiles can't be found by user id
+	if len(req.UserIds) > 0 {
+		user, _ := util.GetUserFromCtx(ctx)
+
+		userIds := []int{}
+		for _, userId := range req.UserIds {
+			err := c.service.userService.CheckPrivacy(user, userId, users.HideProfile)
+			if err == users.ErrPrivateProfile {
+				continue
+			} else if err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+
+			userIds = append(userIds, userId)
+		}
+
+		if len(userIds) == 0 {
+			page, limit := req.Pager.Parse()
+
+			ctx.JSON(http.StatusCreated, FilterMatchesResponse{
+				Items: []*models.Match{},
+				Metadata: &models.PaginationResponse{
+					Page:           page,
+					ResultsPerPage: limit,
+				},
+			})
+			return
+		}
+
+		req.UserIds = userIds
+	}
+
 	res, err := c.service.Filter(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
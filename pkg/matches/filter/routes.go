@@ -1,20 +1,25 @@
 package filter
 
 import (
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 )
 
 func RegisterRoutes(
 	r *gin.RouterGroup,
 	service *MatchesFilterService,
-	memoryStore *persist.MemoryStore,
+	cacheStore cachestore.Store,
 ) {
 	controller := controller{
 		service: service,
 	}
 
-	routes := r.Group("/matches")
+	routes := r.Group("/matches",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitHeavy),
+	)
 
-	routes.POST("/filter", controller.filter)
+	routes.POST("/filter", middleware.OptionalAuthMiddleWave, controller.filter)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/theggv/kf2-stats-backend/pkg/common/steamapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 	"github.com/theggv/kf2-stats-backend/pkg/maps"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
 	"github.com/theggv/kf2-stats-backend/pkg/server"
 	"github.com/theggv/kf2-stats-backend/pkg/session"
 	"github.com/theggv/kf2-stats-backend/pkg/session/difficulty"
@@ -59,6 +60,8 @@ func (s *MatchesFilterService) Filter(req FilterMatchesRequest) (*FilterMatchesR
 	conds := []string{}
 	args := []any{}
 
+	conds = append(conds, moderation.NotExcludedCond)
+
 	// Prepare fields
 	fields = append(fields,
 		"session.id", "session.server_id", "session.map_id",
@@ -121,6 +124,15 @@ func (s *MatchesFilterService) Filter(req FilterMatchesRequest) (*FilterMatchesR
 		conds = append(conds, fmt.Sprintf("session.mode = %v", *req.Mode))
 	}
 
+	if len(req.UserIds) > 0 {
+		conds = append(conds, fmt.Sprintf(`session.id IN (
+			SELECT ws.session_id
+			FROM wave_stats ws
+			INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+			WHERE wsp.player_id IN (%v) AND %v
+		)`, util.IntArrayToString(req.UserIds, ","), moderation.NotBannedCond("wsp.player_id")))
+	}
+
 	if req.Exclude != nil {
 		exclude := req.Exclude
 
@@ -427,7 +439,7 @@ func (s *MatchesFilterService) getMapData(matchId []int) ([]*filterMapData, erro
 	}
 
 	sql := fmt.Sprintf(`
-		SELECT session.id, maps.name, maps.preview FROM session
+		SELECT session.id, coalesce(maps.display_name, maps.name), maps.preview FROM session
 		INNER JOIN maps ON maps.id = session.map_id
 		WHERE %v`,
 		fmt.Sprintf("session.id in (%v)", util.IntArrayToString(matchId, ",")),
@@ -600,7 +612,7 @@ func (s *MatchesFilterService) getLiveData(matchId []int) ([]*filterPlayerData,
 			userId = append(userId, key)
 		}
 
-		profiles, err := s.userService.GetUserProfiles(userId)
+		profiles, err := s.userService.GetMatchProfiles(userId)
 		if err != nil {
 			return nil, err
 		}
@@ -4,36 +4,54 @@ import (
 	"time"
 
 	cache "github.com/chenyahui/gin-cache"
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
 )
 
 func RegisterRoutes(
 	r *gin.RouterGroup,
 	service *MatchesService,
-	memoryStore *persist.MemoryStore,
+	cacheStore cachestore.Store,
 ) {
 	controller := controller{
 		service: service,
 	}
 
-	routes := r.Group("/matches")
+	// Match data changes with new waves of the session, player lists depend on privacy settings
+	sessionCache := cache.Cache(cacheStore, 15*time.Second,
+		strategy.CacheByRequestURITagged(cacheStore,
+			strategy.TagByParam("id", cachestore.SessionTag, cachestore.TagUsers),
+		),
+	)
+	serverCache := cache.Cache(cacheStore, 15*time.Second,
+		strategy.CacheByRequestURITagged(cacheStore,
+			strategy.TagByParam("id", cachestore.ServerTag, cachestore.TagUsers),
+		),
+	)
+
+	routes := r.Group("/matches",
+		middleware.ApiTokenMiddleWave(models.ApiScopeAnalyticsRead),
+		middleware.RateLimitMiddleWave(middleware.RateLimitAnalytics),
+	)
 
 	routes.GET("/:id",
-		cache.CacheByRequestURI(memoryStore, 15*time.Second),
+		sessionCache,
 		controller.getById)
 	routes.GET("/:id/live",
 		controller.getMatchLiveData)
 	routes.GET("/:id/waves",
-		cache.CacheByRequestURI(memoryStore, 15*time.Second),
+		sessionCache,
 		controller.getMatchWaves)
 	routes.GET("/:id/user/:userId/stats",
-		cache.CacheByRequestURI(memoryStore, 15*time.Second),
+		sessionCache,
 		controller.getMatchPlayerStats)
 	routes.GET("/:id/summary",
-		cache.CacheByRequestURI(memoryStore, 15*time.Second),
+		sessionCache,
 		controller.getMatchAggregatedStats)
 	routes.GET("/server/:id",
-		cache.CacheByRequestURI(memoryStore, 15*time.Second),
+		serverCache,
 		controller.getLastServerMatch)
 }
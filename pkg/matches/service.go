@@ -2,6 +2,7 @@ package matches
 
 import (
 	"database/sql"
+	"slices"
 
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/steamapi"
@@ -176,7 +177,7 @@ func (s *MatchesService) GetMatchWaves(sessionId int) (*GetMatchWavesResponse, e
 		userId = append(userId, key)
 	}
 
-	users, err := s.userService.GetUserProfiles(userId)
+	users, err := s.userService.GetMatchProfiles(userId)
 	if err != nil {
 		return nil, err
 	}
@@ -493,7 +494,8 @@ func (s *MatchesService) GetMatchLiveData(sessionId int) (*GetMatchLiveDataRespo
 		return nil, err
 	}
 
-	steamIdSet := make(map[string]bool)
+	authIdSet := make(map[string]models.AuthType)
+	userIds := []int{}
 	for rows.Next() {
 		item := GetMatchLiveDataResponsePlayer{}
 
@@ -507,9 +509,8 @@ func (s *MatchesService) GetMatchLiveData(sessionId int) (*GetMatchLiveDataRespo
 			return nil, err
 		}
 
-		if item.AuthType == models.Steam {
-			steamIdSet[item.AuthId] = true
-		}
+		authIdSet[item.AuthId] = item.AuthType
+		userIds = append(userIds, item.Id)
 
 		if item.IsSpectator {
 			res.Spectators = append(res.Spectators, &item)
@@ -519,27 +520,37 @@ func (s *MatchesService) GetMatchLiveData(sessionId int) (*GetMatchLiveDataRespo
 	}
 
 	{
-		steamIds := []string{}
-		for key := range steamIdSet {
-			steamIds = append(steamIds, key)
-		}
-
-		steamData, err := s.userService.GetSteamData(steamIds)
+		profileData, err := s.userService.GetProfileData(authIdSet)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, item := range res.Players {
-			if data, ok := steamData[item.AuthId]; ok {
-				item.Avatar = &data.Avatar
-				item.ProfileUrl = &data.ProfileUrl
+			if data, ok := profileData[item.AuthId]; ok {
+				item.Avatar = data.Avatar
+				item.ProfileUrl = data.ProfileUrl
 			}
 		}
 
 		for _, item := range res.Spectators {
-			if data, ok := steamData[item.AuthId]; ok {
-				item.Avatar = &data.Avatar
-				item.ProfileUrl = &data.ProfileUrl
+			if data, ok := profileData[item.AuthId]; ok {
+				item.Avatar = data.Avatar
+				item.ProfileUrl = data.ProfileUrl
+			}
+		}
+	}
+
+	{
+		anonymized, err := s.userService.GetHiddenUsers(userIds, users.AnonymizeName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range slices.Concat(res.Players, res.Spectators) {
+			if anonymized[item.Id] {
+				item.Name = users.AnonymousName
+				item.Avatar = nil
+				item.ProfileUrl = nil
 			}
 		}
 	}
@@ -53,4 +53,11 @@ func ExecuteAll(db *sql.DB) {
 	migration_2025_05_23_0001_add_fields(db)
 	migration_2025_05_27_0001_migrate_leaderboard(db)
 	migration_2025_11_14_0001_clean_procs(db)
+	migration_2026_10_19_0001_server_identity(db)
+	migration_2026_10_19_0002_server_metadata(db)
+	migration_2026_10_19_0003_maps_catalog(db)
+	migration_2026_10_19_0004_moderation(db)
+	migration_2026_10_19_0005_token_families(db)
+	migration_2026_10_19_0006_users_erasure(db)
+	migration_2026_10_19_0007_job_queue(db)
 }
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func migration_2026_10_19_0001_server_identity(db *sql.DB) {
+	name := "migration_2026_10_19_0001_server_identity"
+
+	if isMigrationExists(db, name) {
+		return
+	}
+
+	fmt.Printf("performing %v...\n", name)
+
+	// Fresh databases are created with the new schema already, so every step is checked
+	_, err := db.Exec(`
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0001_server_identity;
+ 		CREATE PROCEDURE migration_2026_10_19_0001_server_identity()
+ 		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = 'server' AND column_name = 'guid'
+			) THEN
+				ALTER TABLE server
+				ADD COLUMN guid VARCHAR(64) AFTER address,
+				ADD UNIQUE INDEX idx_uniq_server_guid (guid);
+			END IF;
+
+			IF EXISTS (
+				SELECT 1 FROM information_schema.statistics
+				WHERE table_schema = DATABASE() AND table_name = 'server' AND index_name = 'idx_uniq_server_address'
+			) THEN
+				ALTER TABLE server
+				DROP INDEX idx_uniq_server_address,
+				ADD INDEX idx_server_address (address);
+			END IF;
+
+			INSERT IGNORE INTO server_address_history (server_id, address, first_seen, last_seen)
+			SELECT
+				server.id,
+				server.address,
+				coalesce(min(session.created_at), CURRENT_TIMESTAMP),
+				coalesce(max(session.updated_at), CURRENT_TIMESTAMP)
+			FROM server
+			LEFT JOIN session ON session.server_id = server.id
+			GROUP BY server.id;
+ 		END;
+ 
+ 		CALL migration_2026_10_19_0001_server_identity();
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0001_server_identity;
+ 		`,
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	writeMigration(db, name)
+}
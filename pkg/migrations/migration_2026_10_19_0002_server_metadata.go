@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func migration_2026_10_19_0002_server_metadata(db *sql.DB) {
+	name := "migration_2026_10_19_0002_server_metadata"
+
+	if isMigrationExists(db, name) {
+		return
+	}
+
+	fmt.Printf("performing %v...\n", name)
+
+	_, err := db.Exec(`
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0002_server_metadata;
+ 		CREATE PROCEDURE migration_2026_10_19_0002_server_metadata()
+ 		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = 'server' AND column_name = 'display_name'
+			) THEN
+				ALTER TABLE server
+				ADD COLUMN display_name VARCHAR(128) AFTER guid,
+				ADD COLUMN region VARCHAR(32) AFTER display_name,
+				ADD COLUMN description TEXT AFTER region,
+				ADD COLUMN discord_url VARCHAR(512) AFTER description,
+				ADD INDEX idx_server_region (region);
+			END IF;
+
+			INSERT IGNORE INTO server_name_history (server_id, name)
+			SELECT id, name FROM server;
+ 		END;
+ 
+ 		CALL migration_2026_10_19_0002_server_metadata();
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0002_server_metadata;
+ 		`,
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	writeMigration(db, name)
+}
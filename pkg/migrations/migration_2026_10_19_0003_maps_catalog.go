@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func migration_2026_10_19_0003_maps_catalog(db *sql.DB) {
+	name := "migration_2026_10_19_0003_maps_catalog"
+
+	if isMigrationExists(db, name) {
+		return
+	}
+
+	fmt.Printf("performing %v...\n", name)
+
+	_, err := db.Exec(`
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0003_maps_catalog;
+ 		CREATE PROCEDURE migration_2026_10_19_0003_maps_catalog()
+ 		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = 'maps' AND column_name = 'display_name'
+			) THEN
+				ALTER TABLE maps
+				ADD COLUMN display_name VARCHAR(128) AFTER preview,
+				ADD COLUMN is_official BOOLEAN NOT NULL DEFAULT 0 AFTER display_name,
+				ADD COLUMN workshop_id BIGINT UNSIGNED AFTER is_official,
+				ADD COLUMN author VARCHAR(128) AFTER workshop_id;
+			END IF;
+ 		END;
+ 
+ 		CALL migration_2026_10_19_0003_maps_catalog();
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0003_maps_catalog;
+ 		`,
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	writeMigration(db, name)
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func migration_2026_10_19_0004_moderation(db *sql.DB) {
+	name := "migration_2026_10_19_0004_moderation"
+
+	if isMigrationExists(db, name) {
+		return
+	}
+
+	fmt.Printf("performing %v...\n", name)
+
+	_, err := db.Exec(`
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0004_moderation;
+ 		CREATE PROCEDURE migration_2026_10_19_0004_moderation()
+ 		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = 'session' AND column_name = 'is_excluded'
+			) THEN
+				ALTER TABLE session
+				ADD COLUMN is_excluded BOOLEAN NOT NULL DEFAULT 0 AFTER is_completed;
+			END IF;
+ 		END;
+ 
+ 		CALL migration_2026_10_19_0004_moderation();
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0004_moderation;
+ 		`,
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	writeMigration(db, name)
+}
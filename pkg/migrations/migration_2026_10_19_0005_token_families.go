@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Hashes stored refresh tokens, every existing token becomes a family of its own
+func migration_2026_10_19_0005_token_families(db *sql.DB) {
+	name := "migration_2026_10_19_0005_token_families"
+
+	if isMigrationExists(db, name) {
+		return
+	}
+
+	fmt.Printf("performing %v...\n", name)
+
+	_, err := db.Exec(`
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0005_token_families;
+ 		CREATE PROCEDURE migration_2026_10_19_0005_token_families()
+ 		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = 'users_token' AND column_name = 'token_hash'
+			) THEN
+				ALTER TABLE users_token
+				ADD COLUMN family_id INTEGER NULL AFTER user_id,
+				ADD COLUMN token_hash CHAR(64) NULL AFTER family_id,
+				ADD COLUMN used_at TIMESTAMP NULL AFTER token_hash;
+
+				UPDATE users_token SET token_hash = SHA2(token, 256), family_id = id;
+
+				DELETE t1 FROM users_token t1
+				INNER JOIN users_token t2 ON t2.token_hash = t1.token_hash AND t2.id < t1.id;
+
+				INSERT INTO users_token_family (id, user_id, created_at, last_used_at)
+				SELECT id, user_id, created_at, created_at FROM users_token;
+
+				ALTER TABLE users_token
+				DROP COLUMN token,
+				MODIFY family_id INTEGER NOT NULL,
+				MODIFY token_hash CHAR(64) NOT NULL,
+				ADD FOREIGN KEY (family_id) REFERENCES users_token_family(id) ON UPDATE CASCADE ON DELETE CASCADE,
+				ADD UNIQUE INDEX idx_uniq_users_token_hash (token_hash);
+			END IF;
+ 		END;
+ 
+ 		CALL migration_2026_10_19_0005_token_families();
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0005_token_families;
+ 		`,
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	writeMigration(db, name)
+}
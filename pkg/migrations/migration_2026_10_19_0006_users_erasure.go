@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func migration_2026_10_19_0006_users_erasure(db *sql.DB) {
+	name := "migration_2026_10_19_0006_users_erasure"
+
+	if isMigrationExists(db, name) {
+		return
+	}
+
+	fmt.Printf("performing %v...\n", name)
+
+	_, err := db.Exec(`
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0006_users_erasure;
+ 		CREATE PROCEDURE migration_2026_10_19_0006_users_erasure()
+ 		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'erased_at'
+			) THEN
+				ALTER TABLE users
+				ADD COLUMN erased_at TIMESTAMP NULL AFTER created_at;
+			END IF;
+ 		END;
+ 
+ 		CALL migration_2026_10_19_0006_users_erasure();
+ 		DROP PROCEDURE IF EXISTS migration_2026_10_19_0006_users_erasure;
+ 		`,
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	writeMigration(db, name)
+}
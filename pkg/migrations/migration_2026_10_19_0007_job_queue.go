@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/theggv/kf2-stats-backend/pkg/jobs"
+)
+
+// Demos used to be picked up by processed flag, queue the ones which are not processed yet
+func migration_2026_10_19_0007_job_queue(db *sql.DB) {
+	name := "migration_2026_10_19_0007_job_queue"
+
+	if isMigrationExists(db, name) {
+		return
+	}
+
+	fmt.Printf("performing %v...\n", name)
+
+	_, err := db.Exec(`
+		INSERT IGNORE INTO job_queue (type, session_id)
+		SELECT ?, session_id FROM session_demo WHERE processed = 0`,
+		jobs.DemoJob,
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	writeMigration(db, name)
+}
@@ -0,0 +1,160 @@
+package moderation
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+type controller struct {
+	service *ModerationService
+}
+
+// Moderator is unknown if the request is authorized with the api key
+func getModeratorId(ctx *gin.Context) *int {
+	if user, ok := util.GetUserFromCtx(ctx); ok {
+		return &user.UserId
+	}
+
+	return nil
+}
+
+// @Summary Get user bans
+// @Tags 	Moderation
+// @Produce json
+// @Param   user_id query 	int false "User id"
+// @Param   active query 	bool false "Only active bans"
+// @Success 200 {object} 	GetBansResponse
+// @Router /moderation/bans [get]
+func (c *controller) getBans(ctx *gin.Context) {
+	var req GetBansRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := c.service.GetBans(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetBansResponse{
+		Items: items,
+	})
+}
+
+// @Summary Ban user from leaderboards
+// @Tags 	Moderation
+// @Produce json
+// @Param   body body 		BanUserRequest true "Body"
+// @Success 201
+// @Router /moderation/bans [post]
+func (c *controller) banUser(ctx *gin.Context) {
+	var req BanUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := c.service.BanUser(getModeratorId(ctx), req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// @Summary Revoke active bans of user
+// @Tags 	Moderation
+// @Produce json
+// @Param   userId path   	int true "User id"
+// @Param   body body 		UnbanUserRequest false "Body"
+// @Success 200
+// @Router /moderation/bans/{userId} [delete]
+func (c *controller) unbanUser(ctx *gin.Context) {
+	userId, err := strconv.Atoi(ctx.Params.ByName("userId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Reason is optional
+	var req UnbanUserRequest
+	ctx.ShouldBindJSON(&req)
+
+	err = c.service.UnbanUser(getModeratorId(ctx), userId, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Exclude session from leaderboards and analytics
+// @Tags 	Moderation
+// @Produce json
+// @Param   id path   	 	int true "Session id"
+// @Param   body body 		ExcludeSessionRequest false "Body"
+// @Success 200
+// @Router /moderation/sessions/{id}/exclude [post]
+func (c *controller) excludeSession(ctx *gin.Context) {
+	c.setSessionExcluded(ctx, true)
+}
+
+// @Summary Include previously excluded session back
+// @Tags 	Moderation
+// @Produce json
+// @Param   id path   	 	int true "Session id"
+// @Param   body body 		ExcludeSessionRequest false "Body"
+// @Success 200
+// @Router /moderation/sessions/{id}/exclude [delete]
+func (c *controller) includeSession(ctx *gin.Context) {
+	c.setSessionExcluded(ctx, false)
+}
+
+func (c *controller) setSessionExcluded(ctx *gin.Context, excluded bool) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Reason is optional
+	var req ExcludeSessionRequest
+	ctx.ShouldBindJSON(&req)
+
+	err = c.service.SetSessionExcluded(getModeratorId(ctx), id, excluded, req.Reason)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Get moderation audit log
+// @Tags 	Moderation
+// @Produce json
+// @Param   body body 		GetLogRequest true "Body"
+// @Success 200 {object} 	GetLogResponse
+// @Router /moderation/log [post]
+func (c *controller) getLog(ctx *gin.Context) {
+	var req GetLogRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.GetLog(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
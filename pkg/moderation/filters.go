@@ -0,0 +1,15 @@
+package moderation
+
+import "fmt"
+
+// Condition for session queries which skips sessions hidden by moderators
+const NotExcludedCond = "NOT session.is_excluded"
+
+// Condition which skips users with an active ban, userIdColumn is the column to check
+func NotBannedCond(userIdColumn string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM users_ban
+		WHERE users_ban.user_id = %v AND users_ban.revoked_at IS NULL AND
+			(users_ban.expires_at IS NULL OR users_ban.expires_at > CURRENT_TIMESTAMP)
+	)`, userIdColumn)
+}
@@ -0,0 +1,42 @@
+package moderation
+
+import "time"
+
+type Action = int
+
+const (
+	BanUser Action = iota + 1
+	UnbanUser
+	ExcludeSession
+	IncludeSession
+)
+
+type Ban struct {
+	Id       int    `json:"id"`
+	UserId   int    `json:"user_id"`
+	UserName string `json:"user_name"`
+
+	ModeratorId   *int    `json:"moderator_id"`
+	ModeratorName *string `json:"moderator_name"`
+
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	IsActive bool `json:"is_active"`
+}
+
+type LogEntry struct {
+	Id int `json:"id"`
+
+	ModeratorId   *int    `json:"moderator_id"`
+	ModeratorName *string `json:"moderator_name"`
+
+	Action    Action  `json:"action"`
+	UserId    *int    `json:"user_id"`
+	SessionId *int    `json:"session_id"`
+	Reason    *string `json:"reason"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
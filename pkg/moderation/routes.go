@@ -0,0 +1,24 @@
+package moderation
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, service *ModerationService) {
+	controller := controller{
+		service: service,
+	}
+
+	routes := r.Group("/moderation", middleware.RoleMiddleWave(models.Moderator))
+
+	routes.GET("/bans", controller.getBans)
+	routes.POST("/bans", controller.banUser)
+	routes.DELETE("/bans/:userId", controller.unbanUser)
+
+	routes.POST("/sessions/:id/exclude", controller.excludeSession)
+	routes.DELETE("/sessions/:id/exclude", controller.includeSession)
+
+	routes.POST("/log", controller.getLog)
+}
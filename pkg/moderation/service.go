@@ -0,0 +1,298 @@
+package moderation
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/server"
+)
+
+type ModerationService struct {
+	db *sql.DB
+
+	serverService *server.ServerService
+
+	cache cachestore.Invalidator
+}
+
+func NewModerationService(db *sql.DB) *ModerationService {
+	service := ModerationService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *ModerationService) Inject(serverService *server.ServerService, cache cachestore.Invalidator) {
+	s.serverService = serverService
+	s.cache = cache
+}
+
+func (s *ModerationService) writeLog(
+	tx *sql.Tx, moderatorId *int, action Action, userId, sessionId *int, reason string,
+) error {
+	var reasonValue *string
+	if reason != "" {
+		reasonValue = &reason
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO moderation_log (moderator_id, action, user_id, session_id, reason)
+		VALUES (?, ?, ?, ?, ?)`,
+		moderatorId, action, userId, sessionId, reasonValue,
+	)
+
+	return err
+}
+
+func (s *ModerationService) BanUser(moderatorId *int, req BanUserRequest) (int, error) {
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return 0, errors.New("expires_at should be in the future")
+	}
+
+	var id int64
+
+	defer cachestore.Invalidate(s.cache, cachestore.TagUsers)
+
+	err := util.Transact(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			INSERT INTO users_ban (user_id, moderator_id, reason, expires_at)
+			VALUES (?, ?, ?, ?)`,
+			req.UserId, moderatorId, req.Reason, req.ExpiresAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return s.writeLog(tx, moderatorId, BanUser, &req.UserId, nil, req.Reason)
+	})
+
+	return int(id), err
+}
+
+// Revokes all active bans of the user
+func (s *ModerationService) UnbanUser(moderatorId *int, userId int, req UnbanUserRequest) error {
+	defer cachestore.Invalidate(s.cache, cachestore.TagUsers)
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			UPDATE users_ban SET revoked_at = CURRENT_TIMESTAMP
+			WHERE user_id = ? AND revoked_at IS NULL AND
+				(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`,
+			userId,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return fmt.Errorf("user %v has no active bans", userId)
+		}
+
+		return s.writeLog(tx, moderatorId, UnbanUser, &userId, nil, req.Reason)
+	})
+}
+
+func (s *ModerationService) GetBans(req GetBansRequest) ([]*Ban, error) {
+	conds := make([]string, 0)
+	args := make([]any, 0)
+
+	conds = append(conds, "1 = 1")
+
+	if req.UserId != 0 {
+		conds = append(conds, "ban.user_id = ?")
+		args = append(args, req.UserId)
+	}
+
+	if req.ActiveOnly {
+		conds = append(conds,
+			"ban.revoked_at IS NULL",
+			"(ban.expires_at IS NULL OR ban.expires_at > CURRENT_TIMESTAMP)",
+		)
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			ban.id, ban.user_id, users.name,
+			ban.moderator_id, moderator.name,
+			ban.reason, ban.expires_at, ban.revoked_at, ban.created_at,
+			ban.revoked_at IS NULL AND
+				(ban.expires_at IS NULL OR ban.expires_at > CURRENT_TIMESTAMP) AS is_active
+		FROM users_ban ban
+		INNER JOIN users ON users.id = ban.user_id
+		LEFT JOIN users moderator ON moderator.id = ban.moderator_id
+		WHERE %v
+		ORDER BY ban.id DESC`,
+		strings.Join(conds, " AND "),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*Ban{}
+	for rows.Next() {
+		item := Ban{}
+
+		err := rows.Scan(
+			&item.Id, &item.UserId, &item.UserName,
+			&item.ModeratorId, &item.ModeratorName,
+			&item.Reason, &item.ExpiresAt, &item.RevokedAt, &item.CreatedAt,
+			&item.IsActive,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// Hides or shows the session and rebuilds weekly stats of its week
+func (s *ModerationService) SetSessionExcluded(
+	moderatorId *int, sessionId int, excluded bool, reason string,
+) error {
+	var serverId, period int
+	var isCompleted bool
+
+	err := s.db.QueryRow(`
+		SELECT server_id, coalesce(yearweek(started_at), 0), is_completed
+		FROM session WHERE id = ?`, sessionId,
+	).Scan(&serverId, &period, &isCompleted)
+	if err != nil {
+		return err
+	}
+
+	defer cachestore.Invalidate(s.cache,
+		cachestore.SessionTag(sessionId), cachestore.ServerTag(serverId), cachestore.TagSessions,
+	)
+
+	action := ExcludeSession
+	if !excluded {
+		action = IncludeSession
+	}
+
+	err = util.Transact(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE session SET is_excluded = ? WHERE id = ? AND is_excluded != ?`,
+			excluded, sessionId, excluded)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			if excluded {
+				return fmt.Errorf("session %v is already excluded", sessionId)
+			}
+			return fmt.Errorf("session %v is not excluded", sessionId)
+		}
+
+		return s.writeLog(tx, moderatorId, action, nil, &sessionId, reason)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Incomplete sessions are not aggregated yet
+	if !isCompleted || period == 0 {
+		return nil
+	}
+
+	return s.serverService.RecalcWeeklyStats(serverId, period)
+}
+
+func (s *ModerationService) GetLog(req GetLogRequest) (*GetLogResponse, error) {
+	page, limit := req.Pager.Parse()
+
+	conds := make([]string, 0)
+	args := make([]any, 0)
+
+	conds = append(conds, "1 = 1")
+
+	if req.ModeratorId != 0 {
+		conds = append(conds, "log.moderator_id = ?")
+		args = append(args, req.ModeratorId)
+	}
+
+	if req.UserId != 0 {
+		conds = append(conds, "log.user_id = ?")
+		args = append(args, req.UserId)
+	}
+
+	if req.SessionId != 0 {
+		conds = append(conds, "log.session_id = ?")
+		args = append(args, req.SessionId)
+	}
+
+	var total int
+	err := s.db.QueryRow(fmt.Sprintf(`
+		SELECT count(*) FROM moderation_log log WHERE %v`, strings.Join(conds, " AND "),
+	), args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			log.id, log.moderator_id, moderator.name,
+			log.action, log.user_id, log.session_id, log.reason,
+			log.created_at
+		FROM moderation_log log
+		LEFT JOIN users moderator ON moderator.id = log.moderator_id
+		WHERE %v
+		ORDER BY log.id DESC
+		LIMIT %v, %v`,
+		strings.Join(conds, " AND "), page*limit, limit,
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*LogEntry{}
+	for rows.Next() {
+		item := LogEntry{}
+
+		err := rows.Scan(
+			&item.Id, &item.ModeratorId, &item.ModeratorName,
+			&item.Action, &item.UserId, &item.SessionId, &item.Reason,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return &GetLogResponse{
+		Items: items,
+		Metadata: models.PaginationResponse{
+			Page:           page,
+			ResultsPerPage: limit,
+			TotalResults:   total,
+		},
+	}, nil
+}
@@ -0,0 +1,45 @@
+package moderation
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type BanUserRequest struct {
+	UserId int    `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+
+	// Permanent ban if not set
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UnbanUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+type GetBansRequest struct {
+	UserId     int  `form:"user_id"`
+	ActiveOnly bool `form:"active"`
+}
+
+type GetBansResponse struct {
+	Items []*Ban `json:"items"`
+}
+
+type ExcludeSessionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type GetLogRequest struct {
+	ModeratorId int `json:"moderator_id"`
+	UserId      int `json:"user_id"`
+	SessionId   int `json:"session_id"`
+
+	Pager models.PaginationRequest `json:"pager"`
+}
+
+type GetLogResponse struct {
+	Items    []*LogEntry               `json:"items"`
+	Metadata models.PaginationResponse `json:"metadata"`
+}
@@ -0,0 +1,343 @@
+package organizations
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/leaderboards"
+)
+
+type controller struct {
+	service *OrganizationsService
+}
+
+// Parses organization id and checks that the authorized user has the required role
+func (c *controller) authorize(ctx *gin.Context, ownerOnly bool) (int, bool) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return 0, false
+	}
+
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.String(http.StatusUnauthorized, "")
+		return 0, false
+	}
+
+	role, err := c.service.GetMemberRole(id, user.UserId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return 0, false
+	}
+
+	if role == 0 || ownerOnly && role != Owner {
+		ctx.String(http.StatusForbidden, "")
+		return 0, false
+	}
+
+	return id, true
+}
+
+// @Summary Get organizations by pattern
+// @Tags 	Organizations
+// @Produce json
+// @Param   pattern query 	string false "Name or slug pattern"
+// @Success 200 {object} 	GetOrganizationsResponse
+// @Router /organizations/ [get]
+func (c *controller) getByPattern(ctx *gin.Context) {
+	pattern := ctx.Query("pattern")
+
+	items, err := c.service.GetByPattern(pattern)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetOrganizationsResponse{
+		Items: items,
+	})
+}
+
+// @Summary Get organization with servers and members
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Success 200 {object} 	GetOrganizationResponse
+// @Router /organizations/{id} [get]
+func (c *controller) getById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.getDetailed(id)
+	if err != nil {
+		ctx.String(http.StatusNotFound, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// @Summary Create organization, authorized user becomes its owner
+// @Tags 	Organizations
+// @Produce json
+// @Param   body body 		CreateOrganizationRequest true "Body"
+// @Success 201 {object} 	CreateOrganizationResponse
+// @Router /organizations/ [post]
+func (c *controller) create(ctx *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.String(http.StatusUnauthorized, "")
+		return
+	}
+
+	id, err := c.service.Create(req, user.UserId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, CreateOrganizationResponse{
+		Id: id,
+	})
+}
+
+// @Summary Update organization name and branding
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Param   body body 		UpdateOrganizationRequest true "Body"
+// @Success 200
+// @Router /organizations/{id} [put]
+func (c *controller) update(ctx *gin.Context) {
+	var req UpdateOrganizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, ok := c.authorize(ctx, false)
+	if !ok {
+		return
+	}
+
+	err := c.service.Update(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Delete organization
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Success 200
+// @Router /organizations/{id} [delete]
+func (c *controller) delete(ctx *gin.Context) {
+	id, ok := c.authorize(ctx, true)
+	if !ok {
+		return
+	}
+
+	err := c.service.Delete(id)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Add organization admin
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Param   body body 		AddMemberRequest true "Body"
+// @Success 200
+// @Router /organizations/{id}/members [post]
+func (c *controller) addMember(ctx *gin.Context) {
+	var req AddMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, ok := c.authorize(ctx, false)
+	if !ok {
+		return
+	}
+
+	err := c.service.AddMember(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Remove organization admin
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Param   userId path   	int true "User id"
+// @Success 200
+// @Router /organizations/{id}/members/{userId} [delete]
+func (c *controller) removeMember(ctx *gin.Context) {
+	userId, err := strconv.Atoi(ctx.Params.ByName("userId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, ok := c.authorize(ctx, false)
+	if !ok {
+		return
+	}
+
+	err = c.service.RemoveMember(id, userId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Attach server to organization
+// @Description Caller should be organization admin and have server admin role on the server
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Param   body body 		AddServerRequest true "Body"
+// @Success 200
+// @Router /organizations/{id}/servers [post]
+func (c *controller) addServer(ctx *gin.Context) {
+	var req AddServerRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, ok := c.authorize(ctx, false)
+	if !ok {
+		return
+	}
+
+	// Organization admin should also manage the server itself
+	user, _ := util.GetUserFromCtx(ctx)
+	if !user.HasRole(req.ServerId, models.ServerAdmin) {
+		ctx.String(http.StatusForbidden, "")
+		return
+	}
+
+	err := c.service.AddServer(id, req.ServerId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Detach server from organization
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Param   serverId path   int true "Server id"
+// @Success 200
+// @Router /organizations/{id}/servers/{serverId} [delete]
+func (c *controller) removeServer(ctx *gin.Context) {
+	serverId, err := strconv.Atoi(ctx.Params.ByName("serverId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, ok := c.authorize(ctx, false)
+	if !ok {
+		return
+	}
+
+	err = c.service.RemoveServer(id, serverId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Get analytics rolled up across organization servers
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Param   body body 		DashboardRequest true "Body"
+// @Success 201 {object} 	DashboardResponse
+// @Router /organizations/{id}/dashboard [post]
+func (c *controller) getDashboard(ctx *gin.Context) {
+	var req DashboardRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.getDashboard(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// @Summary Get leaderboard across organization servers
+// @Tags 	Organizations
+// @Produce json
+// @Param   id path   	 	int true "Organization id"
+// @Param   body body 		leaderboards.LeaderBoardsRequest true "Body"
+// @Success 200 {object} 	leaderboards.LeaderBoardsResponse
+// @Router /organizations/{id}/leaderboards [post]
+func (c *controller) getLeaderBoard(ctx *gin.Context) {
+	var req leaderboards.LeaderBoardsRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.getLeaderBoard(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
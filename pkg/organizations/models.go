@@ -0,0 +1,39 @@
+package organizations
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type MemberRole = int
+
+const (
+	Owner MemberRole = iota + 1
+	Admin
+)
+
+type Branding struct {
+	Description *string `json:"description"`
+	LogoUrl     *string `json:"logo_url"`
+	BannerUrl   *string `json:"banner_url"`
+	AccentColor *string `json:"accent_color"`
+	WebsiteUrl  *string `json:"website_url"`
+	DiscordUrl  *string `json:"discord_url"`
+}
+
+type Organization struct {
+	Id   int    `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+
+	Branding
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrganizationMember struct {
+	models.UserProfile
+
+	Role MemberRole `json:"role"`
+}
@@ -0,0 +1,72 @@
+package organizations
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	cache "github.com/chenyahui/gin-cache"
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/strategy"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/leaderboards"
+)
+
+func RegisterRoutes(
+	r *gin.RouterGroup,
+	service *OrganizationsService,
+	cacheStore cachestore.Store,
+) {
+	controller := controller{
+		service: service,
+	}
+
+	store := cachestore.Tagged(cacheStore, cachestore.TagSessions, cachestore.TagUsers)
+
+	routes := r.Group("/organizations")
+
+	routes.GET("/", controller.getByPattern)
+	routes.GET("/:id", controller.getById)
+
+	routes.POST("/", middleware.AuthMiddleWave, controller.create)
+	routes.PUT("/:id", middleware.AuthMiddleWave, controller.update)
+	routes.DELETE("/:id", middleware.AuthMiddleWave, controller.delete)
+
+	routes.POST("/:id/members", middleware.AuthMiddleWave, controller.addMember)
+	routes.DELETE("/:id/members/:userId", middleware.AuthMiddleWave, controller.removeMember)
+
+	routes.POST("/:id/servers", middleware.AuthMiddleWave, controller.addServer)
+	routes.DELETE("/:id/servers/:serverId", middleware.AuthMiddleWave, controller.removeServer)
+
+	routes.POST("/:id/dashboard",
+		cache.Cache(store, 5*time.Minute,
+			strategy.CacheByRequestBody(func(req DashboardRequest) string {
+				from, to := "", ""
+				if req.From != nil {
+					from = req.From.Format("2006-01-02")
+				}
+				if req.To != nil {
+					to = req.To.Format("2006-01-02")
+				}
+
+				return fmt.Sprintf("%v/%v/%v/%v", from, to, req.Period, req.MapsLimit)
+			}),
+		),
+		controller.getDashboard)
+
+	routes.POST("/:id/leaderboards",
+		cache.Cache(store, 5*time.Minute,
+			strategy.CacheByRequestBody(func(req leaderboards.LeaderBoardsRequest) string {
+				slices.Sort(req.ServerIds)
+
+				return fmt.Sprintf("%v/%v/%v/%v/%v/%v",
+					util.IntArrayToString(req.ServerIds, ","),
+					req.OrderBy, req.Perk, req.Page,
+					req.From.Format("2006-01-02"), req.To.Format("2006-01-02"),
+				)
+			}),
+		),
+		controller.getLeaderBoard)
+}
@@ -0,0 +1,526 @@
+package organizations
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/analytics"
+	analyticsMaps "github.com/theggv/kf2-stats-backend/pkg/analytics/maps"
+	analyticsServer "github.com/theggv/kf2-stats-backend/pkg/analytics/server"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/leaderboards"
+	"github.com/theggv/kf2-stats-backend/pkg/server"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+)
+
+var slugRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+type OrganizationsService struct {
+	db *sql.DB
+
+	userService            *users.UserService
+	serverAnalyticsService *analyticsServer.ServerAnalyticsService
+	mapAnalyticsService    *analyticsMaps.MapAnalyticsService
+	leaderBoardsService    *leaderboards.LeaderBoardsService
+}
+
+func NewOrganizationsService(db *sql.DB) *OrganizationsService {
+	service := OrganizationsService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *OrganizationsService) Inject(
+	userService *users.UserService,
+	serverAnalyticsService *analyticsServer.ServerAnalyticsService,
+	mapAnalyticsService *analyticsMaps.MapAnalyticsService,
+	leaderBoardsService *leaderboards.LeaderBoardsService,
+) {
+	s.userService = userService
+	s.serverAnalyticsService = serverAnalyticsService
+	s.mapAnalyticsService = mapAnalyticsService
+	s.leaderBoardsService = leaderBoardsService
+}
+
+func (s *OrganizationsService) Create(req CreateOrganizationRequest, ownerId int) (int, error) {
+	req.Slug = normalizeSlug(req.Slug)
+	if !slugRegexp.MatchString(req.Slug) {
+		return 0, fmt.Errorf("invalid slug %v", req.Slug)
+	}
+
+	var id int64
+	err := util.Transact(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			INSERT INTO organization (
+				slug, name, description,
+				logo_url, banner_url, accent_color, website_url, discord_url)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			req.Slug, req.Name, req.Description,
+			req.LogoUrl, req.BannerUrl, req.AccentColor, req.WebsiteUrl, req.DiscordUrl,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO organization_member (organization_id, user_id, role)
+			VALUES (?, ?, ?)`, id, ownerId, Owner,
+		)
+
+		return err
+	})
+
+	return int(id), err
+}
+
+func (s *OrganizationsService) Update(id int, req UpdateOrganizationRequest) error {
+	_, err := s.db.Exec(`
+		UPDATE organization
+		SET name = ?, description = ?,
+			logo_url = ?, banner_url = ?, accent_color = ?,
+			website_url = ?, discord_url = ?
+		WHERE id = ?`,
+		req.Name, req.Description,
+		req.LogoUrl, req.BannerUrl, req.AccentColor,
+		req.WebsiteUrl, req.DiscordUrl,
+		id,
+	)
+
+	return err
+}
+
+func (s *OrganizationsService) Delete(id int) error {
+	_, err := s.db.Exec(`DELETE FROM organization WHERE id = ?`, id)
+
+	return err
+}
+
+func (s *OrganizationsService) scanOrganizations(rows *sql.Rows) ([]*Organization, error) {
+	defer rows.Close()
+
+	items := []*Organization{}
+	for rows.Next() {
+		item := Organization{}
+
+		err := rows.Scan(
+			&item.Id, &item.Slug, &item.Name, &item.Description,
+			&item.LogoUrl, &item.BannerUrl, &item.AccentColor,
+			&item.WebsiteUrl, &item.DiscordUrl, &item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+func (s *OrganizationsService) GetByPattern(pattern string) ([]*Organization, error) {
+	sqlPattern := "%" + pattern + "%"
+
+	rows, err := s.db.Query(`
+		SELECT
+			id, slug, name, description,
+			logo_url, banner_url, accent_color,
+			website_url, discord_url, created_at
+		FROM organization
+		WHERE (slug LIKE ?) OR (name LIKE ?)
+		ORDER BY name`, sqlPattern, sqlPattern,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scanOrganizations(rows)
+}
+
+func (s *OrganizationsService) GetById(id int) (*Organization, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			id, slug, name, description,
+			logo_url, banner_url, accent_color,
+			website_url, discord_url, created_at
+		FROM organization
+		WHERE id = ?`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.scanOrganizations(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return items[0], nil
+}
+
+func (s *OrganizationsService) getDetailed(id int) (*GetOrganizationResponse, error) {
+	organization, err := s.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := s.GetServers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.GetMembers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetOrganizationResponse{
+		Organization: *organization,
+		Servers:      servers,
+		Members:      members,
+	}, nil
+}
+
+func (s *OrganizationsService) GetMembers(id int) ([]*OrganizationMember, error) {
+	rows, err := s.db.Query(`
+		SELECT user_id, role
+		FROM organization_member
+		WHERE organization_id = ?
+		ORDER BY role, created_at`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*OrganizationMember{}
+	userIds := []int{}
+
+	for rows.Next() {
+		item := OrganizationMember{}
+
+		err := rows.Scan(&item.Id, &item.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		userIds = append(userIds, item.Id)
+		items = append(items, &item)
+	}
+
+	if len(userIds) == 0 {
+		return items, nil
+	}
+
+	profiles, err := s.userService.GetUserProfiles(userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		for _, item := range items {
+			if item.Id == profile.Id {
+				item.UserProfile = *profile
+			}
+		}
+	}
+
+	return items, nil
+}
+
+func (s *OrganizationsService) GetMemberRole(id, userId int) (MemberRole, error) {
+	var role MemberRole
+
+	err := s.db.QueryRow(`
+		SELECT role
+		FROM organization_member
+		WHERE organization_id = ? AND user_id = ?`, id, userId,
+	).Scan(&role)
+
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return role, err
+}
+
+func (s *OrganizationsService) IsAdmin(id, userId int) bool {
+	role, err := s.GetMemberRole(id, userId)
+
+	return err == nil && (role == Owner || role == Admin)
+}
+
+// Adds user as an admin, ownership can't be transferred this way
+func (s *OrganizationsService) AddMember(id int, req AddMemberRequest) error {
+	_, err := s.db.Exec(`
+		INSERT INTO organization_member (organization_id, user_id, role)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = role`,
+		id, req.UserId, Admin,
+	)
+
+	return err
+}
+
+func (s *OrganizationsService) RemoveMember(id, userId int) error {
+	_, err := s.db.Exec(`
+		DELETE FROM organization_member
+		WHERE organization_id = ? AND user_id = ? AND role != ?`,
+		id, userId, Owner,
+	)
+
+	return err
+}
+
+func (s *OrganizationsService) GetServerIds(id int) ([]int, error) {
+	rows, err := s.db.Query(`
+		SELECT server_id
+		FROM organization_server
+		WHERE organization_id = ?`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []int{}
+	for rows.Next() {
+		var serverId int
+
+		err := rows.Scan(&serverId)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, serverId)
+	}
+
+	return items, nil
+}
+
+func (s *OrganizationsService) GetServers(id int) ([]*server.Server, error) {
+	rows, err := s.db.Query(`
+		SELECT server.id, server.name, server.address
+		FROM organization_server org
+		INNER JOIN server ON server.id = org.server_id
+		WHERE org.organization_id = ?
+		ORDER BY server.name`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*server.Server{}
+	for rows.Next() {
+		item := server.Server{}
+
+		err := rows.Scan(&item.Id, &item.Name, &item.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// Server can belong to a single organization only,
+// it should be detached from the previous organization first
+func (s *OrganizationsService) AddServer(id int, serverId int) error {
+	var current int
+	err := s.db.QueryRow(`
+		SELECT organization_id FROM organization_server WHERE server_id = ?`, serverId,
+	).Scan(&current)
+
+	if err == nil {
+		if current == id {
+			return nil
+		}
+
+		return fmt.Errorf("server %v already belongs to another organization", serverId)
+	}
+
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO organization_server (server_id, organization_id)
+		VALUES (?, ?)`,
+		serverId, id,
+	)
+
+	return err
+}
+
+func (s *OrganizationsService) RemoveServer(id int, serverId int) error {
+	_, err := s.db.Exec(`
+		DELETE FROM organization_server
+		WHERE organization_id = ? AND server_id = ?`,
+		id, serverId,
+	)
+
+	return err
+}
+
+func (s *OrganizationsService) getServersSummary(serverIds []int) ([]*DashboardResponseServer, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			server.id,
+			server.name,
+			count(DISTINCT session.id) as total_sessions,
+			count(DISTINCT aggr.user_id) as total_users
+		FROM server
+		LEFT JOIN session ON session.server_id = server.id
+		LEFT JOIN session_aggregated aggr ON aggr.session_id = session.id
+		WHERE server.id IN (%v)
+		GROUP BY server.id
+		ORDER BY total_users DESC`, util.IntArrayToString(serverIds, ","),
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*DashboardResponseServer{}
+	for rows.Next() {
+		item := DashboardResponseServer{}
+
+		err := rows.Scan(&item.Id, &item.Name, &item.TotalSessions, &item.TotalUsers)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+func (s *OrganizationsService) getDashboard(id int, req DashboardRequest) (*DashboardResponse, error) {
+	serverIds, err := s.GetServerIds(id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := DashboardResponse{
+		Servers:       []*DashboardResponseServer{},
+		SessionCount:  []*models.PeriodData{},
+		Usage:         []*models.PeriodData{},
+		PlayersOnline: []*models.PeriodData{},
+		PopularMaps:   []*analyticsMaps.MapAnalytics{},
+	}
+
+	if len(serverIds) == 0 {
+		return &res, nil
+	}
+
+	res.Servers, err = s.getServersSummary(serverIds)
+	if err != nil {
+		return nil, err
+	}
+
+	res.SessionCount, err = s.serverAnalyticsService.GetSessionCount(analyticsServer.SessionCountRequest{
+		ServerIds: serverIds,
+		From:      req.From,
+		To:        req.To,
+		Period:    req.Period,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Usage is not available by hours
+	if req.Period != analytics.Hour {
+		res.Usage, err = s.serverAnalyticsService.GetUsageInMinutes(analyticsServer.UsageInMinutesRequest{
+			ServerIds: serverIds,
+			From:      req.From,
+			To:        req.To,
+			Period:    req.Period,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res.PlayersOnline, err = s.serverAnalyticsService.GetPlayersOnline(analyticsServer.PlayersOnlineRequest{
+		ServerIds: serverIds,
+		From:      req.From,
+		To:        req.To,
+		Period:    req.Period,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mapsReq := analyticsMaps.MapAnalyticsRequest{
+		ServerIds: serverIds,
+		Limit:     req.MapsLimit,
+		From:      time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:        time.Now(),
+	}
+	if req.From != nil && req.To != nil {
+		mapsReq.From, mapsReq.To = *req.From, *req.To
+	}
+
+	res.PopularMaps, err = s.mapAnalyticsService.GetMapAnalytics(mapsReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (s *OrganizationsService) getLeaderBoard(
+	id int, req leaderboards.LeaderBoardsRequest,
+) (*leaderboards.LeaderBoardsResponse, error) {
+	serverIds, err := s.GetServerIds(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(serverIds) == 0 {
+		return nil, fmt.Errorf("organization has no servers")
+	}
+
+	// Restrict requested servers to the organization ones
+	if len(req.ServerIds) > 0 {
+		filtered := []int{}
+		for _, serverId := range req.ServerIds {
+			if slices.Contains(serverIds, serverId) {
+				filtered = append(filtered, serverId)
+			}
+		}
+
+		if len(filtered) == 0 {
+			return nil, fmt.Errorf("requested servers don't belong to the organization")
+		}
+
+		serverIds = filtered
+	}
+
+	req.ServerIds = serverIds
+
+	return s.leaderBoardsService.GetLeaderBoard(req)
+}
+
+func normalizeSlug(slug string) string {
+	return strings.ToLower(strings.TrimSpace(slug))
+}
@@ -0,0 +1,71 @@
+package organizations
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/analytics"
+	analyticsMaps "github.com/theggv/kf2-stats-backend/pkg/analytics/maps"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/server"
+)
+
+type CreateOrganizationRequest struct {
+	Slug string `json:"slug" binding:"required"`
+	Name string `json:"name" binding:"required"`
+
+	Branding
+}
+
+type CreateOrganizationResponse struct {
+	Id int `json:"id"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+
+	Branding
+}
+
+type GetOrganizationsResponse struct {
+	Items []*Organization `json:"items"`
+}
+
+type GetOrganizationResponse struct {
+	Organization
+
+	Servers []*server.Server      `json:"servers"`
+	Members []*OrganizationMember `json:"members"`
+}
+
+type AddMemberRequest struct {
+	UserId int `json:"user_id" binding:"required"`
+}
+
+type AddServerRequest struct {
+	ServerId int `json:"server_id" binding:"required"`
+}
+
+type DashboardRequest struct {
+	From   *time.Time           `json:"date_from"`
+	To     *time.Time           `json:"date_to"`
+	Period analytics.TimePeriod `json:"period" binding:"required"`
+
+	MapsLimit int `json:"maps_limit"`
+}
+
+type DashboardResponseServer struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+
+	TotalSessions int `json:"total_sessions"`
+	TotalUsers    int `json:"total_users"`
+}
+
+type DashboardResponse struct {
+	Servers []*DashboardResponseServer `json:"servers"`
+
+	SessionCount  []*models.PeriodData          `json:"session_count"`
+	Usage         []*models.PeriodData          `json:"usage"`
+	PlayersOnline []*models.PeriodData          `json:"players_online"`
+	PopularMaps   []*analyticsMaps.MapAnalytics `json:"popular_maps"`
+}
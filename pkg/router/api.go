@@ -1,26 +1,35 @@
 package router
 
 import (
-	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
+	analyticsCD "github.com/theggv/kf2-stats-backend/pkg/analytics/cd"
 	analyticsMaps "github.com/theggv/kf2-stats-backend/pkg/analytics/maps"
 	analyticsPerks "github.com/theggv/kf2-stats-backend/pkg/analytics/perks"
 	analyticsServer "github.com/theggv/kf2-stats-backend/pkg/analytics/server"
+	analyticsSquads "github.com/theggv/kf2-stats-backend/pkg/analytics/squads"
 	analyticsUsers "github.com/theggv/kf2-stats-backend/pkg/analytics/users"
+	"github.com/theggv/kf2-stats-backend/pkg/apitokens"
 	"github.com/theggv/kf2-stats-backend/pkg/auth"
 	"github.com/theggv/kf2-stats-backend/pkg/common/store"
 	"github.com/theggv/kf2-stats-backend/pkg/leaderboards"
 	"github.com/theggv/kf2-stats-backend/pkg/maps"
 	"github.com/theggv/kf2-stats-backend/pkg/matches"
 	matchesFilter "github.com/theggv/kf2-stats-backend/pkg/matches/filter"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
+	"github.com/theggv/kf2-stats-backend/pkg/organizations"
 	"github.com/theggv/kf2-stats-backend/pkg/server"
+	"github.com/theggv/kf2-stats-backend/pkg/server/health"
 	"github.com/theggv/kf2-stats-backend/pkg/session"
 	"github.com/theggv/kf2-stats-backend/pkg/session/difficulty"
 	"github.com/theggv/kf2-stats-backend/pkg/stats"
 	"github.com/theggv/kf2-stats-backend/pkg/users"
+	"github.com/theggv/kf2-stats-backend/pkg/users/achievements"
+	"github.com/theggv/kf2-stats-backend/pkg/users/follows"
+	"github.com/theggv/kf2-stats-backend/pkg/users/gdpr"
+	"github.com/theggv/kf2-stats-backend/pkg/users/records"
 )
 
-func RegisterApiRoutes(r *gin.Engine, store *store.Store, memoryStore *persist.MemoryStore) {
+func RegisterApiRoutes(r *gin.Engine, store *store.Store) {
 	api := r.Group("/api")
 
 	auth.RegisterRoutes(api, store.Auth)
@@ -29,15 +38,25 @@ func RegisterApiRoutes(r *gin.Engine, store *store.Store, memoryStore *persist.M
 	session.RegisterRoutes(api, store.Sessions)
 	stats.RegisterRoutes(api, store.Stats)
 	users.RegisterRoutes(api, store.Users)
-	matches.RegisterRoutes(api, store.Matches, memoryStore)
+	matches.RegisterRoutes(api, store.Matches, store.Cache)
 
-	matchesFilter.RegisterRoutes(api, store.MatchesFilter, memoryStore)
+	matchesFilter.RegisterRoutes(api, store.MatchesFilter, store.Cache)
 	difficulty.RegisterRoutes(api, store.Difficulty)
+	achievements.RegisterRoutes(api, store.Achievements)
+	records.RegisterRoutes(api, store.Records)
+	health.RegisterRoutes(api, store.Health)
 
-	analyticsMaps.RegisterRoutes(api, store.AnalyticsMaps, memoryStore)
-	analyticsServer.RegisterRoutes(api, store.AnalyticsServer, memoryStore)
-	analyticsPerks.RegisterRoutes(api, store.AnalyticsPerks, memoryStore)
-	analyticsUsers.RegisterRoutes(api, store.AnalyticsUsers, memoryStore)
+	analyticsMaps.RegisterRoutes(api, store.AnalyticsMaps, store.Cache)
+	analyticsServer.RegisterRoutes(api, store.AnalyticsServer, store.Cache)
+	analyticsPerks.RegisterRoutes(api, store.AnalyticsPerks, store.Cache)
+	analyticsUsers.RegisterRoutes(api, store.AnalyticsUsers, store.Cache)
+	analyticsSquads.RegisterRoutes(api, store.AnalyticsSquads, store.Cache)
+	analyticsCD.RegisterRoutes(api, store.AnalyticsCD, store.Cache)
 
-	leaderboards.RegisterRoutes(api, store.LeaderBoards, memoryStore)
+	leaderboards.RegisterRoutes(api, store.LeaderBoards, store.Cache)
+	organizations.RegisterRoutes(api, store.Organizations, store.Cache)
+	moderation.RegisterRoutes(api, store.Moderation)
+	apitokens.RegisterRoutes(api, store.ApiTokens)
+	gdpr.RegisterRoutes(api, store.Gdpr)
+	follows.RegisterRoutes(api, store.Follows)
 }
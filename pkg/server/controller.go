@@ -37,12 +37,18 @@ func (c *serverController) getById(ctx *gin.Context) {
 // @Tags 	Server
 // @Produce json
 // @Param   pattern query 	string false "Get servers by pattern"
+// @Param   region query 	string false "Server region"
+// @Param   tags query 		[]string false "Server must have all of the tags"
 // @Success 200 {array} 	Server
 // @Router /servers [get]
 func (c *serverController) getByPattern(ctx *gin.Context) {
-	pattern := ctx.Query("pattern")
+	var req GetByPatternRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
 
-	items, err := c.service.GetByPattern(pattern)
+	items, err := c.service.GetByPattern(req)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
@@ -75,6 +81,107 @@ func (c *serverController) updateName(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{})
 }
 
+// @Summary Update server display name, region, description, tags and discord link
+// @Tags 	Server
+// @Produce json
+// @Param   id path   	 	int true "Server id"
+// @Param   key query 		string false "Api key, alternative to the admin role"
+// @Param   body body 		UpdateMetadataRequest true "Body"
+// @Success 200
+// @Router /servers/{id}/metadata [put]
+func (c *serverController) updateMetadata(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req UpdateMetadataRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.UpdateMetadata(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Get server name history
+// @Tags 	Server
+// @Produce json
+// @Param   id path   	 	int true "Server id"
+// @Success 200 {object} 	GetNameHistoryResponse
+// @Router /servers/{id}/names [get]
+func (c *serverController) getNameHistory(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := c.service.GetNameHistory(id)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetNameHistoryResponse{
+		Items: items,
+	})
+}
+
+// @Summary Get server address history
+// @Tags 	Server
+// @Produce json
+// @Param   id path   	 	int true "Server id"
+// @Success 200 {object} 	GetAddressHistoryResponse
+// @Router /servers/{id}/addresses [get]
+func (c *serverController) getAddressHistory(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := c.service.GetAddressHistory(id)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetAddressHistoryResponse{
+		Items: items,
+	})
+}
+
+// @Summary Merge source server into target server
+// @Tags 	Server
+// @Produce json
+// @Param   key query 		string false "Api key, alternative to the admin role"
+// @Param   body body 		MergeServersRequest true "Body"
+// @Success 200
+// @Router /servers/merge [post]
+func (c *serverController) merge(ctx *gin.Context) {
+	var req MergeServersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := c.service.Merge(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
 // @Summary Get recent server users
 // @Tags 	Server
 // @Produce json
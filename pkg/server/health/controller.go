@@ -0,0 +1,128 @@
+package health
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type controller struct {
+	service *HealthService
+}
+
+// @Summary Get server health and uptime
+// @Tags 	Server
+// @Produce json
+// @Param   id path   	 	int true "Server id"
+// @Param   days query 		int false "Uptime window in days"
+// @Success 200 {object} 	GetHealthResponse
+// @Router /servers/{id}/health [get]
+func (c *controller) getHealth(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req GetHealthRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.GetHealth(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// @Summary Get server alerts
+// @Tags 	Server
+// @Produce json
+// @Param   id path   	 	int true "Server id"
+// @Param   key query 		string false "Api key, alternative to the admin role"
+// @Success 200 {object} 	GetAlertsResponse
+// @Router /servers/{id}/alerts [get]
+func (c *controller) getAlerts(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := c.service.GetAlerts(id)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetAlertsResponse{
+		Items: items,
+	})
+}
+
+// @Summary Create server alert
+// @Tags 	Server
+// @Produce json
+// @Param   id path   	 	int true "Server id"
+// @Param   key query 		string false "Api key, alternative to the admin role"
+// @Param   body body 		CreateAlertRequest true "Body"
+// @Success 201 {object} 	CreateAlertResponse
+// @Router /servers/{id}/alerts [post]
+func (c *controller) createAlert(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req CreateAlertRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	alertId, err := c.service.CreateAlert(id, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, CreateAlertResponse{
+		Id: alertId,
+	})
+}
+
+// @Summary Delete server alert
+// @Tags 	Server
+// @Produce json
+// @Param   id path   	 	int true "Server id"
+// @Param   alertId path   	int true "Alert id"
+// @Param   key query 		string false "Api key, alternative to the admin role"
+// @Success 200
+// @Router /servers/{id}/alerts/{alertId} [delete]
+func (c *controller) deleteAlert(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	alertId, err := strconv.Atoi(ctx.Params.ByName("alertId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.DeleteAlert(id, alertId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
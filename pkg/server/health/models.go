@@ -0,0 +1,47 @@
+package health
+
+import "time"
+
+type AlertEvent = string
+
+const (
+	ServerSilent    AlertEvent = "server_silent"
+	AbortedSessions AlertEvent = "aborted_sessions"
+)
+
+const (
+	// Server is considered online if the last heartbeat is newer than this
+	onlineThreshold = 5 * time.Minute
+
+	heartbeatRetention = 90 * 24 * time.Hour
+)
+
+type Alert struct {
+	Id       int    `json:"id"`
+	ServerId int    `json:"server_id"`
+	Webhook  string `json:"webhook"`
+
+	// Notify when server didn't send heartbeats for this amount of minutes, 0 to disable
+	SilentMinutes int `json:"silent_minutes"`
+
+	// Notify when server has this amount of aborted sessions within the window, 0 to disable
+	AbortedThreshold     int `json:"aborted_threshold"`
+	AbortedWindowMinutes int `json:"aborted_window_minutes"`
+
+	SilentNotifiedAt  *time.Time `json:"silent_notified_at"`
+	AbortedNotifiedAt *time.Time `json:"aborted_notified_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookPayload struct {
+	// Discord compatible message
+	Content string `json:"content"`
+
+	Event      AlertEvent `json:"event"`
+	ServerId   int        `json:"server_id"`
+	ServerName string     `json:"server_name"`
+
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
+	AbortedSessions int        `json:"aborted_sessions,omitempty"`
+}
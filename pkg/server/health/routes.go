@@ -0,0 +1,22 @@
+package health
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, service *HealthService) {
+	controller := controller{
+		service: service,
+	}
+
+	routes := r.Group("/servers")
+
+	routes.GET("/:id/health", controller.getHealth)
+
+	alerts := routes.Group("/:id/alerts", middleware.ServerRoleMiddleWave("id", models.ServerAdmin))
+	alerts.GET("", controller.getAlerts)
+	alerts.POST("", controller.createAlert)
+	alerts.DELETE("/:alertId", controller.deleteAlert)
+}
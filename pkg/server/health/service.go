@@ -0,0 +1,306 @@
+package health
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type HealthService struct {
+	db *sql.DB
+
+	client *http.Client
+}
+
+func NewHealthService(db *sql.DB) *HealthService {
+	service := HealthService{
+		db:     db,
+		client: newWebhookClient(),
+	}
+
+	return &service
+}
+
+// Sends alerts and removes old heartbeats, run by the worker every minute
+func (s *HealthService) Monitor() error {
+	err := s.checkAlerts()
+	if err != nil {
+		fmt.Printf("[health] %v\n", err)
+	}
+
+	_, err = s.db.Exec(`DELETE FROM server_heartbeat WHERE minute < ?`,
+		time.Now().Add(-heartbeatRetention))
+
+	return err
+}
+
+// Records heartbeat of the server which hosts the session
+func (s *HealthService) Heartbeat(sessionId int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO server_heartbeat (server_id, minute)
+		SELECT server_id, DATE_FORMAT(CURRENT_TIMESTAMP, '%Y-%m-%d %H:%i:00')
+		FROM session
+		WHERE id = ?
+		ON DUPLICATE KEY UPDATE minute = minute`, sessionId,
+	)
+
+	return err
+}
+
+func (s *HealthService) getLastHeartbeat(serverId int) (*time.Time, error) {
+	var lastHeartbeat *time.Time
+
+	err := s.db.QueryRow(`
+		SELECT max(minute)
+		FROM server_heartbeat
+		WHERE server_id = ?`, serverId,
+	).Scan(&lastHeartbeat)
+
+	return lastHeartbeat, err
+}
+
+func (s *HealthService) GetHealth(serverId int, req GetHealthRequest) (*GetHealthResponse, error) {
+	if req.Days <= 0 {
+		req.Days = 7
+	}
+	req.Days = min(req.Days, int(heartbeatRetention.Hours()/24))
+
+	now := time.Now()
+	res := GetHealthResponse{
+		ServerId: serverId,
+		From:     now.AddDate(0, 0, -req.Days),
+		To:       now,
+	}
+
+	lastHeartbeat, err := s.getLastHeartbeat(serverId)
+	if err != nil {
+		return nil, err
+	}
+
+	res.LastHeartbeatAt = lastHeartbeat
+	res.IsOnline = lastHeartbeat != nil && now.Sub(*lastHeartbeat) < onlineThreshold
+
+	// Uptime is counted from the first heartbeat if server is younger than the window
+	{
+		var minutes int
+		var firstHeartbeat *time.Time
+
+		err := s.db.QueryRow(`
+			SELECT
+				count(CASE WHEN minute >= ? THEN 1 END),
+				min(minute)
+			FROM server_heartbeat
+			WHERE server_id = ?`, res.From, serverId,
+		).Scan(&minutes, &firstHeartbeat)
+		if err != nil {
+			return nil, err
+		}
+
+		if firstHeartbeat != nil {
+			start := res.From
+			if firstHeartbeat.After(start) {
+				start = *firstHeartbeat
+			}
+
+			if totalMinutes := now.Sub(start).Minutes(); totalMinutes >= 1 {
+				res.UptimePercent = min(100, 100*float64(minutes)/totalMinutes)
+			}
+		}
+	}
+
+	err = s.db.QueryRow(`
+		SELECT
+			count(*),
+			count(CASE WHEN aborted.session_id IS NOT NULL THEN 1 END)
+		FROM session
+		LEFT JOIN session_aborted aborted ON aborted.session_id = session.id
+		WHERE session.server_id = ? AND session.created_at >= ?`, serverId, res.From,
+	).Scan(&res.TotalSessions, &res.AbortedSessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (s *HealthService) CreateAlert(serverId int, req CreateAlertRequest) (int, error) {
+	if req.SilentMinutes <= 0 && req.AbortedThreshold <= 0 {
+		return 0, fmt.Errorf("either silent_minutes or aborted_threshold should be set")
+	}
+
+	if req.AbortedThreshold > 0 && req.AbortedWindowMinutes <= 0 {
+		req.AbortedWindowMinutes = 24 * 60
+	}
+
+	if err := validateWebhook(req.Webhook); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.Exec(`
+		INSERT INTO server_alert (
+			server_id, webhook,
+			silent_minutes, aborted_threshold, aborted_window_minutes)
+		VALUES (?, ?, ?, ?, ?)`,
+		serverId, req.Webhook,
+		max(req.SilentMinutes, 0), max(req.AbortedThreshold, 0), req.AbortedWindowMinutes,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+func (s *HealthService) DeleteAlert(serverId, alertId int) error {
+	_, err := s.db.Exec(`DELETE FROM server_alert WHERE id = ? AND server_id = ?`,
+		alertId, serverId)
+
+	return err
+}
+
+func (s *HealthService) getAlerts(cond string, args ...any) ([]*Alert, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			id, server_id, webhook,
+			silent_minutes, aborted_threshold, aborted_window_minutes,
+			silent_notified_at, aborted_notified_at, created_at
+		FROM server_alert
+		WHERE %v
+		ORDER BY id`, cond,
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*Alert{}
+	for rows.Next() {
+		item := Alert{}
+
+		err := rows.Scan(
+			&item.Id, &item.ServerId, &item.Webhook,
+			&item.SilentMinutes, &item.AbortedThreshold, &item.AbortedWindowMinutes,
+			&item.SilentNotifiedAt, &item.AbortedNotifiedAt, &item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+func (s *HealthService) GetAlerts(serverId int) ([]*Alert, error) {
+	return s.getAlerts("server_id = ?", serverId)
+}
+
+func (s *HealthService) checkAlerts() error {
+	alerts, err := s.getAlerts("1 = 1")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, alert := range alerts {
+		var serverName string
+		err := s.db.QueryRow(`SELECT name FROM server WHERE id = ?`, alert.ServerId).Scan(&serverName)
+		if err != nil {
+			fmt.Printf("[health] alert %v: %v\n", alert.Id, err)
+			continue
+		}
+
+		if alert.SilentMinutes > 0 {
+			lastHeartbeat, err := s.getLastHeartbeat(alert.ServerId)
+			if err != nil {
+				fmt.Printf("[health] alert %v: %v\n", alert.Id, err)
+			}
+
+			// Notify once per silence period, server without heartbeats at all is skipped
+			isSilent := err == nil && lastHeartbeat != nil &&
+				now.Sub(*lastHeartbeat) > time.Duration(alert.SilentMinutes)*time.Minute
+			isNotified := isSilent &&
+				alert.SilentNotifiedAt != nil && alert.SilentNotifiedAt.After(*lastHeartbeat)
+
+			if isSilent && !isNotified {
+				err := s.notify(alert, "silent_notified_at", &WebhookPayload{
+					Content: fmt.Sprintf("Server %v stopped reporting, last heartbeat at %v",
+						serverName, lastHeartbeat.Format(time.RFC3339)),
+					Event:           ServerSilent,
+					ServerId:        alert.ServerId,
+					ServerName:      serverName,
+					LastHeartbeatAt: lastHeartbeat,
+				})
+				if err != nil {
+					fmt.Printf("[health] alert %v: %v\n", alert.Id, err)
+				}
+			}
+		}
+
+		if alert.AbortedThreshold > 0 {
+			window := time.Duration(alert.AbortedWindowMinutes) * time.Minute
+
+			var count int
+			err := s.db.QueryRow(`
+				SELECT count(*)
+				FROM session_aborted
+				WHERE server_id = ? AND aborted_at >= ?`,
+				alert.ServerId, now.Add(-window),
+			).Scan(&count)
+			if err != nil {
+				fmt.Printf("[health] alert %v: %v\n", alert.Id, err)
+				continue
+			}
+
+			// Notify at most once per window
+			isNotified := alert.AbortedNotifiedAt != nil && now.Sub(*alert.AbortedNotifiedAt) < window
+
+			if count >= alert.AbortedThreshold && !isNotified {
+				err := s.notify(alert, "aborted_notified_at", &WebhookPayload{
+					Content: fmt.Sprintf("Server %v has %v aborted sessions in the last %v minutes",
+						serverName, count, alert.AbortedWindowMinutes),
+					Event:           AbortedSessions,
+					ServerId:        alert.ServerId,
+					ServerName:      serverName,
+					AbortedSessions: count,
+				})
+				if err != nil {
+					fmt.Printf("[health] alert %v: %v\n", alert.Id, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *HealthService) notify(alert *Alert, notifiedField string, payload *WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Post(alert.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %v", res.Status)
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(`
+		UPDATE server_alert SET %v = CURRENT_TIMESTAMP WHERE id = ?`, notifiedField),
+		alert.Id,
+	)
+
+	return err
+}
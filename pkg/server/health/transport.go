@@ -0,0 +1,40 @@
+package health
+
+import "time"
+
+type GetHealthRequest struct {
+	// Uptime window in days, 7 by default
+	Days int `form:"days"`
+}
+
+type GetHealthResponse struct {
+	ServerId int `json:"server_id"`
+
+	IsOnline        bool       `json:"is_online"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at"`
+
+	UptimePercent float64 `json:"uptime_percent"`
+
+	TotalSessions   int `json:"total_sessions"`
+	AbortedSessions int `json:"aborted_sessions"`
+
+	From time.Time `json:"date_from"`
+	To   time.Time `json:"date_to"`
+}
+
+type CreateAlertRequest struct {
+	// Https url, hosts resolving to private or loopback addresses are rejected
+	Webhook string `json:"webhook" binding:"required,url"`
+
+	SilentMinutes        int `json:"silent_minutes"`
+	AbortedThreshold     int `json:"aborted_threshold"`
+	AbortedWindowMinutes int `json:"aborted_window_minutes"`
+}
+
+type CreateAlertResponse struct {
+	Id int `json:"id"`
+}
+
+type GetAlertsResponse struct {
+	Items []*Alert `json:"items"`
+}
@@ -1,7 +1,27 @@
 package server
 
+import "time"
+
 type Server struct {
 	Id      int    `json:"id"`
 	Name    string `json:"name"`
 	Address string `json:"address"`
+
+	DisplayName *string  `json:"display_name"`
+	Region      *string  `json:"region"`
+	Description *string  `json:"description"`
+	DiscordUrl  *string  `json:"discord_url"`
+	Tags        []string `json:"tags"`
+}
+
+type AddressHistoryItem struct {
+	Address   string    `json:"address"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type NameHistoryItem struct {
+	Name      string    `json:"name"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
 }
@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 )
 
 func RegisterRoutes(r *gin.RouterGroup, serverService *ServerService) {
@@ -15,6 +16,10 @@ func RegisterRoutes(r *gin.RouterGroup, serverService *ServerService) {
 	routes.GET("/", controller.getByPattern)
 	routes.GET("/:id", controller.getById)
 	routes.GET("/:id/last-session", controller.getLastSession)
+	routes.GET("/:id/addresses", controller.getAddressHistory)
+	routes.GET("/:id/names", controller.getNameHistory)
+	routes.PUT("/:id/metadata", middleware.ServerRoleMiddleWave("id", models.ServerAdmin), controller.updateMetadata)
 	routes.PUT("/name", middleware.MutatorAuthMiddleWave, controller.updateName)
 	routes.POST("/users/recent", controller.getRecentUsers)
+	routes.POST("/merge", middleware.RoleMiddleWave(models.SuperAdmin), controller.merge)
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 	"github.com/theggv/kf2-stats-backend/pkg/session/difficulty"
@@ -16,14 +17,18 @@ type ServerService struct {
 
 	userService *users.UserService
 	diffService *difficulty.DifficultyCalculatorService
+
+	cache cachestore.Invalidator
 }
 
 func (s *ServerService) Inject(
 	userService *users.UserService,
 	diffService *difficulty.DifficultyCalculatorService,
+	cache cachestore.Invalidator,
 ) {
 	s.userService = userService
 	s.diffService = diffService
+	s.cache = cache
 }
 
 func NewServerService(db *sql.DB) *ServerService {
@@ -35,40 +40,191 @@ func NewServerService(db *sql.DB) *ServerService {
 }
 
 func (s *ServerService) Create(req AddServerRequest) (int, error) {
-	_, err := s.db.Exec(`
-		INSERT INTO server (name, address) VALUES (?, ?)
-			ON DUPLICATE KEY UPDATE name = ?`,
-		req.Name, req.Address, req.Name)
+	var id int
+
+	err := util.Transact(s.db, func(tx *sql.Tx) error {
+		var err error
+
+		if req.Guid != nil && *req.Guid != "" {
+			id, err = s.findCreateByGuid(tx, req)
+		} else {
+			id, err = s.findCreateByAddress(tx, req)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE server SET name = coalesce(display_name, ?), address = ? WHERE id = ?`,
+			req.Name, req.Address, id)
+		if err != nil {
+			return err
+		}
+
+		err = s.addNameHistory(tx, id, req.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO server_address_history (server_id, address) VALUES (?, ?)
+				ON DUPLICATE KEY UPDATE last_seen = CURRENT_TIMESTAMP`,
+			id, req.Address)
+
+		return err
+	})
+
+	return id, err
+}
+
+// Servers with guid keep their identity after address change.
+// Existing server without guid on the same address is claimed on the first request.
+func (s *ServerService) findCreateByGuid(tx *sql.Tx, req AddServerRequest) (int, error) {
+	var id int
+
+	err := tx.QueryRow(`SELECT id FROM server WHERE guid = ?`, req.Guid).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	err = tx.QueryRow(`
+		SELECT id FROM server
+		WHERE address = ? AND guid IS NULL
+		ORDER BY id DESC
+		LIMIT 1`, req.Address,
+	).Scan(&id)
+
+	if err == nil {
+		_, err = tx.Exec(`UPDATE server SET guid = ? WHERE id = ?`, req.Guid, id)
+		return id, err
+	}
+
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
 
+	res, err := tx.Exec(`INSERT INTO server (name, address, guid) VALUES (?, ?, ?)`,
+		req.Name, req.Address, req.Guid)
 	if err != nil {
 		return 0, err
 	}
 
-	data, err := s.getByAddress(req.Address)
+	lastId, err := res.LastInsertId()
+
+	return int(lastId), err
+}
+
+func (s *ServerService) findCreateByAddress(tx *sql.Tx, req AddServerRequest) (int, error) {
+	var id int
+
+	err := tx.QueryRow(`
+		SELECT id FROM server
+		WHERE address = ?
+		ORDER BY guid IS NULL DESC, id DESC
+		LIMIT 1`, req.Address,
+	).Scan(&id)
+
+	if err == nil {
+		return id, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO server (name, address) VALUES (?, ?)`,
+		req.Name, req.Address)
 	if err != nil {
 		return 0, err
 	}
 
-	return data.Id, err
+	lastId, err := res.LastInsertId()
+
+	return int(lastId), err
 }
 
-func (s *ServerService) GetByPattern(pattern string) ([]Server, error) {
-	sqlPattern := "%" + pattern + "%"
-	rows, err := s.db.Query(`
-		SELECT id, name, address FROM server 
-		WHERE (address LIKE ?) OR (name LIKE ?)
-		ORDER BY name`, sqlPattern, sqlPattern)
+func (s *ServerService) addNameHistory(tx *sql.Tx, id int, name string) error {
+	_, err := tx.Exec(`
+		INSERT INTO server_name_history (server_id, name) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE last_seen = CURRENT_TIMESTAMP`,
+		id, name)
+
+	return err
+}
+
+const serverFields = `
+	server.id, server.name, server.address,
+	server.display_name, server.region, server.description, server.discord_url,
+	(SELECT GROUP_CONCAT(tag ORDER BY tag) FROM server_tags WHERE server_id = server.id)`
+
+func scanServer(row interface{ Scan(...any) error }) (*Server, error) {
+	server := Server{
+		Tags: []string{},
+	}
+
+	var tags *string
+
+	err := row.Scan(
+		&server.Id, &server.Name, &server.Address,
+		&server.DisplayName, &server.Region, &server.Description, &server.DiscordUrl,
+		&tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tags != nil {
+		server.Tags = strings.Split(*tags, ",")
+	}
+
+	return &server, nil
+}
+
+func (s *ServerService) GetByPattern(req GetByPatternRequest) ([]*Server, error) {
+	sqlPattern := "%" + req.Pattern + "%"
+
+	conds := []string{"(server.address LIKE ? OR server.name LIKE ?)"}
+	args := []any{sqlPattern, sqlPattern}
+
+	if req.Region != "" {
+		conds = append(conds, "server.region = ?")
+		args = append(args, req.Region)
+	}
+
+	if len(req.Tags) > 0 {
+		placeholders := []string{}
+		for _, tag := range req.Tags {
+			placeholders = append(placeholders, "?")
+			args = append(args, tag)
+		}
+		args = append(args, len(req.Tags))
+
+		conds = append(conds, fmt.Sprintf(`server.id IN (
+			SELECT server_id FROM server_tags
+			WHERE tag IN (%v)
+			GROUP BY server_id
+			HAVING count(DISTINCT tag) = ?
+		)`, strings.Join(placeholders, ", ")))
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT %v FROM server 
+		WHERE %v
+		ORDER BY server.name`, serverFields, strings.Join(conds, " AND "),
+	), args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
-	results := []Server{}
+	results := []*Server{}
 
 	for rows.Next() {
-		server := Server{}
-
-		err := rows.Scan(&server.Id, &server.Name, &server.Address)
+		server, err := scanServer(rows)
 		if err != nil {
 			continue
 		}
@@ -80,34 +236,328 @@ func (s *ServerService) GetByPattern(pattern string) ([]Server, error) {
 }
 
 func (s *ServerService) GetById(id int) (*Server, error) {
-	row := s.db.QueryRow(`SELECT id, name, address FROM server WHERE id = ?`, id)
+	row := s.db.QueryRow(fmt.Sprintf(`SELECT %v FROM server WHERE id = ?`, serverFields), id)
+
+	return scanServer(row)
+}
 
-	server := Server{}
+// MySQL reports zero affected rows for unchanged values, so existence is checked separately
+func checkAffected(tx *sql.Tx, res sql.Result, id int) error {
+	if count, _ := res.RowsAffected(); count > 0 {
+		return nil
+	}
 
-	err := row.Scan(&server.Id, &server.Name, &server.Address)
+	var exists bool
+	err := tx.QueryRow(`SELECT count(*) > 0 FROM server WHERE id = ?`, id).Scan(&exists)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &server, nil
+	if !exists {
+		return fmt.Errorf("server %v not found", id)
+	}
+
+	return nil
 }
 
-func (s *ServerService) getByAddress(address string) (*Server, error) {
-	row := s.db.QueryRow(`SELECT id, name, address FROM server WHERE address = ?`, address)
+// Name provided by the mutator is kept in history only if the display name is set
+func (s *ServerService) UpdateName(data UpdateNameRequest) error {
+	defer cachestore.Invalidate(s.cache, cachestore.ServerTag(data.Id))
 
-	server := Server{}
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE server SET name = coalesce(display_name, ?) WHERE id = ?`,
+			data.Name, data.Id)
+		if err != nil {
+			return err
+		}
+
+		err = checkAffected(tx, res, data.Id)
+		if err != nil {
+			return err
+		}
+
+		return s.addNameHistory(tx, data.Id, data.Name)
+	})
+}
+
+func (s *ServerService) UpdateMetadata(id int, req UpdateMetadataRequest) error {
+	tags := []string{}
+	lookup := map[string]bool{}
+
+	for _, tag := range req.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || lookup[strings.ToLower(tag)] {
+			continue
+		}
+
+		if len(tag) > 32 || strings.Contains(tag, ",") {
+			return fmt.Errorf("invalid tag %v", tag)
+		}
+
+		lookup[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+
+	if req.DisplayName != nil && strings.TrimSpace(*req.DisplayName) == "" {
+		req.DisplayName = nil
+	}
+
+	defer cachestore.Invalidate(s.cache, cachestore.ServerTag(id))
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		// Fall back to the latest name provided by the mutator when display name is removed
+		res, err := tx.Exec(`
+			UPDATE server
+			SET display_name = ?,
+				name = coalesce(?, (
+					SELECT name FROM server_name_history
+					WHERE server_id = server.id
+					ORDER BY last_seen DESC
+					LIMIT 1
+				), name),
+				region = ?,
+				description = ?,
+				discord_url = ?
+			WHERE id = ?`,
+			req.DisplayName, req.DisplayName,
+			req.Region, req.Description, req.DiscordUrl,
+			id,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = checkAffected(tx, res, id)
+		if err != nil {
+			return err
+		}
 
-	err := row.Scan(&server.Id, &server.Name, &server.Address)
+		_, err = tx.Exec(`DELETE FROM server_tags WHERE server_id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			_, err := tx.Exec(`INSERT INTO server_tags (server_id, tag) VALUES (?, ?)`, id, tag)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *ServerService) GetNameHistory(id int) ([]*NameHistoryItem, error) {
+	rows, err := s.db.Query(`
+		SELECT name, first_seen, last_seen
+		FROM server_name_history
+		WHERE server_id = ?
+		ORDER BY last_seen DESC`, id,
+	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &server, nil
+	items := []*NameHistoryItem{}
+	for rows.Next() {
+		item := NameHistoryItem{}
+
+		err := rows.Scan(&item.Name, &item.FirstSeen, &item.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
 }
 
-func (s *ServerService) UpdateName(data UpdateNameRequest) error {
-	_, err := s.db.Exec(`UPDATE server SET name = ? WHERE id = ?`,
-		data.Name, data.Id)
+func (s *ServerService) GetAddressHistory(id int) ([]*AddressHistoryItem, error) {
+	rows, err := s.db.Query(`
+		SELECT address, first_seen, last_seen
+		FROM server_address_history
+		WHERE server_id = ?
+		ORDER BY last_seen DESC`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*AddressHistoryItem{}
+	for rows.Next() {
+		item := AddressHistoryItem{}
+
+		err := rows.Scan(&item.Address, &item.FirstSeen, &item.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// Moves sessions and address history of the source server to the target one,
+// deletes the source server and recalculates weekly stats of the target server.
+func (s *ServerService) Merge(req MergeServersRequest) error {
+	if req.SourceId == req.TargetId {
+		return fmt.Errorf("source and target servers are the same")
+	}
+
+	defer cachestore.Invalidate(s.cache,
+		cachestore.ServerTag(req.SourceId), cachestore.ServerTag(req.TargetId), cachestore.TagSessions,
+	)
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		var guid *string
+		err := tx.QueryRow(`SELECT guid FROM server WHERE id = ?`, req.SourceId).Scan(&guid)
+		if err != nil {
+			return fmt.Errorf("source server: %v", err)
+		}
+
+		var targetId int
+		err = tx.QueryRow(`SELECT id FROM server WHERE id = ?`, req.TargetId).Scan(&targetId)
+		if err != nil {
+			return fmt.Errorf("target server: %v", err)
+		}
+
+		stmts := []string{
+			`UPDATE session SET server_id = ? WHERE server_id = ?`,
+			`INSERT INTO server_address_history (server_id, address, first_seen, last_seen)
+				SELECT ?, address, first_seen, last_seen
+				FROM server_address_history
+				WHERE server_id = ?
+				ON DUPLICATE KEY UPDATE
+					first_seen = LEAST(first_seen, VALUES(first_seen)),
+					last_seen = GREATEST(last_seen, VALUES(last_seen))`,
+			`INSERT INTO server_name_history (server_id, name, first_seen, last_seen)
+				SELECT ?, name, first_seen, last_seen
+				FROM server_name_history
+				WHERE server_id = ?
+				ON DUPLICATE KEY UPDATE
+					first_seen = LEAST(first_seen, VALUES(first_seen)),
+					last_seen = GREATEST(last_seen, VALUES(last_seen))`,
+			`INSERT IGNORE INTO server_tags (server_id, tag)
+				SELECT ?, tag FROM server_tags WHERE server_id = ?`,
+			`UPDATE IGNORE organization_server SET server_id = ? WHERE server_id = ?`,
+			`INSERT IGNORE INTO server_heartbeat (server_id, minute)
+				SELECT ?, minute FROM server_heartbeat WHERE server_id = ?`,
+			`UPDATE session_aborted SET server_id = ? WHERE server_id = ?`,
+			`UPDATE server_alert SET server_id = ? WHERE server_id = ?`,
+			`INSERT IGNORE INTO users_role (user_id, role, server_id)
+				SELECT user_id, role, ? FROM users_role WHERE server_id = ?`,
+		}
+
+		for _, stmt := range stmts {
+			_, err := tx.Exec(stmt, req.TargetId, req.SourceId)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Scoped roles don't reference the server table, so they're cleaned up explicitly
+		_, err = tx.Exec(`DELETE FROM users_role WHERE server_id = ?`, req.SourceId)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM server WHERE id = ?`, req.SourceId)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE server SET guid = coalesce(guid, ?) WHERE id = ?`,
+			guid, req.TargetId)
+		if err != nil {
+			return err
+		}
+
+		return s.recalcWeeklyStats(tx, req.TargetId, 0)
+	})
+}
+
+// Rebuilds weekly user stats of the server, period is yearweek or 0 for all weeks
+func (s *ServerService) RecalcWeeklyStats(serverId, period int) error {
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		return s.recalcWeeklyStats(tx, serverId, period)
+	})
+}
+
+func (s *ServerService) recalcWeeklyStats(tx *sql.Tx, serverId, period int) error {
+	weeklyConds := []string{"server_id = ?"}
+	sessionConds := []string{"session.server_id = ?", "session.is_completed", "NOT session.is_excluded"}
+	args := []any{serverId}
+
+	if period != 0 {
+		weeklyConds = append(weeklyConds, "period = ?")
+		sessionConds = append(sessionConds, "yearweek(session.started_at) = ?")
+		args = append(args, period)
+	}
+
+	for _, table := range []string{"user_weekly_stats_total", "user_weekly_stats_perk"} {
+		_, err := tx.Exec(fmt.Sprintf(`DELETE FROM %v WHERE %v`,
+			table, strings.Join(weeklyConds, " AND ")), args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	sessionIds := []int{}
+	{
+		rows, err := tx.Query(fmt.Sprintf(`
+			SELECT id FROM session
+			WHERE %v
+			ORDER BY id`, strings.Join(sessionConds, " AND "),
+		), args...)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id int
+
+			err := rows.Scan(&id)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			sessionIds = append(sessionIds, id)
+		}
+		rows.Close()
+	}
+
+	for _, sessionId := range sessionIds {
+		_, err := tx.Exec(`CALL update_user_stats_weekly(?)`, sessionId)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Buffs uptime is filled by demo records after the session is aggregated
+	_, err := tx.Exec(fmt.Sprintf(`
+		UPDATE user_weekly_stats_perk weekly
+		INNER JOIN (
+			SELECT
+				yearweek(session.started_at) AS period,
+				aggr.user_id AS user_id,
+				aggr.perk AS perk,
+				sum(aggr.buffs_active_length) AS buffs_active_length,
+				sum(aggr.buffs_total_length) AS buffs_total_length
+			FROM session
+			INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+			WHERE %v
+			GROUP BY period, aggr.user_id, aggr.perk
+		) t ON weekly.period = t.period AND weekly.user_id = t.user_id AND weekly.perk = t.perk
+		SET weekly.buffs_active_length = t.buffs_active_length,
+			weekly.buffs_total_length = t.buffs_total_length
+		WHERE weekly.server_id = ?`, strings.Join(sessionConds, " AND "),
+	), append(args, serverId)...)
 
 	return err
 }
@@ -117,6 +567,7 @@ func (s *ServerService) GetRecentUsers(req RecentUsersRequest) (*RecentUsersResp
 
 	conds := []string{}
 	conds = append(conds, fmt.Sprintf("session.server_id = %v", req.ServerId))
+	conds = append(conds, users.NotHiddenCond("wsp.player_id", users.HideProfile, users.HideSocial))
 
 	sql := fmt.Sprintf(`
 		WITH ranking AS (
@@ -164,7 +615,7 @@ func (s *ServerService) GetRecentUsers(req RecentUsersRequest) (*RecentUsersResp
 	}
 
 	wspIds := []int{}
-	steamIdSet := make(map[string]bool)
+	authIdSet := make(map[string]models.AuthType)
 	items := []*RecentUsersResponseUser{}
 
 	var total int
@@ -182,9 +633,7 @@ func (s *ServerService) GetRecentUsers(req RecentUsersRequest) (*RecentUsersResp
 
 		wspIds = append(wspIds, item.WaveStatsPlayerId)
 
-		if profile.Type == models.Steam {
-			steamIdSet[profile.AuthId] = true
-		}
+		authIdSet[profile.AuthId] = profile.Type
 
 		item.UserProfile = &profile
 
@@ -192,20 +641,15 @@ func (s *ServerService) GetRecentUsers(req RecentUsersRequest) (*RecentUsersResp
 	}
 
 	{
-		steamIds := []string{}
-		for key := range steamIdSet {
-			steamIds = append(steamIds, key)
-		}
-
-		steamData, err := s.userService.GetSteamData(steamIds)
+		profileData, err := s.userService.GetProfileData(authIdSet)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, item := range items {
-			if data, ok := steamData[item.UserProfile.AuthId]; ok {
-				item.UserProfile.Avatar = &data.Avatar
-				item.UserProfile.ProfileUrl = &data.ProfileUrl
+			if data, ok := profileData[item.UserProfile.AuthId]; ok {
+				item.UserProfile.Avatar = data.Avatar
+				item.UserProfile.ProfileUrl = data.ProfileUrl
 			}
 		}
 	}
@@ -7,14 +7,23 @@ import (
 type AddServerRequest struct {
 	Name    string `json:"name" binding:"required"`
 	Address string `json:"address" binding:"required"`
+
+	// Stable identity of the server, survives address change
+	Guid *string `json:"guid"`
 }
 
 type AddServerResponse struct {
 	Id int `json:"id"`
 }
 
+type GetByPatternRequest struct {
+	Pattern string   `form:"pattern"`
+	Region  string   `form:"region"`
+	Tags    []string `form:"tags"`
+}
+
 type GetByPatternResponse struct {
-	Items []Server `json:"items"`
+	Items []*Server `json:"items"`
 }
 
 type UpdateNameRequest struct {
@@ -22,6 +31,29 @@ type UpdateNameRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
+type UpdateMetadataRequest struct {
+	// Overrides names provided by the mutator
+	DisplayName *string `json:"display_name"`
+
+	Region      *string  `json:"region"`
+	Description *string  `json:"description"`
+	DiscordUrl  *string  `json:"discord_url"`
+	Tags        []string `json:"tags"`
+}
+
+type GetNameHistoryResponse struct {
+	Items []*NameHistoryItem `json:"items"`
+}
+
+type MergeServersRequest struct {
+	SourceId int `json:"source_id" binding:"required"`
+	TargetId int `json:"target_id" binding:"required"`
+}
+
+type GetAddressHistoryResponse struct {
+	Items []*AddressHistoryItem `json:"items"`
+}
+
 type RecentUsersRequest struct {
 	ServerId int `json:"server_id" binding:"required"`
 
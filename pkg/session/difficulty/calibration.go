@@ -0,0 +1,388 @@
+package difficulty
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+const (
+	// Outcome weights of a single session hardness estimate
+	calibrationLossWeight     = 0.2
+	calibrationDeathWeight    = 0.1
+	calibrationDurationWeight = 0.1
+
+	// Buckets with less sessions or with a single map can't tell maps apart
+	calibrationMinBucketSessions = 10
+	// Factor of a map with N sessions is shrunk towards 1 by N / (N + prior)
+	calibrationPriorSessions = 30
+
+	calibrationMinFactor = 0.8
+	calibrationMaxFactor = 1.25
+
+	// Sessions of a map are recalculated only if its factor moved further than this
+	calibrationRecalcThreshold = 0.01
+)
+
+type calibrationSession struct {
+	MapId int
+
+	Mode        models.GameMode
+	Difficulty  models.GameDifficulty
+	Length      models.GameLength
+	MaxMonsters int
+	Players     int
+
+	IsWin       bool
+	DeathRate   float64
+	LogDuration *float64
+}
+
+// Comparable settings: sessions are only compared within the same bucket
+func (s *calibrationSession) bucket() string {
+	return fmt.Sprintf("%v/%v/%v/%v/%v",
+		s.Mode, s.Difficulty, s.Length, s.MaxMonsters/16, min(s.Players, 6),
+	)
+}
+
+type calibrationBucket struct {
+	sessions    int
+	losses      float64
+	deathRate   float64
+	logDuration float64
+	durations   int
+	maps        map[int]bool
+}
+
+type calibrationMap struct {
+	sessions    int
+	sum         float64
+	sumSquares  float64
+	wins        int
+	deathRate   float64
+	logDuration float64
+	durations   int
+}
+
+func (s *DifficultyCalculatorService) getCalibrationSessions() ([]*calibrationSession, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			session.map_id,
+			session.mode,
+			session.diff,
+			session.length,
+			COALESCE(extra.max_monsters, 0),
+			COUNT(DISTINCT wsp.player_id),
+			session.status = %v,
+			COUNT(CASE WHEN wsp.is_dead THEN 1 END) / COUNT(wsp.id),
+			(
+				SELECT avg(ln(d.duration / d.predicted_duration))
+				FROM wave_stats ws_d
+				INNER JOIN wave_stats_diff d ON d.stats_id = ws_d.id
+				WHERE ws_d.session_id = session.id AND d.duration > 15 AND d.predicted_duration > 0
+			)
+		FROM session
+		LEFT JOIN session_game_data_extra extra ON extra.session_id = session.id
+		INNER JOIN wave_stats ws ON ws.session_id = session.id AND ws.wave <= session.length
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		WHERE session.status IN (%v, %v) AND session.mode IN (%v, %v)
+			AND NOT session.is_excluded
+		GROUP BY session.id`,
+		models.Win, models.Win, models.Lose, models.Survival, models.ControlledDifficulty,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*calibrationSession{}
+	for rows.Next() {
+		item := calibrationSession{}
+
+		err := rows.Scan(
+			&item.MapId, &item.Mode, &item.Difficulty, &item.Length,
+			&item.MaxMonsters, &item.Players,
+			&item.IsWin, &item.DeathRate, &item.LogDuration,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// Estimates map difficulty factors from win rates, death rates and wave durations
+// relative to sessions with comparable settings on other maps
+func calibrate(sessions []*calibrationSession) map[int]*MapDifficulty {
+	buckets := map[string]*calibrationBucket{}
+
+	for _, item := range sessions {
+		key := item.bucket()
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &calibrationBucket{maps: map[int]bool{}}
+			buckets[key] = bucket
+		}
+
+		bucket.sessions += 1
+		bucket.deathRate += item.DeathRate
+		bucket.maps[item.MapId] = true
+		if !item.IsWin {
+			bucket.losses += 1
+		}
+		if item.LogDuration != nil {
+			bucket.logDuration += *item.LogDuration
+			bucket.durations += 1
+		}
+	}
+
+	maps := map[int]*calibrationMap{}
+
+	for _, item := range sessions {
+		bucket := buckets[item.bucket()]
+		if bucket.sessions < calibrationMinBucketSessions || len(bucket.maps) < 2 {
+			continue
+		}
+
+		loss := 0.0
+		if !item.IsWin {
+			loss = 1
+		}
+
+		avgLoss := bucket.losses / float64(bucket.sessions)
+		avgDeathRate := bucket.deathRate / float64(bucket.sessions)
+
+		hardness := 1 +
+			calibrationLossWeight*(loss-avgLoss) +
+			calibrationDeathWeight*clamp((item.DeathRate-avgDeathRate)/math.Max(avgDeathRate, 0.02), -1, 1)
+
+		if item.LogDuration != nil && bucket.durations > 0 {
+			avgLogDuration := bucket.logDuration / float64(bucket.durations)
+			hardness += calibrationDurationWeight * clamp(*item.LogDuration-avgLogDuration, -1, 1)
+		}
+
+		m, ok := maps[item.MapId]
+		if !ok {
+			m = &calibrationMap{}
+			maps[item.MapId] = m
+		}
+
+		m.sessions += 1
+		m.sum += hardness
+		m.sumSquares += hardness * hardness
+		m.deathRate += item.DeathRate
+		if item.IsWin {
+			m.wins += 1
+		}
+		if item.LogDuration != nil {
+			m.logDuration += *item.LogDuration
+			m.durations += 1
+		}
+	}
+
+	res := map[int]*MapDifficulty{}
+
+	for mapId, m := range maps {
+		n := float64(m.sessions)
+		mean := m.sum / n
+
+		stdErr := 0.0
+		if m.sessions > 1 {
+			variance := math.Max(0, (m.sumSquares-n*mean*mean)/(n-1))
+			stdErr = math.Sqrt(variance / n)
+		}
+
+		shrink := n / (n + calibrationPriorSessions)
+		factor := func(x float64) float64 {
+			return clamp(1+shrink*(x-1), calibrationMinFactor, calibrationMaxFactor)
+		}
+
+		item := MapDifficulty{
+			MapId:         mapId,
+			Factor:        factor(mean),
+			FactorLow:     factor(mean - 1.96*stdErr),
+			FactorHigh:    factor(mean + 1.96*stdErr),
+			Sessions:      m.sessions,
+			WinRate:       float64(m.wins) / n,
+			DeathRate:     m.deathRate / n,
+			DurationRatio: 1,
+		}
+
+		if m.durations > 0 {
+			item.DurationRatio = math.Exp(m.logDuration / float64(m.durations))
+		}
+
+		res[mapId] = &item
+	}
+
+	return res
+}
+
+func (s *DifficultyCalculatorService) getMapFactors() (map[int]float64, error) {
+	rows, err := s.db.Query(`SELECT map_id, factor FROM map_difficulty`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[int]float64{}
+	for rows.Next() {
+		var mapId int
+		var factor float64
+
+		err := rows.Scan(&mapId, &factor)
+		if err != nil {
+			return nil, err
+		}
+
+		res[mapId] = factor
+	}
+
+	return res, nil
+}
+
+// Recomputes map difficulty factors and recalculates sessions of maps whose factor changed
+func (s *DifficultyCalculatorService) CalibrateMaps() error {
+	if !s.calibrationMu.TryLock() {
+		return fmt.Errorf("calibration is already running")
+	}
+	defer s.calibrationMu.Unlock()
+
+	start := time.Now()
+
+	sessions, err := s.getCalibrationSessions()
+	if err != nil {
+		return err
+	}
+
+	items := calibrate(sessions)
+
+	prevFactors, err := s.getMapFactors()
+	if err != nil {
+		return err
+	}
+
+	changed := []int{}
+	for mapId, item := range items {
+		prev, ok := prevFactors[mapId]
+		if !ok {
+			prev = 1
+		}
+
+		if math.Abs(item.Factor-prev) > calibrationRecalcThreshold {
+			changed = append(changed, mapId)
+		}
+	}
+	for mapId, prev := range prevFactors {
+		if _, ok := items[mapId]; !ok && math.Abs(prev-1) > calibrationRecalcThreshold {
+			changed = append(changed, mapId)
+		}
+	}
+
+	err = util.Transact(s.db, func(tx *sql.Tx) error {
+		mapIds := []int{}
+		values := []string{}
+
+		for mapId, item := range items {
+			mapIds = append(mapIds, mapId)
+			values = append(values,
+				fmt.Sprintf("(%v, %v, %v, %v, %v, %v, %v, %v)",
+					mapId, item.Factor, item.FactorLow, item.FactorHigh, item.Sessions,
+					item.WinRate, item.DeathRate, item.DurationRatio,
+				),
+			)
+		}
+
+		if len(mapIds) == 0 {
+			_, err := tx.Exec(`DELETE FROM map_difficulty`)
+			return err
+		}
+
+		_, err := tx.Exec(fmt.Sprintf(`
+			DELETE FROM map_difficulty WHERE map_id NOT IN (%v)`,
+			util.IntArrayToString(mapIds, ","),
+		))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(fmt.Sprintf(`
+			INSERT INTO map_difficulty (
+				map_id, factor, factor_low, factor_high, sessions,
+				win_rate, death_rate, duration_ratio
+			)
+			VALUES %v
+			ON DUPLICATE KEY UPDATE
+				factor = VALUES(factor),
+				factor_low = VALUES(factor_low),
+				factor_high = VALUES(factor_high),
+				sessions = VALUES(sessions),
+				win_rate = VALUES(win_rate),
+				death_rate = VALUES(death_rate),
+				duration_ratio = VALUES(duration_ratio),
+				updated_at = CURRENT_TIMESTAMP
+			`, strings.Join(values, ","),
+		))
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, mapId := range changed {
+		err := s.RecalculateByMapId(mapId)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("[calibration] %v maps calibrated, %v recalculated in %v\n",
+		len(items), len(changed), time.Since(start))
+
+	return nil
+}
+
+func (s *DifficultyCalculatorService) GetMapDifficulties() ([]*MapDifficulty, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			md.map_id, COALESCE(maps.display_name, maps.name),
+			md.factor, md.factor_low, md.factor_high, md.sessions,
+			md.win_rate, md.death_rate, md.duration_ratio,
+			md.updated_at
+		FROM map_difficulty md
+		INNER JOIN maps ON maps.id = md.map_id
+		ORDER BY md.factor DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*MapDifficulty{}
+	for rows.Next() {
+		item := MapDifficulty{}
+
+		err := rows.Scan(
+			&item.MapId, &item.MapName,
+			&item.Factor, &item.FactorLow, &item.FactorHigh, &item.Sessions,
+			&item.WinRate, &item.DeathRate, &item.DurationRatio,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
@@ -5,7 +5,6 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/theggv/kf2-stats-backend/pkg/common/config"
 )
 
 type controller struct {
@@ -15,16 +14,10 @@ type controller struct {
 // @Summary Recalculate all session difficulties
 // @Tags 	Difficulty
 // @Produce json
-// @Param   key query 	string true "Api key"
+// @Param   key query 	string false "Api key, alternative to the admin role"
 // @Success 201
 // @Router /sessions/difficulty/server [post]
 func (c *controller) recalculateAll(ctx *gin.Context) {
-	key := ctx.Query("key")
-	if key != config.Instance.Token {
-		ctx.String(http.StatusUnauthorized, "Invalid api key")
-		return
-	}
-
 	err := c.service.RecalculateAll()
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
@@ -37,17 +30,11 @@ func (c *controller) recalculateAll(ctx *gin.Context) {
 // @Summary Recalculate session difficulties by server_id
 // @Tags 	Difficulty
 // @Produce json
-// @Param   key query 	string true "Api key"
+// @Param   key query 	string false "Api key, alternative to the admin role"
 // @Param   id path   	 	int true "Server id"
 // @Success 201
 // @Router /sessions/difficulty/server/{id} [post]
 func (c *controller) recalculateByServerId(ctx *gin.Context) {
-	key := ctx.Query("key")
-	if key != config.Instance.Token {
-		ctx.String(http.StatusUnauthorized, "Invalid api key")
-		return
-	}
-
 	id, err := strconv.Atoi(ctx.Params.ByName("id"))
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
@@ -118,3 +105,36 @@ func (c *controller) checkIfQueued(ctx *gin.Context) {
 	isInsideQueue := c.service.CheckIfQueued(id)
 	ctx.JSON(http.StatusOK, isInsideQueue)
 }
+
+// @Summary Get calibrated map difficulty factors
+// @Tags 	Difficulty
+// @Produce json
+// @Success 200 {object} 	GetMapDifficultiesResponse
+// @Router /sessions/difficulty/maps [get]
+func (c *controller) getMapDifficulties(ctx *gin.Context) {
+	items, err := c.service.GetMapDifficulties()
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetMapDifficultiesResponse{
+		Items: items,
+	})
+}
+
+// @Summary Recalibrate map difficulty factors and recalculate affected sessions
+// @Tags 	Difficulty
+// @Produce json
+// @Param   key query 	string false "Api key, alternative to the admin role"
+// @Success 201
+// @Router /sessions/difficulty/maps/calibrate [post]
+func (c *controller) calibrateMaps(ctx *gin.Context) {
+	err := c.service.CalibrateMaps()
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{})
+}
@@ -1,6 +1,10 @@
 package difficulty
 
-import "github.com/theggv/kf2-stats-backend/pkg/common/models"
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
 
 type DifficultyCalculatorGameScore struct {
 	AvgZedsDifficulty    float64 `json:"avg_zeds_difficulty"`
@@ -10,6 +14,7 @@ type DifficultyCalculatorGameScore struct {
 
 	CompletionPercent float64 `json:"completion_p"`
 	RestartsPenalty   float64 `json:"restarts_penalty"`
+	MapBonus          float64 `json:"map_bonus"`
 
 	PotentialScore float64 `json:"potential_score"`
 	FinalScore     float64 `json:"final_score"`
@@ -24,6 +29,8 @@ type DifficultyCalculatorGameSession struct {
 	Status     models.GameStatus     `json:"status"`
 	Length     models.GameLength     `json:"length"`
 	Difficulty models.GameDifficulty `json:"diff"`
+
+	MapBonus float64 `json:"map_bonus"`
 }
 
 type DifficultyCalculatorGameWaveScore struct {
@@ -80,3 +87,23 @@ func (d *DifficultyCalculatorGame) GetLastWave() (*DifficultyCalculatorGameWave,
 
 	return nil, false
 }
+
+type MapDifficulty struct {
+	MapId   int    `json:"map_id"`
+	MapName string `json:"map_name"`
+
+	Factor     float64 `json:"factor"`
+	FactorLow  float64 `json:"factor_low"`
+	FactorHigh float64 `json:"factor_high"`
+	Sessions   int     `json:"sessions"`
+
+	WinRate       float64 `json:"win_rate"`
+	DeathRate     float64 `json:"death_rate"`
+	DurationRatio float64 `json:"duration_ratio"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GetMapDifficultiesResponse struct {
+	Items []*MapDifficulty `json:"items"`
+}
@@ -2,6 +2,8 @@ package difficulty
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 )
 
 func RegisterRoutes(r *gin.RouterGroup, service *DifficultyCalculatorService) {
@@ -12,9 +14,13 @@ func RegisterRoutes(r *gin.RouterGroup, service *DifficultyCalculatorService) {
 	routes := r.Group("/sessions/difficulty")
 
 	routes.GET("/:id", controller.getById)
-	routes.POST("/:id", controller.addToQueue)
+	routes.POST("/:id", middleware.RoleMiddleWave(models.Moderator), controller.addToQueue)
 	routes.GET("/:id/check", controller.checkIfQueued)
 
-	routes.POST("/server", controller.recalculateAll)
-	routes.POST("/server/:id", controller.recalculateByServerId)
+	routes.POST("/server", middleware.RoleMiddleWave(models.SuperAdmin), controller.recalculateAll)
+	routes.POST("/server/:id",
+		middleware.ServerRoleMiddleWave("id", models.ServerAdmin), controller.recalculateByServerId)
+
+	routes.GET("/maps", controller.getMapDifficulties)
+	routes.POST("/maps/calibrate", middleware.RoleMiddleWave(models.SuperAdmin), controller.calibrateMaps)
 }
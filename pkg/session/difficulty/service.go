@@ -10,65 +10,42 @@ import (
 
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/jobs"
 )
 
 type DifficultyCalculatorService struct {
 	db *sql.DB
 
-	queue map[int]bool
-	mu    sync.Mutex
+	calibrationMu sync.Mutex
 }
 
 func NewDifficultyCalculator(db *sql.DB) *DifficultyCalculatorService {
 	service := DifficultyCalculatorService{
-		db:    db,
-		queue: map[int]bool{},
+		db: db,
 	}
 
-	go service.initQueue(30 * time.Second)
-
 	return &service
 }
 
+// Queued sessions are recalculated by the worker
 func (s *DifficultyCalculatorService) AddToQueue(sessionId int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.queue[sessionId] = true
+	if err := jobs.Enqueue(s.db, jobs.DifficultyJob, sessionId); err != nil {
+		fmt.Printf("[difficulty] session %v: %v\n", sessionId, err)
+	}
 }
 
 func (s *DifficultyCalculatorService) CheckIfQueued(sessionId int) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	_, exists := s.queue[sessionId]
+	exists, err := jobs.IsQueued(s.db, jobs.DifficultyJob, sessionId)
+	if err != nil {
+		fmt.Printf("[difficulty] session %v: %v\n", sessionId, err)
+	}
 
 	return exists
 }
 
-func (s *DifficultyCalculatorService) initQueue(updateTime time.Duration) {
-	for range time.Tick(updateTime) {
-		s.processQueue()
-	}
-}
-
-func (s *DifficultyCalculatorService) processQueue() {
-	items := []int{}
-
-	s.mu.Lock()
-	for item := range s.queue {
-		items = append(items, item)
-	}
-	clear(s.queue)
-	s.mu.Unlock()
-
-	if len(items) == 0 {
-		return
-	}
-
-	_, err := s.BatchRecalculate(items)
-	if err != nil {
-		fmt.Printf("[processQueue] %v\n", err)
-	}
+func (s *DifficultyCalculatorService) ProcessQueue(sessionIds []int) error {
+	_, err := s.BatchRecalculate(sessionIds)
+	return err
 }
 
 func (s *DifficultyCalculatorService) GetById(sessionId int) (*models.SessionMetadataDifficulty, error) {
@@ -232,9 +209,18 @@ func (s *DifficultyCalculatorService) RecalculateAll() error {
 }
 
 func (s *DifficultyCalculatorService) RecalculateByServerId(serverId int) error {
-	stmt := `SELECT count(*) FROM session WHERE server_id = ?`
+	return s.recalculateBy("server_id", serverId)
+}
+
+func (s *DifficultyCalculatorService) RecalculateByMapId(mapId int) error {
+	return s.recalculateBy("map_id", mapId)
+}
 
-	row := s.db.QueryRow(stmt, serverId)
+// Recalculates all sessions with session.<column> = id in batches
+func (s *DifficultyCalculatorService) recalculateBy(column string, id int) error {
+	stmt := fmt.Sprintf(`SELECT count(*) FROM session WHERE %v = ?`, column)
+
+	row := s.db.QueryRow(stmt, id)
 
 	var totalSessions int
 	err := row.Scan(&totalSessions)
@@ -245,9 +231,9 @@ func (s *DifficultyCalculatorService) RecalculateByServerId(serverId int) error
 	limit := 1000
 
 	for cursor := 0; cursor < totalSessions; cursor += limit {
-		stmt := `SELECT id FROM session WHERE server_id = ? LIMIT ?, ?`
+		stmt := fmt.Sprintf(`SELECT id FROM session WHERE %v = ? LIMIT ?, ?`, column)
 
-		rows, err := s.db.Query(stmt, serverId, cursor, limit)
+		rows, err := s.db.Query(stmt, id, cursor, limit)
 		if err != nil {
 			return err
 		}
@@ -372,10 +358,11 @@ func (s *DifficultyCalculatorService) updateItems(
 				}
 
 				values = append(values,
-					fmt.Sprintf("(%v, %v, %v, %v, %v, %v, %v, %v)",
+					fmt.Sprintf("(%v, %v, %v, %v, %v, %v, %v, %v, %v)",
 						item.Session.Id,
 						res.AvgZedsDifficulty, res.StdDevZedsDifficulty, res.MaxZedsDifficulty,
-						res.CompletionPercent, res.RestartsPenalty, res.PotentialScore, res.FinalScore,
+						res.CompletionPercent, res.RestartsPenalty, res.MapBonus,
+						res.PotentialScore, res.FinalScore,
 					),
 				)
 			}
@@ -383,7 +370,7 @@ func (s *DifficultyCalculatorService) updateItems(
 			stmt := fmt.Sprintf(`
 				INSERT INTO session_diff (
 					session_id, avg_zeds_diff, stddev_zeds_diff, max_zeds_diff,
-					completion_p, restarts_penalty, potential_score, final_score
+					completion_p, restarts_penalty, map_bonus, potential_score, final_score
 				) 
 				VALUES %v
 				ON DUPLICATE KEY UPDATE
@@ -392,6 +379,7 @@ func (s *DifficultyCalculatorService) updateItems(
 					max_zeds_diff = VALUES(max_zeds_diff),
 					completion_p = VALUES(completion_p),
 					restarts_penalty = VALUES(restarts_penalty),
+					map_bonus = VALUES(map_bonus),
 					potential_score = VALUES(potential_score),
 					final_score = VALUES(final_score)
 				`, strings.Join(values, ","),
@@ -499,7 +487,9 @@ func (s *DifficultyCalculatorService) processSession(data *DifficultyCalculatorG
 
 			res.RestartsPenalty = npInterp(float64(totalRestarts), pair{0, 3}, pair{1, 0})
 
-			res.FinalScore = res.PotentialScore * res.CompletionPercent * res.RestartsPenalty
+			res.MapBonus = session.MapBonus
+
+			res.FinalScore = res.PotentialScore * (1 + res.MapBonus) * res.CompletionPercent * res.RestartsPenalty
 		}
 
 		data.Result = &res
@@ -518,8 +508,10 @@ func (s *DifficultyCalculatorService) getSessions(sessionId []int) ([]*Difficult
 				session.length as game_length,
 				session.diff as game_difficulty,
 				session.mode as game_mode,
-				session.status as game_status
+				session.status as game_status,
+				COALESCE(md.factor, 1) - 1 as map_bonus
 			FROM session
+			LEFT JOIN map_difficulty md ON md.map_id = session.map_id
 			WHERE session.id IN (%v)
 			`, util.IntArrayToString(sessionId, ","),
 		)
@@ -535,6 +527,7 @@ func (s *DifficultyCalculatorService) getSessions(sessionId []int) ([]*Difficult
 			err := rows.Scan(
 				&item.Id, &item.ServerId, &item.MapId,
 				&item.Length, &item.Difficulty, &item.Mode, &item.Status,
+				&item.MapBonus,
 			)
 
 			if err != nil {
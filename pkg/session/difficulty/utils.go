@@ -16,3 +16,7 @@ func npInterp(x float64, xp, fp pair) float64 {
 
 	return fp.From*(1-t) + fp.To*t
 }
+
+func clamp(x, lo, hi float64) float64 {
+	return min(max(x, lo), hi)
+}
@@ -6,14 +6,20 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"time"
 
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
 	"github.com/theggv/kf2-stats-backend/pkg/common/demorecord"
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/jobs"
 	"github.com/theggv/kf2-stats-backend/pkg/maps"
 	"github.com/theggv/kf2-stats-backend/pkg/server"
+	"github.com/theggv/kf2-stats-backend/pkg/server/health"
 	"github.com/theggv/kf2-stats-backend/pkg/session/difficulty"
 	"github.com/theggv/kf2-stats-backend/pkg/users"
+	"github.com/theggv/kf2-stats-backend/pkg/users/achievements"
+	"github.com/theggv/kf2-stats-backend/pkg/users/records"
 )
 
 type SessionService struct {
@@ -23,6 +29,14 @@ type SessionService struct {
 	serverService *server.ServerService
 	usersService  *users.UserService
 	diffService   *difficulty.DifficultyCalculatorService
+
+	achievementsService *achievements.AchievementsService
+	recordsService      *records.RecordsService
+	healthService       *health.HealthService
+
+	cache cachestore.Invalidator
+	// Global aggregates are shared by every server, so they are dropped at most once a minute
+	aggregates *cachestore.Debouncer
 }
 
 func NewSessionService(db *sql.DB) *SessionService {
@@ -38,17 +52,27 @@ func (s *SessionService) Inject(
 	serverService *server.ServerService,
 	usersService *users.UserService,
 	diffService *difficulty.DifficultyCalculatorService,
+	achievementsService *achievements.AchievementsService,
+	recordsService *records.RecordsService,
+	healthService *health.HealthService,
+	cache cachestore.Invalidator,
 ) {
 	s.mapsService = mapsService
 	s.serverService = serverService
 	s.usersService = usersService
 	s.diffService = diffService
+	s.achievementsService = achievementsService
+	s.recordsService = recordsService
+	s.healthService = healthService
+	s.cache = cache
+	s.aggregates = cachestore.NewDebouncer(cache, time.Minute)
 }
 
 func (s *SessionService) Create(req CreateSessionRequest) (int, error) {
 	serverId, err := s.serverService.Create(server.AddServerRequest{
 		Name:    req.ServerName,
 		Address: req.ServerAddress,
+		Guid:    req.ServerGuid,
 	})
 
 	if err != nil {
@@ -83,6 +107,13 @@ func (s *SessionService) Create(req CreateSessionRequest) (int, error) {
 		return 0, err
 	}
 
+	err = s.healthService.Heartbeat(int(id))
+	if err != nil {
+		return 0, err
+	}
+
+	cachestore.Invalidate(s.cache, cachestore.ServerTag(serverId))
+
 	_, err = s.db.Exec(`INSERT INTO session_diff (session_id) VALUES (?)`, id)
 
 	return int(id), err
@@ -154,6 +185,23 @@ func (s *SessionService) GetExtraData(id int) (*models.ExtraGameData, error) {
 
 func (s *SessionService) UpdateStatus(data UpdateStatusRequest) error {
 	defer s.diffService.AddToQueue(data.Id)
+	defer s.achievementsService.AddToQueue(data.Id)
+	defer s.recordsService.AddToQueue(data.Id)
+
+	tags := []string{cachestore.SessionTag(data.Id)}
+
+	var serverId int
+	if err := s.db.QueryRow(`SELECT server_id FROM session WHERE id = ?`, data.Id).Scan(&serverId); err == nil {
+		tags = append(tags, cachestore.ServerTag(serverId))
+	}
+
+	if data.Status == models.Win ||
+		data.Status == models.Lose ||
+		data.Status == models.Aborted {
+		// Completed sessions are included into aggregates
+		cachestore.Invalidate(s.aggregates, cachestore.TagSessions)
+	}
+	defer cachestore.Invalidate(s.cache, tags...)
 
 	_, err := s.db.Exec(`
 		UPDATE session 
@@ -217,8 +265,11 @@ func (s *SessionService) UploadDemo(raw []byte) error {
 		(?, ?)`,
 		demo.Header.SessionId, b.Bytes(),
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return jobs.Enqueue(s.db, jobs.DemoJob, demo.Header.SessionId)
 }
 
 func (s *SessionService) GetDemo(id int) (*demorecord.DemoRecordRaw, error) {
@@ -282,6 +333,11 @@ func (s *SessionService) LoadDemoUsers(demo *demorecord.DemoRecordParsed) {
 }
 
 func (s *SessionService) UpdateGameData(data UpdateGameDataRequest) error {
+	err := s.healthService.Heartbeat(data.SessionId)
+	if err != nil {
+		return err
+	}
+
 	status, err := s.getStatus(data.SessionId)
 	if err != nil || (*status != models.InProgress && *status != models.Lobby) {
 		return err
@@ -5,9 +5,10 @@ import (
 )
 
 type CreateSessionRequest struct {
-	ServerName    string `json:"server_name" binding:"required"`
-	ServerAddress string `json:"server_address" binding:"required"`
-	MapName       string `json:"map_name" binding:"required"`
+	ServerName    string  `json:"server_name" binding:"required"`
+	ServerAddress string  `json:"server_address" binding:"required"`
+	ServerGuid    *string `json:"server_guid"`
+	MapName       string  `json:"map_name" binding:"required"`
 
 	Mode       models.GameMode       `json:"mode" binding:"required"`
 	Length     int                   `json:"length" binding:"required"`
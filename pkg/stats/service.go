@@ -4,23 +4,37 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/session/difficulty"
 	"github.com/theggv/kf2-stats-backend/pkg/users"
+	"github.com/theggv/kf2-stats-backend/pkg/users/achievements"
+	"github.com/theggv/kf2-stats-backend/pkg/users/records"
 )
 
 type StatsService struct {
 	db          *sql.DB
 	userService *users.UserService
 	diffService *difficulty.DifficultyCalculatorService
+
+	achievementsService *achievements.AchievementsService
+	recordsService      *records.RecordsService
+
+	cache cachestore.Invalidator
 }
 
 func (s *StatsService) Inject(
 	userService *users.UserService,
 	diffService *difficulty.DifficultyCalculatorService,
+	achievementsService *achievements.AchievementsService,
+	recordsService *records.RecordsService,
+	cache cachestore.Invalidator,
 ) {
 	s.userService = userService
 	s.diffService = diffService
+	s.achievementsService = achievementsService
+	s.recordsService = recordsService
+	s.cache = cache
 }
 
 func NewStatsService(db *sql.DB) *StatsService {
@@ -159,6 +173,9 @@ func (s *StatsService) createWaveStatsCD(statsId int, req *models.ExtraGameData)
 
 func (s *StatsService) CreateWaveStats(req CreateWaveStatsRequest) error {
 	defer s.diffService.AddToQueue(req.SessionId)
+	defer s.achievementsService.AddToQueue(req.SessionId)
+	defer s.recordsService.AddToQueue(req.SessionId)
+	defer cachestore.Invalidate(s.cache, cachestore.SessionTag(req.SessionId))
 
 	statsId, err := s.createWaveStats(&req)
 	if err != nil {
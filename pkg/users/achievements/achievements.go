@@ -0,0 +1,88 @@
+package achievements
+
+import "github.com/theggv/kf2-stats-backend/pkg/common/models"
+
+var officialMaps = []string{
+	"KF-Airship", "KF-AshwoodAsylum", "KF-Barmwich", "KF-BioticsLab",
+	"KF-BlackForest", "KF-BurningParis", "KF-CarillonHamlet", "KF-Catacombs",
+	"KF-ContainmentStation", "KF-Crash", "KF-DieSector", "KF-Dystopia2029",
+	"KF-Elysium", "KF-EvacuationPoint", "KF-Farmhouse", "KF-HellmarkStation",
+	"KF-HostileGrounds", "KF-InfernalRealm", "KF-KrampusLair", "KF-Lockdown",
+	"KF-MonsterBall", "KF-Moonbase", "KF-Netherhold", "KF-Nightmare",
+	"KF-Nuked", "KF-Outpost", "KF-Prison", "KF-Rig", "KF-Sanitarium",
+	"KF-SantasWorkshop", "KF-ShoppingSpree", "KF-Spillway", "KF-SteamFortress",
+	"KF-TheDescent", "KF-TragicKingdom", "KF-VolterManor", "KF-ZedLanding",
+}
+
+// Achievements are declared here and evaluated by rule type, see models.go
+var Achievements = []Achievement{
+	{
+		Id:          "first_win",
+		Title:       "Survivor",
+		Description: "Win any game",
+		Rule: Rule{
+			Type: SessionWin,
+		},
+	},
+	{
+		Id:          "hoe_long_win",
+		Title:       "Hell Walker",
+		Description: "Win Hell on Earth on long game length",
+		Rule: Rule{
+			Type:       SessionWin,
+			Length:     models.Long,
+			Difficulty: models.HellOnEarth,
+		},
+	},
+	{
+		Id:          "hoe_long_all_official_maps",
+		Title:       "Tour of Hell",
+		Description: "Win Hell on Earth on long game length on every official map",
+		Rule: Rule{
+			Type:       MapsWin,
+			Length:     models.Long,
+			Difficulty: models.HellOnEarth,
+			Maps:       officialMaps,
+		},
+	},
+	{
+		Id:          "sharpshooter_scrakes",
+		Title:       "Chainsaw Massacre",
+		Description: "Kill 10 scrakes in one wave as Sharpshooter",
+		Rule: Rule{
+			Type:  WaveKills,
+			Perk:  models.Sharpshooter,
+			Zed:   "scrake",
+			Count: 10,
+		},
+	},
+	{
+		Id:          "fleshpounds",
+		Title:       "Pound for Pound",
+		Description: "Kill 10 fleshpounds in one wave",
+		Rule: Rule{
+			Type:  WaveKills,
+			Zed:   "fp",
+			Count: 10,
+		},
+	},
+	{
+		Id:          "quarter_pounds",
+		Title:       "Fast Food",
+		Description: "Kill 20 quarter pounds in one wave",
+		Rule: Rule{
+			Type:  WaveKills,
+			Zed:   "qp",
+			Count: 20,
+		},
+	},
+	{
+		Id:          "perfect_buffs",
+		Title:       "Guardian Angel",
+		Description: "Keep team buffs active for the whole wave as Medic",
+		Rule: Rule{
+			Type: WaveBuffsUptime,
+			Perk: models.Medic,
+		},
+	},
+}
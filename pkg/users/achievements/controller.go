@@ -0,0 +1,79 @@
+package achievements
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+)
+
+type controller struct {
+	service *AchievementsService
+}
+
+// @Summary Get all achievements with rarity
+// @Tags 	Achievements
+// @Produce json
+// @Success 200 {object} GetAchievementsResponse
+// @Router /achievements [get]
+func (c *controller) getAll(ctx *gin.Context) {
+	items, err := c.service.getAll()
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetAchievementsResponse{
+		Items: items,
+	})
+}
+
+// @Summary Get user achievements
+// @Tags 	Achievements
+// @Produce json
+// @Param   id path   	 	int true "User id"
+// @Success 200 {object} 	GetUserAchievementsResponse
+// @Router /users/{id}/achievements [get]
+func (c *controller) getByUserId(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := util.GetUserFromCtx(ctx)
+	if err := c.service.userService.CheckPrivacy(user, id, users.HideProfile); err == users.ErrPrivateProfile {
+		ctx.String(http.StatusForbidden, err.Error())
+		return
+	} else if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.getByUserId(id)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// @Summary Add session to achievements evaluation queue
+// @Tags 	Achievements
+// @Produce json
+// @Param   id path   	 	int true "Session id"
+// @Success 201
+// @Router /achievements/session/{id} [post]
+func (c *controller) addToQueue(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.service.AddToQueue(id)
+	ctx.JSON(http.StatusCreated, gin.H{})
+}
@@ -0,0 +1,54 @@
+package achievements
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type RuleType = int
+
+const (
+	// Kill at least Count zeds of type Zed in a single wave
+	WaveKills RuleType = iota + 1
+	// Win a session matching mode, length and difficulty
+	SessionWin
+	// Win a session matching length and difficulty on every map from Maps
+	MapsWin
+	// Keep team buffs active for the whole wave as medic (evaluated from demo records)
+	WaveBuffsUptime
+)
+
+type Rule struct {
+	Type RuleType
+
+	// Zero values mean "any"
+	Perk       models.Perk
+	Mode       models.GameMode
+	Length     models.GameLength
+	Difficulty models.GameDifficulty
+
+	// Column of wave_stats_player_kills
+	Zed   string
+	Count int
+
+	Maps []string
+}
+
+type Achievement struct {
+	Id          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+
+	Rule Rule `json:"-"`
+}
+
+type UserAchievement struct {
+	Achievement
+
+	Rarity float64 `json:"rarity"`
+
+	Unlocked   bool       `json:"unlocked"`
+	UnlockedAt *time.Time `json:"unlocked_at"`
+	SessionId  *int       `json:"session_id"`
+}
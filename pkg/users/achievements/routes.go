@@ -0,0 +1,19 @@
+package achievements
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, service *AchievementsService) {
+	controller := controller{
+		service: service,
+	}
+
+	r.GET("/users/:id/achievements", middleware.OptionalAuthMiddleWave, controller.getByUserId)
+
+	routes := r.Group("/achievements")
+
+	routes.GET("/", controller.getAll)
+	routes.POST("/session/:id", middleware.MutatorAuthMiddleWave, controller.addToQueue)
+}
@@ -0,0 +1,391 @@
+package achievements
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/demorecord"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/jobs"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+)
+
+var zedColumns = map[string]bool{
+	"cyst": true, "alpha_clot": true, "slasher": true, "stalker": true,
+	"crawler": true, "gorefast": true, "rioter": true, "elite_crawler": true,
+	"gorefiend": true, "siren": true, "bloat": true, "edar": true,
+	"husk_n": true, "husk_b": true, "husk_r": true,
+	"scrake": true, "fp": true, "qp": true, "boss": true, "custom": true,
+}
+
+type AchievementsService struct {
+	db *sql.DB
+
+	userService *users.UserService
+}
+
+func NewAchievementsService(db *sql.DB) *AchievementsService {
+	service := AchievementsService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *AchievementsService) Inject(userService *users.UserService) {
+	s.userService = userService
+}
+
+// Queued sessions are evaluated by the worker
+func (s *AchievementsService) AddToQueue(sessionId int) {
+	if err := jobs.Enqueue(s.db, jobs.AchievementsJob, sessionId); err != nil {
+		fmt.Printf("[achievements] session %v: %v\n", sessionId, err)
+	}
+}
+
+// Failed sessions are logged and not retried, the same rules fail the same way
+func (s *AchievementsService) ProcessQueue(sessionIds []int) error {
+	for _, sessionId := range sessionIds {
+		err := s.EvaluateSession(sessionId)
+		if err != nil {
+			fmt.Printf("[achievements] session %v: %v\n", sessionId, err)
+		}
+	}
+
+	return nil
+}
+
+// Evaluates all stats based rules for players of the session
+func (s *AchievementsService) EvaluateSession(sessionId int) error {
+	for _, item := range Achievements {
+		var userIds []int
+		var err error
+
+		switch item.Rule.Type {
+		case WaveKills:
+			userIds, err = s.evalWaveKills(sessionId, &item.Rule)
+		case SessionWin:
+			userIds, err = s.evalSessionWin(sessionId, &item.Rule)
+		case MapsWin:
+			userIds, err = s.evalMapsWin(sessionId, &item.Rule)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("%v: %v", item.Id, err)
+		}
+
+		err = s.unlock(item.Id, sessionId, userIds)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Evaluates demo record based rules
+func (s *AchievementsService) EvaluateDemo(analysis *demorecord.DemoRecordAnalysis) error {
+	for _, item := range Achievements {
+		if item.Rule.Type != WaveBuffsUptime {
+			continue
+		}
+
+		userIds := s.evalWaveBuffsUptime(analysis, &item.Rule)
+
+		err := s.unlock(item.Id, analysis.SessionId, userIds)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *AchievementsService) unlock(achievementId string, sessionId int, userIds []int) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	values := []string{}
+	args := []any{}
+
+	for _, userId := range userIds {
+		values = append(values, "(?, ?, ?)")
+		args = append(args, userId, achievementId, sessionId)
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf(`
+		INSERT IGNORE INTO user_achievements (user_id, achievement_id, session_id)
+		VALUES %v`, strings.Join(values, ", "),
+	), args...)
+
+	return err
+}
+
+func (s *AchievementsService) queryUserIds(query string, args ...any) ([]int, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []int{}
+	for rows.Next() {
+		var userId int
+
+		err := rows.Scan(&userId)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, userId)
+	}
+
+	return items, nil
+}
+
+func (s *AchievementsService) evalWaveKills(sessionId int, rule *Rule) ([]int, error) {
+	if !zedColumns[rule.Zed] {
+		return nil, fmt.Errorf("unknown zed column %v", rule.Zed)
+	}
+
+	conds := []string{"ws.session_id = ?", fmt.Sprintf("kills.%v >= ?", rule.Zed)}
+	args := []any{sessionId, rule.Count}
+
+	if rule.Perk > 0 {
+		conds = append(conds, "wsp.perk = ?")
+		args = append(args, rule.Perk)
+	}
+
+	return s.queryUserIds(fmt.Sprintf(`
+		SELECT DISTINCT wsp.player_id
+		FROM wave_stats ws
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		INNER JOIN wave_stats_player_kills kills ON kills.player_stats_id = wsp.id
+		WHERE %v`, strings.Join(conds, " AND "),
+	), args...)
+}
+
+func (s *AchievementsService) evalSessionWin(sessionId int, rule *Rule) ([]int, error) {
+	conds := []string{"session.id = ?", fmt.Sprintf("session.status = %v", models.Win)}
+	args := []any{sessionId}
+
+	if rule.Mode > 0 {
+		conds = append(conds, "session.mode = ?")
+		args = append(args, rule.Mode)
+	}
+
+	if rule.Length != 0 {
+		conds = append(conds, "session.length = ?")
+		args = append(args, rule.Length)
+	}
+
+	if rule.Difficulty > 0 {
+		conds = append(conds, "session.diff = ?")
+		args = append(args, rule.Difficulty)
+	}
+
+	if rule.Perk > 0 {
+		conds = append(conds, "wsp.perk = ?")
+		args = append(args, rule.Perk)
+	}
+
+	return s.queryUserIds(fmt.Sprintf(`
+		SELECT DISTINCT wsp.player_id
+		FROM session
+		INNER JOIN wave_stats ws ON ws.session_id = session.id
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		WHERE %v`, strings.Join(conds, " AND "),
+	), args...)
+}
+
+func (s *AchievementsService) evalMapsWin(sessionId int, rule *Rule) ([]int, error) {
+	if len(rule.Maps) == 0 {
+		return nil, nil
+	}
+
+	conds := []string{fmt.Sprintf("session.status = %v", models.Win)}
+	args := []any{sessionId}
+
+	if rule.Length != 0 {
+		conds = append(conds, "session.length = ?")
+		args = append(args, rule.Length)
+	}
+
+	if rule.Difficulty > 0 {
+		conds = append(conds, "session.diff = ?")
+		args = append(args, rule.Difficulty)
+	}
+
+	placeholders := []string{}
+	for _, name := range rule.Maps {
+		placeholders = append(placeholders, "?")
+		args = append(args, name)
+	}
+	conds = append(conds, fmt.Sprintf("maps.name IN (%v)", strings.Join(placeholders, ", ")))
+
+	args = append(args, len(rule.Maps))
+
+	return s.queryUserIds(fmt.Sprintf(`
+		WITH players AS (
+			SELECT DISTINCT wsp.player_id
+			FROM wave_stats ws
+			INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+			WHERE ws.session_id = ?
+		)
+		SELECT wsp.player_id
+		FROM session
+		INNER JOIN maps ON maps.id = session.map_id
+		INNER JOIN wave_stats ws ON ws.session_id = session.id
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		INNER JOIN players ON players.player_id = wsp.player_id
+		WHERE %v
+		GROUP BY wsp.player_id
+		HAVING COUNT(DISTINCT maps.name) >= ?`, strings.Join(conds, " AND "),
+	), args...)
+}
+
+func (s *AchievementsService) evalWaveBuffsUptime(
+	analysis *demorecord.DemoRecordAnalysis, rule *Rule,
+) []int {
+	unlocked := map[int]bool{}
+
+	for _, wave := range analysis.Waves {
+		buffs := wave.Analytics.BuffsUptime
+		if buffs.TotalTicks <= 0 || buffs.BuffedTicks < buffs.TotalTicks {
+			continue
+		}
+
+		died := map[int]bool{}
+		for _, item := range wave.PlayerEvents.Deaths {
+			died[item.UserId] = true
+		}
+
+		for _, item := range wave.PlayerEvents.Perks {
+			if rule.Perk > 0 && item.Perk != rule.Perk || died[item.UserId] {
+				continue
+			}
+
+			if profile := analysis.Players.GetByIndex(item.UserId); profile != nil {
+				unlocked[profile.Id] = true
+			}
+		}
+	}
+
+	items := []int{}
+	for userId := range unlocked {
+		items = append(items, userId)
+	}
+
+	return items
+}
+
+func (s *AchievementsService) getRarity() (map[string]float64, error) {
+	var totalUsers int
+	{
+		row := s.db.QueryRow(`SELECT COUNT(*) FROM users`)
+		err := row.Scan(&totalUsers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT achievement_id, COUNT(*)
+		FROM user_achievements
+		GROUP BY achievement_id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := map[string]float64{}
+	for rows.Next() {
+		var id string
+		var count int
+
+		err := rows.Scan(&id, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		if totalUsers > 0 {
+			items[id] = 100 * float64(count) / float64(totalUsers)
+		}
+	}
+
+	return items, nil
+}
+
+func (s *AchievementsService) getAll() ([]*UserAchievement, error) {
+	rarity, err := s.getRarity()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*UserAchievement{}
+	for _, item := range Achievements {
+		items = append(items, &UserAchievement{
+			Achievement: item,
+			Rarity:      rarity[item.Id],
+		})
+	}
+
+	return items, nil
+}
+
+func (s *AchievementsService) getByUserId(userId int) (*GetUserAchievementsResponse, error) {
+	items, err := s.getAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT achievement_id, unlocked_at, session_id
+		FROM user_achievements
+		WHERE user_id = ?`, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type unlock struct {
+		at        time.Time
+		sessionId *int
+	}
+
+	unlocks := map[string]*unlock{}
+	for rows.Next() {
+		var id string
+		item := unlock{}
+
+		err := rows.Scan(&id, &item.at, &item.sessionId)
+		if err != nil {
+			return nil, err
+		}
+
+		unlocks[id] = &item
+	}
+
+	res := GetUserAchievementsResponse{
+		Total: len(items),
+		Items: items,
+	}
+
+	for _, item := range items {
+		if data, ok := unlocks[item.Id]; ok {
+			item.Unlocked = true
+			item.UnlockedAt = &data.at
+			item.SessionId = data.sessionId
+			res.Unlocked += 1
+		}
+	}
+
+	return &res, nil
+}
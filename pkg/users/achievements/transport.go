@@ -0,0 +1,12 @@
+package achievements
+
+type GetAchievementsResponse struct {
+	Items []*UserAchievement `json:"items"`
+}
+
+type GetUserAchievementsResponse struct {
+	Total    int `json:"total"`
+	Unlocked int `json:"unlocked"`
+
+	Items []*UserAchievement `json:"items"`
+}
@@ -0,0 +1,34 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/config"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+// Erased users keep only a hash of their account id, so bans can be restored
+// if the same account plays again without storing the account itself.
+// Account ids are guessable, so the hash is keyed with a server secret.
+func ErasedAuthHash(authId string, authType models.AuthType) string {
+	mac := hmac.New(sha256.New, []byte(config.Instance.ErasedBanSecretKey))
+	mac.Write([]byte(fmt.Sprintf("%v:%v", authType, authId)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Restores active ban of the erased account for the newly created user
+func (s *UserService) restoreErasedBan(userId int, authId string, authType models.AuthType) error {
+	_, err := s.db.Exec(`
+		INSERT INTO users_ban (user_id, reason, expires_at)
+		SELECT ?, reason, expires_at
+		FROM users_ban_erased
+		WHERE auth_hash = ? AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`,
+		userId, ErasedAuthHash(authId, authType),
+	)
+
+	return err
+}
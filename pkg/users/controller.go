@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
 )
 
 type userController struct {
@@ -70,6 +71,15 @@ func (c *userController) getUserDetailed(ctx *gin.Context) {
 		return
 	}
 
+	user, _ := util.GetUserFromCtx(ctx)
+	if err := c.service.CheckPrivacy(user, id, HideProfile); err == ErrPrivateProfile {
+		ctx.String(http.StatusForbidden, err.Error())
+		return
+	} else if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	item, err := c.service.getUserDetailed(id)
 	if err != nil {
 		ctx.String(http.StatusNotFound, err.Error())
@@ -78,3 +88,146 @@ func (c *userController) getUserDetailed(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, item)
 }
+
+// @Summary Create code to link another account of the player
+// @Tags 	Users
+// @Produce json
+// @Success 201 {object} CreateLinkCodeResponse
+// @Router /users/link/code [post]
+func (c *userController) createLinkCode(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	res, err := c.service.CreateLinkCode(user.UserId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// @Summary Link account which typed the code in game
+// @Tags 	Users
+// @Produce json
+// @Param   body body    VerifyLinkCodeRequest true "Body"
+// @Success 201
+// @Router /users/link/verify [post]
+func (c *userController) verifyLinkCode(ctx *gin.Context) {
+	var req VerifyLinkCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := c.service.VerifyLinkCode(req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{})
+}
+
+// @Summary Get linked accounts of the current user
+// @Tags 	Users
+// @Produce json
+// @Success 200 {object} GetLinkedAccountsResponse
+// @Router /users/link [get]
+func (c *userController) getLinkedAccounts(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	items, err := c.service.GetLinkedAccounts(user.UserId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetLinkedAccountsResponse{
+		Items: items,
+	})
+}
+
+// @Summary Unlink account
+// @Tags 	Users
+// @Produce json
+// @Param   userId path int true "Linked user id"
+// @Success 200
+// @Router /users/link/{userId} [delete]
+func (c *userController) unlink(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	userId, err := strconv.Atoi(ctx.Params.ByName("userId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.service.Unlink(user.UserId, userId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Get privacy settings of the current user
+// @Tags 	Users
+// @Produce json
+// @Success 200 {object} PrivacySettings
+// @Router /users/privacy [get]
+func (c *userController) getPrivacySettings(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	res, err := c.service.GetPrivacySettings(user.UserId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// @Summary Update privacy settings of the current user
+// @Tags 	Users
+// @Produce json
+// @Param   body body    PrivacySettings true "Body"
+// @Success 200
+// @Router /users/privacy [put]
+func (c *userController) updatePrivacySettings(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	var req PrivacySettings
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := c.service.UpdatePrivacySettings(user.UserId, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
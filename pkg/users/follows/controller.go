@@ -0,0 +1,139 @@
+package follows
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+type controller struct {
+	service *FollowsService
+}
+
+// @Summary Get players followed by the current user
+// @Tags 	Users
+// @Produce json
+// @Success 200 {object} GetFollowsResponse
+// @Router /users/follows [get]
+func (c *controller) getFollows(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	items, err := c.service.GetFollows(user.UserId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, GetFollowsResponse{
+		Items: items,
+	})
+}
+
+// @Summary Follow player
+// @Tags 	Users
+// @Produce json
+// @Param   body body 	FollowRequest true "Body"
+// @Success 201
+// @Router /users/follows [post]
+func (c *controller) follow(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	var req FollowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := c.service.Follow(user, req.UserId); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, nil)
+}
+
+// @Summary Unfollow player
+// @Tags 	Users
+// @Produce json
+// @Param   userId path int true "User id"
+// @Success 200
+// @Router /users/follows/{userId} [delete]
+func (c *controller) unfollow(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	userId, err := strconv.Atoi(ctx.Params.ByName("userId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := c.service.Unfollow(user.UserId, userId); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Follow Steam friends of the current user
+// @Tags 	Users
+// @Produce json
+// @Success 201 {object} ImportSteamFriendsResponse
+// @Router /users/follows/steam [post]
+func (c *controller) importSteamFriends(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	res, err := c.service.ImportSteamFriends(user)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// @Summary Get feed of followed players
+// @Tags 	Users
+// @Produce json
+// @Param   body body 		FeedRequest true "Body"
+// @Success 201 {object} 	FeedResponse
+// @Router /users/feed [post]
+func (c *controller) getFeed(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	var req FeedRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.GetFeed(user.UserId, req)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
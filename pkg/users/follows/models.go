@@ -0,0 +1,38 @@
+package follows
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type HighlightType = string
+
+const (
+	AchievementUnlocked HighlightType = "achievement"
+	RecordSet           HighlightType = "record"
+)
+
+// Highlights older than that are not shown in the feed
+const highlightsPeriod = 7 * 24 * time.Hour
+
+const maxFollows = 500
+
+type FeedHighlight struct {
+	Type HighlightType       `json:"type"`
+	User *models.UserProfile `json:"user"`
+
+	SessionId *int      `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Set for unlocked achievements
+	AchievementId    *string `json:"achievement_id,omitempty"`
+	AchievementTitle *string `json:"achievement_title,omitempty"`
+
+	// Set for personal records
+	RecordType *int         `json:"record_type,omitempty"`
+	Perk       *models.Perk `json:"perk,omitempty"`
+	Value      *float64     `json:"value,omitempty"`
+
+	userId int
+}
@@ -0,0 +1,21 @@
+package follows
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, service *FollowsService) {
+	controller := controller{
+		service: service,
+	}
+
+	routes := r.Group("/users", middleware.AuthMiddleWave)
+
+	routes.GET("/follows", controller.getFollows)
+	routes.POST("/follows", controller.follow)
+	routes.POST("/follows/steam", controller.importSteamFriends)
+	routes.DELETE("/follows/:userId", controller.unfollow)
+
+	routes.POST("/feed", controller.getFeed)
+}
@@ -0,0 +1,460 @@
+package follows
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/steamapi"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/matches/filter"
+	"github.com/theggv/kf2-stats-backend/pkg/moderation"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+	"github.com/theggv/kf2-stats-backend/pkg/users/achievements"
+)
+
+type FollowsService struct {
+	db *sql.DB
+
+	userService   *users.UserService
+	filterService *filter.MatchesFilterService
+	friendsApi    steamapi.FriendsApi
+}
+
+func NewFollowsService(db *sql.DB) *FollowsService {
+	service := FollowsService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *FollowsService) Inject(
+	userService *users.UserService,
+	filterService *filter.MatchesFilterService,
+	friendsApi steamapi.FriendsApi,
+) {
+	s.userService = userService
+	s.filterService = filterService
+	s.friendsApi = friendsApi
+}
+
+func (s *FollowsService) countFollows(userId int) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT count(*) FROM users_follow WHERE user_id = ?`, userId).Scan(&count)
+
+	return count, err
+}
+
+func (s *FollowsService) Follow(user *models.TokenPayload, followedUserId int) error {
+	if user.UserId == followedUserId {
+		return errors.New("you can't follow yourself")
+	}
+
+	if _, err := s.userService.GetById(followedUserId); err != nil {
+		return fmt.Errorf("user %v not found", followedUserId)
+	}
+
+	if err := s.userService.CheckPrivacy(user, followedUserId, users.HideProfile); err != nil {
+		return err
+	}
+
+	count, err := s.countFollows(user.UserId)
+	if err != nil {
+		return err
+	}
+
+	if count >= maxFollows {
+		return fmt.Errorf("you can't follow more than %v players", maxFollows)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT IGNORE INTO users_follow (user_id, followed_user_id) VALUES (?, ?)`,
+		user.UserId, followedUserId,
+	)
+
+	return err
+}
+
+func (s *FollowsService) Unfollow(userId, followedUserId int) error {
+	res, err := s.db.Exec(`
+		DELETE FROM users_follow WHERE user_id = ? AND followed_user_id = ?`,
+		userId, followedUserId,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("you don't follow user %v", followedUserId)
+	}
+
+	return nil
+}
+
+func (s *FollowsService) GetFollows(userId int) ([]*FollowedUser, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			users.id, users.name, users.auth_type, users.auth_id,
+			activity.current_session_id, follow.created_at
+		FROM users_follow follow
+		INNER JOIN users ON users.id = follow.followed_user_id
+		LEFT JOIN users_activity activity ON activity.user_id = users.id
+		WHERE follow.user_id = ?
+		ORDER BY follow.created_at DESC`, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*FollowedUser{}
+	authIdSet := make(map[string]models.AuthType)
+	for rows.Next() {
+		item := FollowedUser{}
+
+		err := rows.Scan(
+			&item.Id, &item.Name, &item.Type, &item.AuthId,
+			&item.CurrentSessionId, &item.FollowedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		authIdSet[item.AuthId] = item.Type
+
+		items = append(items, &item)
+	}
+
+	profileData, err := s.userService.GetProfileData(authIdSet)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if data, ok := profileData[item.AuthId]; ok {
+			item.Avatar = data.Avatar
+			item.ProfileUrl = data.ProfileUrl
+		}
+	}
+
+	return items, nil
+}
+
+// Follows every Steam friend who has played on tracked servers
+func (s *FollowsService) ImportSteamFriends(user *models.TokenPayload) (*ImportSteamFriendsResponse, error) {
+	if user.SteamId == "" {
+		return nil, errors.New("only steam accounts can import friends")
+	}
+
+	friends, err := s.friendsApi.GetFriendList(user.SteamId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := ImportSteamFriendsResponse{}
+	if len(friends) == 0 {
+		return &res, nil
+	}
+
+	count, err := s.countFollows(user.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	placeholders := []string{}
+	args := []any{user.UserId, models.Steam}
+	for _, steamId := range friends {
+		placeholders = append(placeholders, "?")
+		args = append(args, steamId)
+	}
+	args = append(args, user.UserId, max(maxFollows-count, 0))
+
+	result, err := s.db.Exec(fmt.Sprintf(`
+		INSERT IGNORE INTO users_follow (user_id, followed_user_id)
+		SELECT ?, users.id
+		FROM users
+		WHERE users.auth_type = ? AND users.auth_id IN (%v) AND %v AND users.id NOT IN (
+			SELECT followed_user_id FROM users_follow WHERE user_id = ?
+		)
+		LIMIT ?`,
+		strings.Join(placeholders, ","), users.NotHiddenCond("users.id", users.HideProfile),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Added = int(added)
+
+	return &res, nil
+}
+
+// Followed users which didn't enable any of the privacy flags
+func (s *FollowsService) getFeedUserIds(userId int, flags ...users.PrivacyFlag) ([]int, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT followed_user_id
+		FROM users_follow
+		WHERE user_id = ? AND %v`,
+		users.NotHiddenCond("followed_user_id", flags...),
+	), userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []int{}
+	for rows.Next() {
+		var item int
+		if err := rows.Scan(&item); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (s *FollowsService) getLiveSessions(userIds []int) ([]*FeedLiveSession, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			activity.user_id,
+			session.id, session.server_id, session.map_id,
+			session.mode, session.length, session.diff,
+			session.status, session.created_at, session.updated_at,
+			session.started_at, session.completed_at
+		FROM users_activity activity
+		INNER JOIN session ON session.id = activity.current_session_id
+		WHERE activity.user_id IN (%v) AND session.status IN (%v, %v) AND %v
+		ORDER BY session.updated_at DESC`,
+		util.IntArrayToString(userIds, ","), models.Lobby, models.InProgress,
+		moderation.NotExcludedCond,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*FeedLiveSession{}
+	matches := []*models.Match{}
+	sessionUsers := map[int][]int{}
+	userIdSet := map[int]bool{}
+
+	for rows.Next() {
+		var userId int
+		session := models.MatchSession{}
+
+		err := rows.Scan(
+			&userId,
+			&session.Id, &session.ServerId, &session.MapId,
+			&session.Mode, &session.Length, &session.Difficulty,
+			&session.Status, &session.CreatedAt, &session.UpdatedAt,
+			&session.StartedAt, &session.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := sessionUsers[session.Id]; !ok {
+			match := models.Match{Session: session}
+			matches = append(matches, &match)
+			items = append(items, &FeedLiveSession{Match: &match})
+		}
+
+		sessionUsers[session.Id] = append(sessionUsers[session.Id], userId)
+		userIdSet[userId] = true
+	}
+
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	include := true
+	_, err = s.filterService.HandleIncludes(&filter.FilterMatchesRequestIncludes{
+		ServerData:    &include,
+		MapData:       &include,
+		GameData:      &include,
+		ExtraGameData: &include,
+		LiveData:      &include,
+	}, matches)
+	if err != nil {
+		return nil, err
+	}
+
+	userIds = []int{}
+	for userId := range userIdSet {
+		userIds = append(userIds, userId)
+	}
+
+	profiles, err := s.userService.GetMatchProfiles(userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	profilesById := map[int]*models.UserProfile{}
+	for _, profile := range profiles {
+		profilesById[profile.Id] = profile
+	}
+
+	for _, item := range items {
+		item.Users = []*models.UserProfile{}
+		for _, userId := range sessionUsers[item.Match.Session.Id] {
+			if profile, ok := profilesById[userId]; ok {
+				item.Users = append(item.Users, profile)
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// Recently unlocked achievements and personal records
+func (s *FollowsService) getHighlights(userIds []int) ([]*FeedHighlight, error) {
+	from := time.Now().Add(-highlightsPeriod)
+	userIdsStr := util.IntArrayToString(userIds, ",")
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT * FROM (
+			SELECT
+				'%[1]v' AS type, user_id, session_id, unlocked_at AS created_at,
+				achievement_id, NULL AS record_type, NULL AS perk, NULL AS value
+			FROM user_achievements
+			WHERE user_id IN (%[3]v) AND unlocked_at > ?
+			UNION ALL
+			SELECT
+				'%[2]v' AS type, user_id, session_id, achieved_at AS created_at,
+				NULL AS achievement_id, record_type, perk, value
+			FROM user_records
+			WHERE user_id IN (%[3]v) AND achieved_at > ?
+		) t
+		ORDER BY created_at DESC
+		LIMIT 20`,
+		AchievementUnlocked, RecordSet, userIdsStr,
+	), from, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	titles := map[string]string{}
+	for _, item := range achievements.Achievements {
+		titles[item.Id] = item.Title
+	}
+
+	items := []*FeedHighlight{}
+	userIdSet := map[int]bool{}
+	for rows.Next() {
+		item := FeedHighlight{}
+
+		err := rows.Scan(
+			&item.Type, &item.userId, &item.SessionId, &item.CreatedAt,
+			&item.AchievementId, &item.RecordType, &item.Perk, &item.Value,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.AchievementId != nil {
+			if title, ok := titles[*item.AchievementId]; ok {
+				item.AchievementTitle = &title
+			}
+		}
+
+		userIdSet[item.userId] = true
+		items = append(items, &item)
+	}
+
+	userIds = []int{}
+	for userId := range userIdSet {
+		userIds = append(userIds, userId)
+	}
+
+	profiles, err := s.userService.GetMatchProfiles(userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		for _, profile := range profiles {
+			if profile.Id == item.userId {
+				item.User = profile
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// Live sessions, highlights and recent sessions of followed players
+func (s *FollowsService) GetFeed(userId int, req FeedRequest) (*FeedResponse, error) {
+	userIds, err := s.getFeedUserIds(userId, users.HideProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Live and recent sessions tell where the player is, same as last seen data
+	socialUserIds, err := s.getFeedUserIds(userId, users.HideProfile, users.HideSocial)
+	if err != nil {
+		return nil, err
+	}
+
+	res := FeedResponse{
+		Live:       []*FeedLiveSession{},
+		Highlights: []*FeedHighlight{},
+		Sessions: &filter.FilterMatchesResponse{
+			Items:    []*models.Match{},
+			Metadata: &models.PaginationResponse{},
+		},
+	}
+
+	if len(userIds) == 0 {
+		return &res, nil
+	}
+
+	res.Highlights, err = s.getHighlights(userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(socialUserIds) == 0 {
+		return &res, nil
+	}
+
+	res.Live, err = s.getLiveSessions(socialUserIds)
+	if err != nil {
+		return nil, err
+	}
+
+	include := true
+	res.Sessions, err = s.filterService.Filter(filter.FilterMatchesRequest{
+		UserIds: socialUserIds,
+		Exclude: &filter.FilterMatchesRequestExclude{
+			Statuses: []models.GameStatus{models.Lobby, models.InProgress},
+		},
+		Includes: &filter.FilterMatchesRequestIncludes{
+			ServerData:    &include,
+			MapData:       &include,
+			GameData:      &include,
+			ExtraGameData: &include,
+		},
+		Pager: req.Pager,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,42 @@
+package follows
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/matches/filter"
+)
+
+type FollowRequest struct {
+	UserId int `json:"user_id" binding:"required"`
+}
+
+type FollowedUser struct {
+	models.UserProfile
+
+	CurrentSessionId *int      `json:"current_session_id"`
+	FollowedAt       time.Time `json:"followed_at"`
+}
+
+type GetFollowsResponse struct {
+	Items []*FollowedUser `json:"items"`
+}
+
+type ImportSteamFriendsResponse struct {
+	Added int `json:"added"`
+}
+
+type FeedRequest struct {
+	Pager models.PaginationRequest `json:"pager"`
+}
+
+type FeedLiveSession struct {
+	Match *models.Match         `json:"match"`
+	Users []*models.UserProfile `json:"users"`
+}
+
+type FeedResponse struct {
+	Live       []*FeedLiveSession            `json:"live"`
+	Highlights []*FeedHighlight              `json:"highlights"`
+	Sessions   *filter.FilterMatchesResponse `json:"sessions"`
+}
@@ -0,0 +1,136 @@
+package gdpr
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+type controller struct {
+	service *GdprService
+}
+
+func (c *controller) sendExport(ctx *gin.Context, userId int) {
+	var req ExportRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	export, err := c.service.Export(userId)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fileName := fmt.Sprintf("kf2-stats-user-%v", userId)
+
+	switch req.Format {
+	case ExportCsv:
+		var buf bytes.Buffer
+		if err := export.WriteCsvArchive(&buf); err != nil {
+			ctx.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%v.zip"`, fileName))
+		ctx.Data(http.StatusOK, "application/zip", buf.Bytes())
+	case ExportJson, "":
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%v.json"`, fileName))
+		ctx.JSON(http.StatusOK, export)
+	default:
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("unknown format %v", req.Format))
+	}
+}
+
+// @Summary Export all data of the current user
+// @Tags 	Users
+// @Produce json
+// @Param   format query 	string false "json or csv"
+// @Success 200 {object} 	Export
+// @Router /users/export [get]
+func (c *controller) exportOwn(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	c.sendExport(ctx, user.UserId)
+}
+
+// @Summary Erase personal data of the current user, match stats are kept anonymized
+// @Tags 	Users
+// @Produce json
+// @Param   body body 		EraseRequest true "Body"
+// @Success 200
+// @Router /users/erase [post]
+func (c *controller) eraseOwn(ctx *gin.Context) {
+	user, ok := util.GetUserFromCtx(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{})
+		return
+	}
+
+	var req EraseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := c.service.Erase(user.UserId); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Export all data of the user
+// @Tags 	Users
+// @Produce json
+// @Param   id path   		int true "User id"
+// @Param   format query 	string false "json or csv"
+// @Success 200 {object} 	Export
+// @Router /users/{id}/export [get]
+func (c *controller) export(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.sendExport(ctx, id)
+}
+
+// @Summary Erase personal data of the user, match stats are kept anonymized
+// @Tags 	Users
+// @Produce json
+// @Param   id path   int true "User id"
+// @Param   body body EraseRequest true "Body"
+// @Success 200
+// @Router /users/{id}/erase [post]
+func (c *controller) erase(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req EraseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := c.service.Erase(id); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,65 @@
+package gdpr
+
+// Rows of the user selected by stmt, %[1]v is replaced with user id
+type dataset struct {
+	name string
+	stmt string
+}
+
+var datasets = []dataset{
+	{"user", `
+		SELECT id, auth_id, auth_type, name, created_at
+		FROM users WHERE id = %[1]v`},
+	{"steam_profile", `SELECT * FROM users_steam_data WHERE user_id = %[1]v`},
+	{"egs_profile", `SELECT * FROM users_egs_data WHERE user_id = %[1]v`},
+	{"activity", `SELECT * FROM users_activity WHERE user_id = %[1]v`},
+	{"privacy", `SELECT * FROM users_privacy WHERE user_id = %[1]v`},
+	{"linked_accounts", `
+		SELECT * FROM users_link
+		WHERE user_id = %[1]v OR primary_user_id = %[1]v`},
+	{"device_sessions", `
+		SELECT id, device, ip, created_at, last_used_at, revoked_at
+		FROM users_token_family WHERE user_id = %[1]v`},
+	{"api_tokens", `
+		SELECT id, name, prefix, scopes, rate_limit, usage_count, last_used_at, created_at, revoked_at
+		FROM users_api_token WHERE user_id = %[1]v`},
+	{"follows", `SELECT followed_user_id, created_at FROM users_follow WHERE user_id = %[1]v`},
+	{"roles", `SELECT * FROM users_role WHERE user_id = %[1]v`},
+	{"bans", `
+		SELECT id, reason, expires_at, revoked_at, created_at
+		FROM users_ban WHERE user_id = %[1]v`},
+	{"organizations", `SELECT * FROM organization_member WHERE user_id = %[1]v`},
+	{"achievements", `SELECT * FROM user_achievements WHERE user_id = %[1]v`},
+	{"records", `SELECT * FROM user_records WHERE user_id = %[1]v`},
+	{"sessions", `
+		SELECT session.* FROM session
+		WHERE session.id IN (
+			SELECT ws.session_id
+			FROM wave_stats ws
+			INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+			WHERE wsp.player_id = %[1]v
+		)
+		ORDER BY session.id`},
+	{"sessions_aggregated", `
+		SELECT * FROM session_aggregated WHERE user_id = %[1]v ORDER BY session_id`},
+	{"wave_stats", `
+		SELECT ws.session_id, ws.wave, ws.attempt, wsp.*
+		FROM wave_stats_player wsp
+		INNER JOIN wave_stats ws ON ws.id = wsp.stats_id
+		WHERE wsp.player_id = %[1]v
+		ORDER BY wsp.id`},
+	{"wave_stats_kills", `
+		SELECT kills.*
+		FROM wave_stats_player wsp
+		INNER JOIN wave_stats_player_kills kills ON kills.player_stats_id = wsp.id
+		WHERE wsp.player_id = %[1]v
+		ORDER BY wsp.id`},
+	{"wave_stats_comms", `
+		SELECT comms.*
+		FROM wave_stats_player wsp
+		INNER JOIN wave_stats_player_comms comms ON comms.player_stats_id = wsp.id
+		WHERE wsp.player_id = %[1]v
+		ORDER BY wsp.id`},
+	{"weekly_stats", `SELECT * FROM user_weekly_stats_total WHERE user_id = %[1]v`},
+	{"weekly_stats_perks", `SELECT * FROM user_weekly_stats_perk WHERE user_id = %[1]v`},
+}
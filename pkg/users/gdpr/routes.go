@@ -0,0 +1,22 @@
+package gdpr
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, service *GdprService) {
+	controller := controller{
+		service: service,
+	}
+
+	routes := r.Group("/users")
+
+	routes.GET("/export", middleware.AuthMiddleWave, controller.exportOwn)
+	routes.POST("/erase", middleware.AuthMiddleWave, controller.eraseOwn)
+
+	// Requests received outside of the site
+	routes.GET("/:id/export", middleware.RoleMiddleWave(models.SuperAdmin), controller.export)
+	routes.POST("/:id/erase", middleware.RoleMiddleWave(models.SuperAdmin), controller.erase)
+}
@@ -0,0 +1,265 @@
+package gdpr
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+)
+
+const erasedName = "Deleted user"
+
+type GdprService struct {
+	db *sql.DB
+
+	cache cachestore.Invalidator
+}
+
+func NewGdprService(db *sql.DB) *GdprService {
+	service := GdprService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *GdprService) Inject(cache cachestore.Invalidator) {
+	s.cache = cache
+}
+
+func (s *GdprService) queryDataset(userId int, item dataset) ([]string, []map[string]any, error) {
+	rows, err := s.db.Query(fmt.Sprintf(item.stmt, userId))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			// Text columns are scanned as bytes
+			if value, ok := values[i].([]byte); ok {
+				row[column] = string(value)
+			} else {
+				row[column] = values[i]
+			}
+		}
+
+		items = append(items, row)
+	}
+
+	return columns, items, nil
+}
+
+// Collects every row tied to the user
+func (s *GdprService) Export(userId int) (*Export, error) {
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = ? AND erased_at IS NULL)`, userId,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("user %v not found", userId)
+	}
+
+	res := Export{
+		UserId:     userId,
+		ExportedAt: time.Now(),
+		Datasets:   map[string][]map[string]any{},
+		columns:    map[string][]string{},
+	}
+
+	for _, item := range datasets {
+		columns, rows, err := s.queryDataset(userId, item)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", item.name, err)
+		}
+
+		res.Datasets[item.name] = rows
+		res.columns[item.name] = columns
+	}
+
+	return &res, nil
+}
+
+func formatCsvValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return value.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Writes zip archive with csv file per dataset
+func (e *Export) WriteCsvArchive(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	for _, item := range datasets {
+		file, err := archive.Create(item.name + ".csv")
+		if err != nil {
+			return err
+		}
+
+		writer := csv.NewWriter(file)
+
+		columns := e.columns[item.name]
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+
+		for _, row := range e.Datasets[item.name] {
+			record := make([]string, len(columns))
+			for i, column := range columns {
+				record[i] = formatCsvValue(row[column])
+			}
+
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Active ban is kept by account id hash and applied again to the new user of the account
+func (s *GdprService) keepActiveBan(tx *sql.Tx, userId int) error {
+	var authId string
+	var authType models.AuthType
+
+	err := tx.QueryRow(`
+		SELECT auth_id, auth_type FROM users WHERE id = ? AND erased_at IS NULL`, userId,
+	).Scan(&authId, &authType)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user %v not found", userId)
+	} else if err != nil {
+		return err
+	}
+
+	var reason string
+	var expiresAt *time.Time
+
+	// Permanent ban first, then the longest one
+	err = tx.QueryRow(`
+		SELECT reason, expires_at
+		FROM users_ban
+		WHERE user_id = ? AND revoked_at IS NULL AND
+			(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY expires_at IS NULL DESC, expires_at DESC
+		LIMIT 1`, userId,
+	).Scan(&reason, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO users_ban_erased (auth_hash, reason, expires_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE reason = VALUES(reason), expires_at = VALUES(expires_at)`,
+		users.ErasedAuthHash(authId, authType), reason, expiresAt,
+	)
+
+	return err
+}
+
+// Anonymizes the user and removes personal data.
+// Match stats are kept and attributed to the anonymous user, so sessions and aggregates stay intact.
+func (s *GdprService) Erase(userId int) error {
+	defer cachestore.Invalidate(s.cache, cachestore.TagUsers)
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		err := s.keepActiveBan(tx, userId)
+		if err != nil {
+			return err
+		}
+
+		// Auth id is replaced, next login or game with the account creates a new user
+		res, err := tx.Exec(`
+			UPDATE users
+			SET name = ?, auth_id = CONCAT('erased-', id), erased_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND erased_at IS NULL`,
+			erasedName, userId,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return fmt.Errorf("user %v not found", userId)
+		}
+
+		_, err = tx.Exec(`
+			DELETE FROM users_link WHERE user_id = ? OR primary_user_id = ?`, userId, userId,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			DELETE FROM users_follow WHERE user_id = ? OR followed_user_id = ?`, userId, userId,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, table := range []string{
+			"users_steam_data", "users_egs_data", "users_link_code",
+			"users_token_family", "users_api_token", "users_privacy",
+			"users_role", "organization_member",
+		} {
+			_, err := tx.Exec(fmt.Sprintf(`DELETE FROM %v WHERE user_id = ?`, table), userId)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE users_activity
+			SET current_session_id = NULL, perk = 0, level = 0, prestige = 0, health = 0, armor = 0
+			WHERE user_id = ?`, userId,
+		)
+
+		return err
+	})
+}
@@ -0,0 +1,30 @@
+package gdpr
+
+import "time"
+
+type ExportFormat = string
+
+const (
+	ExportJson ExportFormat = "json"
+	ExportCsv  ExportFormat = "csv"
+)
+
+type ExportRequest struct {
+	// Single json document or zip archive with csv file per dataset, json by default
+	Format ExportFormat `form:"format"`
+}
+
+type Export struct {
+	UserId     int       `json:"user_id"`
+	ExportedAt time.Time `json:"exported_at"`
+
+	Datasets map[string][]map[string]any `json:"datasets"`
+
+	// Column order of each dataset for csv files
+	columns map[string][]string
+}
+
+type EraseRequest struct {
+	// Must be set to avoid accidental erasure
+	Confirm bool `json:"confirm" binding:"required"`
+}
@@ -0,0 +1,236 @@
+package users
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+const (
+	linkCodeLength   = 8
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	linkCodeTTL      = 15 * time.Minute
+)
+
+// Matches rows of the user and of every account linked to the same player.
+// Links are one level deep: primary account can't be linked and linked account can't be primary.
+func LinkedIdsCond(column string, userId int) string {
+	return fmt.Sprintf(`%[1]v IN (
+		SELECT %[2]v
+		UNION SELECT primary_user_id FROM users_link WHERE user_id = %[2]v
+		UNION SELECT user_id FROM users_link WHERE primary_user_id = coalesce(
+			(SELECT primary_user_id FROM users_link WHERE user_id = %[2]v), %[2]v
+		)
+	)`, column, userId)
+}
+
+func generateLinkCode() (string, error) {
+	buf := make([]byte, linkCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	for i := range buf {
+		buf[i] = linkCodeAlphabet[int(buf[i])%len(linkCodeAlphabet)]
+	}
+
+	return string(buf), nil
+}
+
+func (s *UserService) getPrimaryUserId(userId int) (*int, error) {
+	var primaryUserId *int
+
+	err := s.db.QueryRow(`SELECT primary_user_id FROM users_link WHERE user_id = ?`, userId).
+		Scan(&primaryUserId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return primaryUserId, err
+}
+
+// Issues a code which the player types in game from the account to be linked
+func (s *UserService) CreateLinkCode(userId int) (*CreateLinkCodeResponse, error) {
+	primaryUserId, err := s.getPrimaryUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if primaryUserId != nil {
+		return nil, errors.New("linked account can't claim other accounts")
+	}
+
+	code, err := generateLinkCode()
+	if err != nil {
+		return nil, err
+	}
+
+	res := CreateLinkCodeResponse{
+		Code:      code,
+		ExpiresAt: time.Now().Add(linkCodeTTL),
+	}
+
+	err = util.Transact(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			DELETE FROM users_link_code
+			WHERE user_id = ? OR expires_at <= CURRENT_TIMESTAMP`, userId,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO users_link_code (code, user_id, expires_at) VALUES (?, ?, ?)`,
+			res.Code, userId, res.ExpiresAt,
+		)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Links the account which echoed the code in game to the account which issued the code
+func (s *UserService) VerifyLinkCode(req VerifyLinkCodeRequest) error {
+	target, err := s.GetByAuth(req.AuthId, req.AuthType)
+	if err != nil {
+		return fmt.Errorf("user %v not found", req.AuthId)
+	}
+
+	return util.Transact(s.db, func(tx *sql.Tx) error {
+		var primaryUserId int
+		err := tx.QueryRow(`
+			SELECT user_id FROM users_link_code
+			WHERE code = ? AND expires_at > CURRENT_TIMESTAMP
+			FOR UPDATE`, req.Code,
+		).Scan(&primaryUserId)
+		if err == sql.ErrNoRows {
+			return errors.New("invalid or expired code")
+		} else if err != nil {
+			return err
+		}
+
+		if primaryUserId == target.Id {
+			return errors.New("account can't be linked to itself")
+		}
+
+		var primaryType models.AuthType
+		err = tx.QueryRow(`SELECT auth_type FROM users WHERE id = ?`, primaryUserId).Scan(&primaryType)
+		if err != nil {
+			return err
+		}
+
+		if primaryType == target.Type {
+			return errors.New("only accounts of different platforms can be linked")
+		}
+
+		var isLinked, hasLinks, isPrimaryLinked bool
+		err = tx.QueryRow(`
+			SELECT
+				EXISTS (SELECT 1 FROM users_link WHERE user_id = ?),
+				EXISTS (SELECT 1 FROM users_link WHERE primary_user_id = ?),
+				EXISTS (SELECT 1 FROM users_link WHERE user_id = ?)`,
+			target.Id, target.Id, primaryUserId,
+		).Scan(&isLinked, &hasLinks, &isPrimaryLinked)
+		if err != nil {
+			return err
+		}
+
+		if isLinked || hasLinks {
+			return fmt.Errorf("user %v is already linked", target.Id)
+		}
+
+		if isPrimaryLinked {
+			return errors.New("linked account can't claim other accounts")
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO users_link (user_id, primary_user_id) VALUES (?, ?)`,
+			target.Id, primaryUserId,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM users_link_code WHERE code = ?`, req.Code)
+
+		return err
+	})
+}
+
+// Returns every account of the player the user belongs to, primary first
+func (s *UserService) GetLinkedAccounts(userId int) ([]*LinkedAccount, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT users.id, users.name, users.auth_type, users.auth_id, link.created_at
+		FROM users
+		LEFT JOIN users_link link ON link.user_id = users.id
+		WHERE %v
+		ORDER BY link.created_at IS NOT NULL, link.created_at, users.id`,
+		LinkedIdsCond("users.id", userId),
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*LinkedAccount{}
+	authIdSet := make(map[string]models.AuthType)
+	for rows.Next() {
+		item := LinkedAccount{}
+
+		err := rows.Scan(&item.Id, &item.Name, &item.Type, &item.AuthId, &item.LinkedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		item.IsPrimary = item.LinkedAt == nil
+		authIdSet[item.AuthId] = item.Type
+
+		items = append(items, &item)
+	}
+
+	profileData, err := s.GetProfileData(authIdSet)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if data, ok := profileData[item.AuthId]; ok {
+			item.Avatar = data.Avatar
+			item.ProfileUrl = data.ProfileUrl
+		}
+	}
+
+	return items, nil
+}
+
+// Either side of the link can remove it
+func (s *UserService) Unlink(authUserId, userId int) error {
+	res, err := s.db.Exec(`
+		DELETE FROM users_link
+		WHERE user_id = ? AND (primary_user_id = ? OR user_id = ?)`,
+		userId, authUserId, authUserId,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("user %v is not linked to your account", userId)
+	}
+
+	return nil
+}
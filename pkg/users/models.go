@@ -9,3 +9,10 @@ type User struct {
 
 	Name string `json:"name"`
 }
+
+// Profile data resolved by the auth provider of the user
+type ProfileData struct {
+	Name       string
+	Avatar     *string
+	ProfileUrl *string
+}
@@ -0,0 +1,163 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+)
+
+type PrivacyFlag = string
+
+const (
+	HideProfile          PrivacyFlag = "hide_profile"
+	HideFromLeaderboards PrivacyFlag = "hide_from_leaderboards"
+	HideSocial           PrivacyFlag = "hide_social"
+	AnonymizeName        PrivacyFlag = "anonymize_name"
+)
+
+const AnonymousName = "Anonymous"
+
+var ErrPrivateProfile = errors.New("profile is private")
+
+// Condition which skips users who enabled one of the privacy flags, userIdColumn is the column to check
+func NotHiddenCond(userIdColumn string, flags ...PrivacyFlag) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM users_privacy
+		WHERE users_privacy.user_id = %v AND (%v)
+	)`, userIdColumn, strings.Join(flags, " OR "))
+}
+
+func (s *UserService) GetPrivacySettings(userId int) (*PrivacySettings, error) {
+	item := PrivacySettings{}
+
+	err := s.db.QueryRow(`
+		SELECT hide_profile, hide_from_leaderboards, hide_social, anonymize_name
+		FROM users_privacy
+		WHERE user_id = ?`, userId,
+	).Scan(&item.HideProfile, &item.HideFromLeaderboards, &item.HideSocial, &item.AnonymizeName)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (s *UserService) UpdatePrivacySettings(userId int, req PrivacySettings) error {
+	_, err := s.db.Exec(`
+		INSERT INTO users_privacy (user_id, hide_profile, hide_from_leaderboards, hide_social, anonymize_name)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			hide_profile = VALUES(hide_profile),
+			hide_from_leaderboards = VALUES(hide_from_leaderboards),
+			hide_social = VALUES(hide_social),
+			anonymize_name = VALUES(anonymize_name)`,
+		userId, req.HideProfile, req.HideFromLeaderboards, req.HideSocial, req.AnonymizeName,
+	)
+	if err != nil {
+		return err
+	}
+
+	cachestore.Invalidate(s.cache, cachestore.TagUsers)
+
+	return nil
+}
+
+// Returns ErrPrivateProfile if any account of the player enabled one of the flags.
+// Accounts of the same player and global moderators bypass the check.
+func (s *UserService) CheckPrivacy(viewer *models.TokenPayload, userId int, flags ...PrivacyFlag) error {
+	viewerId := 0
+	if viewer != nil {
+		if viewer.HasRole(0, models.Moderator) {
+			return nil
+		}
+
+		viewerId = viewer.UserId
+	}
+
+	var isHidden bool
+	err := s.db.QueryRow(fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM users_privacy WHERE %v AND (%v)
+		) AND NOT %v`,
+		LinkedIdsCond("users_privacy.user_id", userId),
+		strings.Join(flags, " OR "),
+		LinkedIdsCond(fmt.Sprint(viewerId), userId),
+	)).Scan(&isHidden)
+	if err != nil {
+		return err
+	}
+
+	if isHidden {
+		return ErrPrivateProfile
+	}
+
+	return nil
+}
+
+// Returns users which have any of the flags enabled on any account of the player, same as CheckPrivacy
+func (s *UserService) GetHiddenUsers(userIds []int, flags ...PrivacyFlag) (map[int]bool, error) {
+	res := make(map[int]bool)
+	if len(userIds) == 0 {
+		return res, nil
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT DISTINCT ids.user_id
+		FROM (
+			SELECT users.id AS user_id, coalesce(link.primary_user_id, users.id) AS primary_id
+			FROM users
+			LEFT JOIN users_link link ON link.user_id = users.id
+			WHERE users.id IN (%v)
+		) ids
+		INNER JOIN (
+			SELECT coalesce(link.primary_user_id, users_privacy.user_id) AS primary_id
+			FROM users_privacy
+			LEFT JOIN users_link link ON link.user_id = users_privacy.user_id
+			WHERE %v
+		) hidden ON hidden.primary_id = ids.primary_id`,
+		util.IntArrayToString(userIds, ","), strings.Join(flags, " OR "),
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userId int
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+
+		res[userId] = true
+	}
+
+	return res, nil
+}
+
+// Same as GetUserProfiles, but profiles of users who enabled name anonymization are hidden
+func (s *UserService) GetMatchProfiles(userId []int) ([]*models.UserProfile, error) {
+	profiles, err := s.GetUserProfiles(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	anonymized, err := s.GetHiddenUsers(userId, AnonymizeName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if anonymized[profile.Id] {
+			profile.Name = AnonymousName
+			profile.Avatar = nil
+			profile.ProfileUrl = nil
+		}
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,61 @@
+package records
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+)
+
+type controller struct {
+	service *RecordsService
+}
+
+// @Summary Get user personal records
+// @Tags 	Users
+// @Produce json
+// @Param   id path   	 	int true "User id"
+// @Success 200 {object} 	GetUserRecordsResponse
+// @Router /users/{id}/records [get]
+func (c *controller) getByUserId(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := util.GetUserFromCtx(ctx)
+	if err := c.service.userService.CheckPrivacy(user, id, users.HideProfile); err == users.ErrPrivateProfile {
+		ctx.String(http.StatusForbidden, err.Error())
+		return
+	} else if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := c.service.getByUserId(id)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// @Summary Rebuild personal records from all sessions
+// @Tags 	Users
+// @Produce json
+// @Param   key query 	string false "Api key, alternative to the admin role"
+// @Success 201
+// @Router /users/records/rebuild [post]
+func (c *controller) rebuildAll(ctx *gin.Context) {
+	err := c.service.RebuildAll()
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{})
+}
@@ -0,0 +1,34 @@
+package records
+
+import (
+	"time"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+type RecordType = int
+
+const (
+	WaveDamage RecordType = iota + 1
+	SessionDamage
+	WaveKills
+	SessionKills
+	WaveAccuracy
+	ZedtimeChain
+	HighestDifficultyWin
+)
+
+// Minimal shots fired in a wave to count accuracy record
+const minAccuracyShots = 50
+
+type UserRecord struct {
+	Type RecordType  `json:"type"`
+	Perk models.Perk `json:"perk"`
+
+	Value float64 `json:"value"`
+
+	SessionId *int `json:"session_id"`
+	Wave      *int `json:"wave"`
+
+	AchievedAt time.Time `json:"achieved_at"`
+}
@@ -0,0 +1,18 @@
+package records
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, service *RecordsService) {
+	controller := controller{
+		service: service,
+	}
+
+	routes := r.Group("/users")
+
+	routes.GET("/:id/records", middleware.OptionalAuthMiddleWave, controller.getByUserId)
+	routes.POST("/records/rebuild", middleware.RoleMiddleWave(models.SuperAdmin), controller.rebuildAll)
+}
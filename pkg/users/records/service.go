@@ -0,0 +1,274 @@
+package records
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/theggv/kf2-stats-backend/pkg/common/demorecord"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
+	"github.com/theggv/kf2-stats-backend/pkg/common/util"
+	"github.com/theggv/kf2-stats-backend/pkg/jobs"
+	"github.com/theggv/kf2-stats-backend/pkg/users"
+)
+
+// Each query returns candidates for the record with columns:
+// user_id, perk, value, session_id, wave, achieved_at, rn
+// where rn = 1 is the best row of the user and perk.
+// Placeholder is replaced with conditions on the session table.
+var recordQueries = map[RecordType]string{
+	WaveDamage: `
+		SELECT
+			wsp.player_id AS user_id, wsp.perk AS perk,
+			wsp.damage_dealt AS value,
+			session.id AS session_id, ws.wave AS wave, wsp.created_at AS achieved_at,
+			ROW_NUMBER() OVER (PARTITION BY wsp.player_id, wsp.perk ORDER BY wsp.damage_dealt DESC, wsp.id) AS rn
+		FROM session
+		INNER JOIN wave_stats ws ON ws.session_id = session.id
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		WHERE wsp.perk > 0 AND %v`,
+	SessionDamage: `
+		SELECT
+			aggr.user_id AS user_id, aggr.perk AS perk,
+			aggr.damage_dealt AS value,
+			session.id AS session_id, NULL AS wave, session.updated_at AS achieved_at,
+			ROW_NUMBER() OVER (PARTITION BY aggr.user_id, aggr.perk ORDER BY aggr.damage_dealt DESC, aggr.id) AS rn
+		FROM session
+		INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+		WHERE aggr.perk > 0 AND %v`,
+	WaveKills: `
+		SELECT
+			wsp.player_id AS user_id, wsp.perk AS perk,
+			kills.total AS value,
+			session.id AS session_id, ws.wave AS wave, wsp.created_at AS achieved_at,
+			ROW_NUMBER() OVER (PARTITION BY wsp.player_id, wsp.perk ORDER BY kills.total DESC, wsp.id) AS rn
+		FROM session
+		INNER JOIN wave_stats ws ON ws.session_id = session.id
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		INNER JOIN aggregated_kills kills ON kills.player_stats_id = wsp.id
+		WHERE wsp.perk > 0 AND %v`,
+	SessionKills: `
+		SELECT
+			aggr.user_id AS user_id, aggr.perk AS perk,
+			kills.total AS value,
+			session.id AS session_id, NULL AS wave, session.updated_at AS achieved_at,
+			ROW_NUMBER() OVER (PARTITION BY aggr.user_id, aggr.perk ORDER BY kills.total DESC, aggr.id) AS rn
+		FROM session
+		INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+		INNER JOIN session_aggregated_kills kills ON kills.id = aggr.id
+		WHERE aggr.perk > 0 AND %v`,
+	WaveAccuracy: fmt.Sprintf(`
+		SELECT
+			wsp.player_id AS user_id, wsp.perk AS perk,
+			wsp.shots_hit / wsp.shots_fired AS value,
+			session.id AS session_id, ws.wave AS wave, wsp.created_at AS achieved_at,
+			ROW_NUMBER() OVER (PARTITION BY wsp.player_id, wsp.perk ORDER BY wsp.shots_hit / wsp.shots_fired DESC, wsp.id) AS rn
+		FROM session
+		INNER JOIN wave_stats ws ON ws.session_id = session.id
+		INNER JOIN wave_stats_player wsp ON wsp.stats_id = ws.id
+		WHERE wsp.perk > 0 AND wsp.shots_fired >= %v AND %%v`, minAccuracyShots,
+	),
+	HighestDifficultyWin: fmt.Sprintf(`
+		SELECT
+			aggr.user_id AS user_id, aggr.perk AS perk,
+			diff.final_score * diff.final_score AS value,
+			session.id AS session_id, NULL AS wave,
+			coalesce(session.completed_at, session.updated_at) AS achieved_at,
+			ROW_NUMBER() OVER (PARTITION BY aggr.user_id, aggr.perk ORDER BY diff.final_score DESC, aggr.id) AS rn
+		FROM session
+		INNER JOIN session_aggregated aggr ON aggr.session_id = session.id
+		INNER JOIN session_diff diff ON diff.session_id = session.id
+		WHERE aggr.perk > 0 AND session.status = %v AND diff.final_score > 0 AND %%v`, models.Win,
+	),
+}
+
+type RecordsService struct {
+	db *sql.DB
+
+	userService *users.UserService
+}
+
+func NewRecordsService(db *sql.DB) *RecordsService {
+	service := RecordsService{
+		db: db,
+	}
+
+	return &service
+}
+
+func (s *RecordsService) Inject(userService *users.UserService) {
+	s.userService = userService
+}
+
+// Queued sessions are processed by the worker after their difficulty is calculated,
+// because difficulty records depend on it
+func (s *RecordsService) AddToQueue(sessionId int) {
+	if err := jobs.Enqueue(s.db, jobs.RecordsJob, sessionId); err != nil {
+		fmt.Printf("[records] session %v: %v\n", sessionId, err)
+	}
+}
+
+func (s *RecordsService) UpdateBySessionIds(sessionIds []int) error {
+	cond := fmt.Sprintf("session.id IN (%v)", util.IntArrayToString(sessionIds, ","))
+
+	for recordType, query := range recordQueries {
+		err := s.upsert(recordType, fmt.Sprintf(query, cond))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Recalculates records from all sessions.
+// Zed time records are kept, because they are calculated from demo records only.
+func (s *RecordsService) RebuildAll() error {
+	_, err := s.db.Exec(`DELETE FROM user_records WHERE record_type != ?`, ZedtimeChain)
+	if err != nil {
+		return err
+	}
+
+	for recordType, query := range recordQueries {
+		err := s.upsert(recordType, fmt.Sprintf(query, "1 = 1"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *RecordsService) upsert(recordType RecordType, query string, args ...any) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO user_records (user_id, perk, record_type, value, session_id, wave, achieved_at)
+		SELECT t.user_id, t.perk, %v, t.value, t.session_id, t.wave, t.achieved_at
+		FROM (%v) t
+		WHERE t.rn = 1
+		ON DUPLICATE KEY UPDATE
+			session_id = IF(VALUES(value) > user_records.value, VALUES(session_id), user_records.session_id),
+			wave = IF(VALUES(value) > user_records.value, VALUES(wave), user_records.wave),
+			achieved_at = IF(VALUES(value) > user_records.value, VALUES(achieved_at), user_records.achieved_at),
+			value = GREATEST(user_records.value, VALUES(value))`,
+		recordType, query,
+	)
+
+	_, err := s.db.Exec(stmt, args...)
+
+	return err
+}
+
+// Updates longest zed time chain of players who killed zeds during the zed time
+func (s *RecordsService) UpdateFromDemo(analysis *demorecord.DemoRecordAnalysis) error {
+	type record struct {
+		duration float64
+		wave     int
+	}
+
+	best := map[[2]int]*record{}
+
+	for _, wave := range analysis.Waves {
+		perks := map[int]int{}
+		for _, item := range wave.PlayerEvents.Perks {
+			perks[item.UserId] = item.Perk
+		}
+
+		for _, zedtime := range wave.Zedtimes {
+			if zedtime.MetaData == nil {
+				continue
+			}
+
+			for _, kill := range wave.PlayerEvents.Kills {
+				if kill.Tick < zedtime.MetaData.StartTick || kill.Tick > zedtime.MetaData.EndTick {
+					continue
+				}
+
+				profile := analysis.Players.GetByIndex(kill.UserId)
+				perk := perks[kill.UserId]
+				if profile == nil || perk <= 0 {
+					continue
+				}
+
+				key := [2]int{profile.Id, perk}
+				if item, ok := best[key]; !ok || item.duration < zedtime.MetaData.Duration {
+					best[key] = &record{
+						duration: zedtime.MetaData.Duration,
+						wave:     wave.MetaData.Wave,
+					}
+				}
+			}
+		}
+	}
+
+	if len(best) == 0 {
+		return nil
+	}
+
+	rows := []string{}
+	args := []any{}
+
+	for key, item := range best {
+		rows = append(rows, "SELECT ? AS user_id, ? AS perk, ? AS value, ? AS session_id, ? AS wave, CURRENT_TIMESTAMP AS achieved_at, 1 AS rn")
+		args = append(args, key[0], key[1], item.duration, analysis.SessionId, item.wave)
+	}
+
+	return s.upsert(ZedtimeChain, strings.Join(rows, " UNION ALL "), args...)
+}
+
+func (s *RecordsService) getByUserId(userId int) (*GetUserRecordsResponse, error) {
+	rows, err := s.db.Query(`
+		SELECT record_type, perk, value, session_id, wave, achieved_at
+		FROM user_records
+		WHERE user_id = ?
+		ORDER BY perk, record_type`, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := GetUserRecordsResponse{
+		Best:  []*UserRecord{},
+		Perks: []*UserRecordsPerk{},
+	}
+
+	best := map[RecordType]*UserRecord{}
+
+	var current *UserRecordsPerk
+	for rows.Next() {
+		item := UserRecord{}
+
+		err := rows.Scan(
+			&item.Type, &item.Perk, &item.Value,
+			&item.SessionId, &item.Wave, &item.AchievedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.Perk != item.Perk {
+			current = &UserRecordsPerk{
+				Perk:  item.Perk,
+				Items: []*UserRecord{},
+			}
+			res.Perks = append(res.Perks, current)
+		}
+
+		current.Items = append(current.Items, &item)
+
+		if record, ok := best[item.Type]; !ok || record.Value < item.Value {
+			best[item.Type] = &item
+		}
+	}
+
+	for _, item := range best {
+		res.Best = append(res.Best, item)
+	}
+
+	sort.Slice(res.Best, func(i, j int) bool {
+		return res.Best[i].Type < res.Best[j].Type
+	})
+
+	return &res, nil
+}
@@ -0,0 +1,16 @@
+package records
+
+import "github.com/theggv/kf2-stats-backend/pkg/common/models"
+
+type UserRecordsPerk struct {
+	Perk models.Perk `json:"perk"`
+
+	Items []*UserRecord `json:"items"`
+}
+
+type GetUserRecordsResponse struct {
+	// Best record of each type across all perks
+	Best []*UserRecord `json:"best"`
+
+	Perks []*UserRecordsPerk `json:"perks"`
+}
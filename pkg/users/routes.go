@@ -3,6 +3,7 @@ package users
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/theggv/kf2-stats-backend/pkg/common/middleware"
+	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 )
 
 func RegisterRoutes(r *gin.RouterGroup, service *UserService) {
@@ -13,6 +14,17 @@ func RegisterRoutes(r *gin.RouterGroup, service *UserService) {
 	routes := r.Group("/users")
 
 	routes.POST("/", middleware.MutatorAuthMiddleWave, controller.create)
-	routes.GET("/:id/detailed", controller.getUserDetailed)
+	routes.GET("/:id/detailed", middleware.OptionalAuthMiddleWave, controller.getUserDetailed)
 	routes.POST("/filter", controller.filter)
+
+	routes.GET("/privacy", middleware.AuthMiddleWave, controller.getPrivacySettings)
+	routes.PUT("/privacy", middleware.AuthMiddleWave, controller.updatePrivacySettings)
+
+	// Code is echoed by the mutator when the player types it in game
+	routes.GET("/link",
+		middleware.ApiTokenMiddleWave(models.ApiScopeProfileRead), middleware.AuthMiddleWave,
+		controller.getLinkedAccounts)
+	routes.POST("/link/code", middleware.AuthMiddleWave, controller.createLinkCode)
+	routes.POST("/link/verify", middleware.MutatorAuthMiddleWave, controller.verifyLinkCode)
+	routes.DELETE("/link/:userId", middleware.AuthMiddleWave, controller.unlink)
 }
@@ -3,7 +3,10 @@ package users
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
+	"github.com/theggv/kf2-stats-backend/pkg/common/cachestore"
+	"github.com/theggv/kf2-stats-backend/pkg/common/egsapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/models"
 	"github.com/theggv/kf2-stats-backend/pkg/common/steamapi"
 	"github.com/theggv/kf2-stats-backend/pkg/common/util"
@@ -14,7 +17,10 @@ type UserService struct {
 	db *sql.DB
 
 	steamApiService *steamapi.SteamApiUserService
+	egsApiService   egsapi.EgsApi
 	diffService     *difficulty.DifficultyCalculatorService
+
+	cache cachestore.Invalidator
 }
 
 func NewUserService(db *sql.DB) *UserService {
@@ -27,10 +33,14 @@ func NewUserService(db *sql.DB) *UserService {
 
 func (s *UserService) Inject(
 	steamApiService *steamapi.SteamApiUserService,
+	egsApiService egsapi.EgsApi,
 	diffService *difficulty.DifficultyCalculatorService,
+	cache cachestore.Invalidator,
 ) {
 	s.steamApiService = steamApiService
+	s.egsApiService = egsApiService
 	s.diffService = diffService
+	s.cache = cache
 }
 
 func (s *UserService) FindCreateFind(req CreateUserRequest) (int, error) {
@@ -65,6 +75,11 @@ func (s *UserService) FindCreateFind(req CreateUserRequest) (int, error) {
 		INSERT INTO users_activity (user_id, current_session_id, last_session_id) 
 		VALUES (?, NULL, NULL)`, data.Id,
 	)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.restoreErasedBan(data.Id, req.AuthId, req.AuthType)
 
 	return data.Id, err
 }
@@ -156,13 +171,30 @@ func (s *UserService) getUserDetailed(id int) (*FilterUsersResponseUser, error)
 		return nil, err
 	}
 
-	if item.Type == models.Steam {
-		steamData, err := s.GetSteamData([]string{item.AuthId})
-		if err == nil {
-			if data, ok := steamData[item.AuthId]; ok {
-				item.Avatar = &data.Avatar
-				item.ProfileUrl = &data.ProfileUrl
+	profileData, err := s.GetProfileData(map[string]models.AuthType{item.AuthId: item.Type})
+	if err == nil {
+		if data, ok := profileData[item.AuthId]; ok {
+			item.Avatar = data.Avatar
+			item.ProfileUrl = data.ProfileUrl
+		}
+	}
+
+	{
+		accounts, err := s.GetLinkedAccounts(item.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		item.LinkedAccounts = []*models.UserProfile{}
+		for _, account := range accounts {
+			if account.Id == item.Id {
+				if !account.IsPrimary {
+					item.PrimaryUserId = &accounts[0].Id
+				}
+				continue
 			}
+
+			item.LinkedAccounts = append(item.LinkedAccounts, account.AsPartial())
 		}
 	}
 
@@ -215,10 +247,10 @@ func (s *UserService) filter(req FilterUsersRequest) (*FilterUsersResponse, erro
 			users_activity.updated_at
 		FROM users
 		INNER JOIN users_activity ON users_activity.user_id = users.id
-		WHERE LOWER(users.name) LIKE ?
+		WHERE LOWER(users.name) LIKE ? AND users.erased_at IS NULL AND %v
 		ORDER BY users_activity.updated_at DESC
 		LIMIT %v, %v
-		`, page*limit, limit,
+		`, NotHiddenCond("users.id", HideProfile), page*limit, limit,
 	)
 	args := []any{
 		fmt.Sprintf("%%%v%%", req.SearchText),
@@ -230,7 +262,7 @@ func (s *UserService) filter(req FilterUsersRequest) (*FilterUsersResponse, erro
 	}
 
 	sessionIdSet := make(map[int]bool)
-	steamIdSet := make(map[string]bool)
+	authIdSet := make(map[string]models.AuthType)
 	items := []*FilterUsersResponseUser{}
 
 	for rows.Next() {
@@ -247,28 +279,21 @@ func (s *UserService) filter(req FilterUsersRequest) (*FilterUsersResponse, erro
 		if item.CurrentSessionId != nil {
 			sessionIdSet[*item.CurrentSessionId] = true
 		}
-		if item.Type == models.Steam {
-			steamIdSet[item.AuthId] = true
-		}
+		authIdSet[item.AuthId] = item.Type
 
 		items = append(items, &item)
 	}
 
 	{
-		steamIds := []string{}
-		for key := range steamIdSet {
-			steamIds = append(steamIds, key)
-		}
-
-		steamData, err := s.GetSteamData(steamIds)
+		profileData, err := s.GetProfileData(authIdSet)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, item := range items {
-			if data, ok := steamData[item.AuthId]; ok {
-				item.Avatar = &data.Avatar
-				item.ProfileUrl = &data.ProfileUrl
+			if data, ok := profileData[item.AuthId]; ok {
+				item.Avatar = data.Avatar
+				item.ProfileUrl = data.ProfileUrl
 			}
 		}
 	}
@@ -302,10 +327,12 @@ func (s *UserService) filter(req FilterUsersRequest) (*FilterUsersResponse, erro
 	var total int
 	{
 		// Prepare count query
-		stmt = `
+		stmt = fmt.Sprintf(`
 			SELECT count(*) FROM users
 			INNER JOIN users_activity ON users_activity.user_id = users.id
-			WHERE lower(users.name) LIKE ?`
+			WHERE lower(users.name) LIKE ? AND users.erased_at IS NULL AND %v`,
+			NotHiddenCond("users.id", HideProfile),
+		)
 
 		args := []any{
 			fmt.Sprintf("%%%v%%", req.SearchText),
@@ -406,40 +433,128 @@ func (s *UserService) GetSteamData(steamIds []string) (map[string]steamapi.GetUs
 	return steamDataSet, nil
 }
 
-func (s *UserService) GetUserProfiles(
-	userId []int,
-) ([]*models.UserProfile, error) {
-	users, err := s.GetManyById(userId)
+// Accounts unknown to the provider fall back to data stored on login
+func (s *UserService) GetEgsData(accountIds []string) (map[string]egsapi.GetUserSummaryPlayer, error) {
+	egsData, err := s.egsApiService.GetUserSummary(accountIds)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(users) == 0 {
-		return []*models.UserProfile{}, nil
+	egsDataSet := make(map[string]egsapi.GetUserSummaryPlayer)
+	for _, data := range egsData {
+		egsDataSet[data.AccountId] = data
+	}
+
+	missing := []string{}
+	args := []any{}
+	for _, accountId := range accountIds {
+		if _, ok := egsDataSet[accountId]; !ok {
+			missing = append(missing, "?")
+			args = append(args, accountId)
+		}
+	}
+
+	if len(missing) == 0 {
+		return egsDataSet, nil
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT account_id, name, avatar, profile_url
+		FROM users_egs_data
+		WHERE account_id IN (%v)`, strings.Join(missing, ","),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		data := egsapi.GetUserSummaryPlayer{}
+
+		err := rows.Scan(&data.AccountId, &data.Name, &data.Avatar, &data.ProfileUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		egsDataSet[data.AccountId] = data
 	}
 
-	set := make(map[string]bool)
+	return egsDataSet, nil
+}
+
+// Resolves profile data of users keyed by auth id with the provider of their auth type
+func (s *UserService) GetProfileData(authIds map[string]models.AuthType) (map[string]*ProfileData, error) {
 	steamIds := []string{}
+	accountIds := []string{}
+
+	for authId, authType := range authIds {
+		switch authType {
+		case models.Steam:
+			steamIds = append(steamIds, authId)
+		case models.EGS:
+			accountIds = append(accountIds, authId)
+		}
+	}
 
-	for _, player := range users {
-		if player.Type != models.Steam {
-			continue
+	res := make(map[string]*ProfileData)
+
+	if len(steamIds) > 0 {
+		steamData, err := s.GetSteamData(steamIds)
+		if err != nil {
+			return nil, err
+		}
+
+		for authId, data := range steamData {
+			res[authId] = &ProfileData{
+				Name:       data.Name,
+				Avatar:     &data.Avatar,
+				ProfileUrl: &data.ProfileUrl,
+			}
 		}
-		set[player.AuthId] = true
 	}
 
-	for key := range set {
-		steamIds = append(steamIds, key)
+	if len(accountIds) > 0 {
+		egsData, err := s.GetEgsData(accountIds)
+		if err != nil {
+			return nil, err
+		}
+
+		for authId, data := range egsData {
+			item := ProfileData{Name: data.Name}
+			if data.Avatar != "" {
+				item.Avatar = &data.Avatar
+			}
+			if data.ProfileUrl != "" {
+				item.ProfileUrl = &data.ProfileUrl
+			}
+
+			res[authId] = &item
+		}
 	}
 
-	steamData, err := s.steamApiService.GetUserSummary(steamIds)
+	return res, nil
+}
+
+func (s *UserService) GetUserProfiles(
+	userId []int,
+) ([]*models.UserProfile, error) {
+	users, err := s.GetManyById(userId)
 	if err != nil {
 		return nil, err
 	}
 
-	steamDataSet := make(map[string]steamapi.GetUserSummaryPlayer)
-	for _, data := range steamData {
-		steamDataSet[data.SteamId] = data
+	if len(users) == 0 {
+		return []*models.UserProfile{}, nil
+	}
+
+	authIdSet := make(map[string]models.AuthType)
+	for _, player := range users {
+		authIdSet[player.AuthId] = player.Type
+	}
+
+	profileData, err := s.GetProfileData(authIdSet)
+	if err != nil {
+		return nil, err
 	}
 
 	profiles := []*models.UserProfile{}
@@ -451,9 +566,9 @@ func (s *UserService) GetUserProfiles(
 			Name:   player.Name,
 		}
 
-		if data, exists := steamDataSet[player.AuthId]; exists {
-			profile.ProfileUrl = &data.ProfileUrl
-			profile.Avatar = &data.Avatar
+		if data, exists := profileData[player.AuthId]; exists {
+			profile.ProfileUrl = data.ProfileUrl
+			profile.Avatar = data.Avatar
 		}
 
 		profiles = append(profiles, &profile)
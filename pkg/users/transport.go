@@ -56,6 +56,10 @@ type FilterUsersResponseUser struct {
 	AuthId string          `json:"-"`
 	Type   models.AuthType `json:"-"`
 
+	// Set if the user is linked to another account which represents the player
+	PrimaryUserId  *int                  `json:"primary_user_id"`
+	LinkedAccounts []*models.UserProfile `json:"linked_accounts"`
+
 	LastSessionId    *int `json:"-"`
 	CurrentSessionId *int `json:"-"`
 }
@@ -115,3 +119,37 @@ type RecentSessionsResponse struct {
 	Items    []*RecentSessionsResponseSession `json:"items"`
 	Metadata models.PaginationResponse        `json:"metadata"`
 }
+
+type CreateLinkCodeResponse struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type VerifyLinkCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+
+	AuthId   string          `json:"auth_id" binding:"required"`
+	AuthType models.AuthType `json:"auth_type" binding:"required"`
+}
+
+type LinkedAccount struct {
+	models.UserProfileFull
+
+	IsPrimary bool       `json:"is_primary"`
+	LinkedAt  *time.Time `json:"linked_at"`
+}
+
+type GetLinkedAccountsResponse struct {
+	Items []*LinkedAccount `json:"items"`
+}
+
+type PrivacySettings struct {
+	// Profile, analytics and sessions are visible to the owner only
+	HideProfile bool `json:"hide_profile"`
+	// Excluded from leaderboards
+	HideFromLeaderboards bool `json:"hide_from_leaderboards"`
+	// Teammates are hidden, user doesn't appear in teammates and last seen lists of others
+	HideSocial bool `json:"hide_social"`
+	// Name and avatar are hidden on match pages
+	AnonymizeName bool `json:"anonymize_name"`
+}